@@ -0,0 +1,434 @@
+package captcha
+
+import (
+	"container/list"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	xdraw "golang.org/x/image/draw"
+)
+
+// AssetLibraryConfig 图片资源库配置
+type AssetLibraryConfig struct {
+	PollInterval time.Duration `json:",default=30s"` // 轮询目录变化的间隔，0 表示不监听
+	MaxWidth     int           `json:",optional"`    // 加载时等比缩小到不超过该宽度，0 表示保持原尺寸
+	MaxHeight    int           `json:",optional"`    // 加载时等比缩小到不超过该高度，0 表示保持原尺寸
+	CacheBytes   int64         `json:",optional"`    // 按需加载（不在扫描目录中）的图片缓存上限，按解码后的像素估算，0 表示默认 64MB，小于 0 表示不缓存
+}
+
+// defaultAssetCacheBytes 按需加载图片的默认缓存上限
+const defaultAssetCacheBytes = 64 << 20
+
+// AssetLibrary 图片资源库
+//
+// 启动时一次性扫描 ImageDir/TemplateDir 等目录，把图片解码（可选缩放）后缓存在内存中，
+// 标注、清单等小文件缓存原始内容；开启轮询后定期检查文件变化并增量重新加载。
+// 同一个资源库可以被多个生成器和多次请求共享，并发安全。
+type AssetLibrary struct {
+	config AssetLibraryConfig
+	roots  []string
+
+	mu      sync.RWMutex
+	stamps  map[string]assetStamp  // 文件路径 -> 大小和修改时间
+	images  map[string]image.Image // 图片路径 -> 解码后的图片
+	files   map[string][]byte      // 非图片文件路径 -> 文件内容
+	version uint64                 // 每次内容变化后递增
+
+	// 按需加载的图片，超出上限时淘汰最久未使用的
+	cache *imageCache
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// assetStamp 用于检测文件变化
+type assetStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewAssetLibrary 创建图片资源库并扫描目录，不存在的目录会被忽略
+func NewAssetLibrary(config AssetLibraryConfig, dirs ...string) *AssetLibrary {
+	l := &AssetLibrary{
+		config: config,
+		stamps: make(map[string]assetStamp),
+		images: make(map[string]image.Image),
+		files:  make(map[string][]byte),
+		cache:  newImageCache(config.CacheBytes),
+		stop:   make(chan struct{}),
+	}
+
+	seen := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		l.roots = append(l.roots, filepath.Clean(dir))
+	}
+
+	l.Reload()
+
+	if config.PollInterval > 0 {
+		go l.watch()
+	}
+
+	return l
+}
+
+// Reload 重新扫描目录，只重新加载新增或变化的文件，返回内容是否有变化
+func (l *AssetLibrary) Reload() bool {
+	stamps := l.scan()
+
+	l.mu.RLock()
+	var changed []string
+	for path, stamp := range stamps {
+		if old, ok := l.stamps[path]; !ok || old != stamp {
+			changed = append(changed, path)
+		}
+	}
+	removed := 0
+	for path := range l.stamps {
+		if _, ok := stamps[path]; !ok {
+			removed++
+		}
+	}
+	l.mu.RUnlock()
+
+	if len(changed) == 0 && removed == 0 {
+		return false
+	}
+
+	// 在锁外解码，避免阻塞读取；加载失败的文件移除旧内容，不再继续使用变化前的版本
+	images := make(map[string]image.Image)
+	files := make(map[string][]byte)
+	var failed []string
+	for _, path := range changed {
+		if isImageFile(path) {
+			img, err := l.decode(path)
+			if err != nil {
+				logx.Errorf("加载图片失败，已移除: %s, error: %v", path, err)
+				failed = append(failed, path)
+				continue
+			}
+			images[path] = img
+		} else {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				logx.Errorf("读取文件失败，已移除: %s, error: %v", path, err)
+				failed = append(failed, path)
+				continue
+			}
+			files[path] = content
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for path := range l.stamps {
+		if _, ok := stamps[path]; !ok {
+			delete(l.images, path)
+			delete(l.files, path)
+		}
+	}
+	for _, path := range failed {
+		delete(l.images, path)
+		delete(l.files, path)
+	}
+	for path, img := range images {
+		l.images[path] = img
+	}
+	for path, content := range files {
+		l.files[path] = content
+	}
+	l.stamps = stamps
+	l.version++
+
+	logx.Infof("图片资源库已加载: %d 张图片, %d 个文件, %d 处变化", len(l.images), len(l.files), len(changed)+removed)
+	return true
+}
+
+// Close 停止轮询
+func (l *AssetLibrary) Close() {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+}
+
+// Version 资源版本号，每次内容变化后递增，可用于使上层缓存失效
+func (l *AssetLibrary) Version() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.version
+}
+
+// Image 获取解码后的图片，不在已扫描目录中的图片会按需加载，并在 CacheBytes 的上限内缓存
+func (l *AssetLibrary) Image(path string) (image.Image, error) {
+	path = filepath.Clean(path)
+
+	l.mu.RLock()
+	img, ok := l.images[path]
+	l.mu.RUnlock()
+	if ok {
+		return img, nil
+	}
+	if img, ok := l.cache.get(path); ok {
+		return img, nil
+	}
+
+	img, err := l.decode(path)
+	if err != nil {
+		return nil, err
+	}
+	l.cache.add(path, img)
+
+	return img, nil
+}
+
+// ReadFile 获取非图片文件（标注、清单等）的内容，不在已扫描目录中的文件直接从磁盘读取
+func (l *AssetLibrary) ReadFile(path string) ([]byte, error) {
+	path = filepath.Clean(path)
+
+	l.mu.RLock()
+	content, ok := l.files[path]
+	l.mu.RUnlock()
+	if ok {
+		return content, nil
+	}
+
+	return os.ReadFile(path)
+}
+
+// Exists 文件是否存在于已扫描的目录中
+func (l *AssetLibrary) Exists(path string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.stamps[filepath.Clean(path)]
+	return ok
+}
+
+// Images 返回目录下（不含子目录）已加载的图片路径，按名称排序
+func (l *AssetLibrary) Images(dir string) []string {
+	dir = filepath.Clean(dir)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var paths []string
+	for path := range l.images {
+		if filepath.Dir(path) == dir {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Subdirs 返回目录下直接包含图片的子目录名称，按名称排序
+func (l *AssetLibrary) Subdirs(dir string) []string {
+	dir = filepath.Clean(dir)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for path := range l.images {
+		parent := filepath.Dir(path)
+		if filepath.Dir(parent) == dir {
+			seen[filepath.Base(parent)] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// imageCache 按字节上限淘汰最久未使用图片的缓存，并发安全
+type imageCache struct {
+	mu     sync.Mutex
+	budget int64
+	used   int64
+	order  *list.List               // 最近使用的在前
+	items  map[string]*list.Element // 图片路径 -> order 中的元素
+}
+
+// cachedImage 缓存中的图片
+type cachedImage struct {
+	path string
+	img  image.Image
+	size int64
+}
+
+// newImageCache 创建图片缓存，budget 为 0 时使用默认上限，小于 0 时不缓存
+func newImageCache(budget int64) *imageCache {
+	if budget == 0 {
+		budget = defaultAssetCacheBytes
+	}
+	return &imageCache{
+		budget: budget,
+		order:  list.New(),
+		items:  make(map[string]*list.Element),
+	}
+}
+
+// get 获取缓存的图片并标记为最近使用
+func (c *imageCache) get(path string) (image.Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[path]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cachedImage).img, true
+}
+
+// add 缓存图片，超出上限时淘汰最久未使用的图片，单张超过上限的图片不缓存
+func (c *imageCache) add(path string, img image.Image) {
+	size := imageSize(img)
+	if size > c.budget {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[path]; ok {
+		c.used -= elem.Value.(*cachedImage).size
+		c.order.Remove(elem)
+	}
+	c.items[path] = c.order.PushFront(&cachedImage{path: path, img: img, size: size})
+	c.used += size
+
+	for c.used > c.budget {
+		oldest := c.order.Back()
+		item := oldest.Value.(*cachedImage)
+		c.order.Remove(oldest)
+		delete(c.items, item.path)
+		c.used -= item.size
+	}
+}
+
+// imageSize 估算解码后图片占用的内存，按每像素 4 字节计算
+func imageSize(img image.Image) int64 {
+	bounds := img.Bounds()
+	return int64(bounds.Dx()) * int64(bounds.Dy()) * 4
+}
+
+// watch 定期轮询目录变化
+func (l *AssetLibrary) watch() {
+	ticker := time.NewTicker(l.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.Reload()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// scan 递归扫描所有根目录，返回文件的大小和修改时间
+func (l *AssetLibrary) scan() map[string]assetStamp {
+	stamps := make(map[string]assetStamp)
+	for _, root := range l.roots {
+		info, err := os.Stat(root)
+		if err != nil {
+			continue
+		}
+
+		// 根路径本身是文件（如直接指向清单文件）
+		if !info.IsDir() {
+			stamps[root] = assetStamp{size: info.Size(), modTime: info.ModTime()}
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if !isImageFile(path) && !isDataFile(path) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			stamps[filepath.Clean(path)] = assetStamp{size: info.Size(), modTime: info.ModTime()}
+			return nil
+		})
+		if err != nil {
+			logx.Errorf("扫描目录失败: %s, error: %v", root, err)
+		}
+	}
+	return stamps
+}
+
+// decode 从磁盘解码图片，并按配置等比缩小
+func (l *AssetLibrary) decode(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("解码图片失败: %w", err)
+	}
+
+	return fitWithin(img, l.config.MaxWidth, l.config.MaxHeight), nil
+}
+
+// fitWithin 等比缩小图片使其不超过指定尺寸，尺寸为 0 表示不限制
+func fitWithin(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	scale := 1.0
+	if maxWidth > 0 && bounds.Dx() > maxWidth {
+		scale = float64(maxWidth) / float64(bounds.Dx())
+	}
+	if maxHeight > 0 && float64(bounds.Dy())*scale > float64(maxHeight) {
+		scale = float64(maxHeight) / float64(bounds.Dy())
+	}
+	if scale == 1.0 {
+		return img
+	}
+
+	width := int(float64(bounds.Dx())*scale + 0.5)
+	height := int(float64(bounds.Dy())*scale + 0.5)
+	dst := image.NewRGBA(image.Rect(0, 0, max(width, 1), max(height, 1)))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, xdraw.Src, nil)
+	return dst
+}
+
+// isImageFile 是否为支持的图片文件
+func isImageFile(path string) bool {
+	name := strings.ToLower(path)
+	return strings.HasSuffix(name, ".jpg") || strings.HasSuffix(name, ".jpeg") ||
+		strings.HasSuffix(name, ".png")
+}
+
+// isDataFile 是否为标注、清单等数据文件
+func isDataFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
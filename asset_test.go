@@ -0,0 +1,147 @@
+package captcha
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePNG 写入一张透明的 PNG 图片
+func writePNG(t *testing.T, path string, width, height int) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestAssetLibraryCacheEvictsLeastRecentlyUsed 按需加载的图片超出上限时淘汰最久未使用的
+func TestAssetLibraryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	paths := make([]string, 3)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, string(rune('a'+i))+".png")
+		writePNG(t, paths[i], 10, 10)
+	}
+
+	// 每张图片 400 字节，上限只能容纳两张
+	l := NewAssetLibrary(AssetLibraryConfig{CacheBytes: 800})
+	defer l.Close()
+
+	for _, path := range paths[:2] {
+		if _, err := l.Image(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 访问 a 使 b 成为最久未使用的
+	if _, err := l.Image(paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Image(paths[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := l.cache.get(paths[1]); ok {
+		t.Errorf("b 应该被淘汰")
+	}
+	for _, path := range []string{paths[0], paths[2]} {
+		if _, ok := l.cache.get(path); !ok {
+			t.Errorf("%s 应该在缓存中", path)
+		}
+	}
+	if l.cache.used > 800 {
+		t.Errorf("缓存占用 %d 超过上限", l.cache.used)
+	}
+}
+
+// TestAssetLibraryCacheDisabled CacheBytes 小于 0 时不缓存
+func TestAssetLibraryCacheDisabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.png")
+	writePNG(t, path, 10, 10)
+
+	l := NewAssetLibrary(AssetLibraryConfig{CacheBytes: -1})
+	defer l.Close()
+
+	if _, err := l.Image(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.cache.items) != 0 {
+		t.Errorf("不应缓存图片")
+	}
+}
+
+// TestAssetLibraryReloadEvictsBrokenImage 文件变化后解码失败时移除旧图片，不再继续使用
+func TestAssetLibraryReloadEvictsBrokenImage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.png")
+	writePNG(t, path, 10, 10)
+
+	l := NewAssetLibrary(AssetLibraryConfig{}, dir)
+	defer l.Close()
+	if got := l.Images(dir); len(got) != 1 {
+		t.Fatalf("Images() = %v, want 1 image", got)
+	}
+
+	if err := os.WriteFile(path, []byte("not a png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !l.Reload() {
+		t.Fatal("Reload() = false, want true")
+	}
+
+	if got := l.Images(dir); len(got) != 0 {
+		t.Errorf("Images() = %v, want none", got)
+	}
+	if _, err := l.Image(path); err == nil {
+		t.Errorf("Image() 应该返回解码错误")
+	}
+}
+
+// TestServiceScansSceneAssetDirs 服务自建的资源库会扫描场景专用配置中的图片目录
+func TestServiceScansSceneAssetDirs(t *testing.T) {
+	selectDir := t.TempDir()
+	for _, category := range []string{"cat", "dog"} {
+		if err := os.Mkdir(filepath.Join(selectDir, category), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			writePNG(t, filepath.Join(selectDir, category, string(rune('a'+i))+".png"), 20+i, 20)
+		}
+	}
+	backgroundDir := t.TempDir()
+	writePNG(t, filepath.Join(backgroundDir, "bg.png"), 320, 160)
+	gridDir := writeGridScene(t)
+
+	service, err := NewService(NewMemStore(),
+		WithScene("select", SceneConfig{Type: CaptchaTypeImageSelect, ImageSelect: ImageSelectConfig{ImageDir: selectDir, ImageCount: 4, SelectCount: 2}}),
+		WithScene("slide", SceneConfig{Type: SlideTypeSelect, Slide: SlideConfig{ImageDir: backgroundDir}}),
+		WithScene("grid", SceneConfig{Type: CaptchaTypeImageGrid, ImageGrid: ImageGridConfig{ImageDir: gridDir}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	for _, dir := range []string{filepath.Join(selectDir, "cat"), filepath.Join(selectDir, "dog"), backgroundDir, gridDir} {
+		if len(service.assets.Images(dir)) == 0 {
+			t.Errorf("%s 未被扫描", dir)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		resp, err := service.GenerateForScene(context.Background(), "select")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target := resp.Data.(ImageSelectCaptchaData).TargetType; target != "cat" && target != "dog" {
+			t.Fatalf("TargetType = %q, want a category of the scene dataset", target)
+		}
+	}
+}
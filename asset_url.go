@@ -0,0 +1,229 @@
+package captcha
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAssetSignatureInvalid 图片地址签名无效
+	ErrAssetSignatureInvalid = errors.New("captcha asset signature invalid")
+
+	// ErrAssetURLExpired 图片地址已过期
+	ErrAssetURLExpired = errors.New("captcha asset url expired")
+)
+
+// AssetURLConfig 图片地址下发配置
+//
+// 开启后 CaptchaResponse.Data 中的图片不再是 data URI，而是带签名的短期地址，
+// 图片在验证码过期前保存在 Store 中，由 Service.AssetHandler 提供下载。Store 实现了 BytesStore 时
+// 保存原始字节，否则以 JSON 保存，图片字节经过 base64 编码，体积增大约 33%。
+type AssetURLConfig struct {
+	BaseURL    string        `json:",optional"` // 图片地址前缀，需要路由到 AssetHandler，如 https://example.com/api/captcha/asset
+	SigningKey string        `json:",optional"` // 签名密钥（HMAC-SHA256）
+	TTL        time.Duration `json:",optional"` // 地址有效期，为 0 时与验证码过期时间一致
+}
+
+// Asset 验证码图片
+type Asset struct {
+	ContentType string `json:"contentType"` // MIME 类型，如 image/png
+	Data        []byte `json:"data"`        // 图片原始字节
+}
+
+// EnableAssetURLs 开启图片地址下发，需要在开始处理请求前调用
+func (s *Service) EnableAssetURLs(config AssetURLConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	s.assetURLs = &config
+	return nil
+}
+
+// Asset 校验签名后返回验证码图片
+func (s *Service) Asset(ctx context.Context, captchaID, name string, expires int64, signature string) (*Asset, error) {
+	if s.assetURLs == nil {
+		return nil, ErrCaptchaNotFound
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signAsset(captchaID, name, expires))) {
+		return nil, ErrAssetSignatureInvalid
+	}
+	if time.Now().Unix() > expires {
+		return nil, ErrAssetURLExpired
+	}
+	if !validCaptchaID(captchaID) {
+		return nil, ErrCaptchaNotFound
+	}
+
+	return s.loadAsset(ctx, assetKey(captchaID, name))
+}
+
+// AssetHandler 返回下载验证码图片的 HTTP 处理器，路径格式为 {captchaID}/{name}?expires=...&sig=...，
+// 挂载时需要去掉 BaseURL 对应的路径前缀，如 http.StripPrefix("/api/captcha/asset/", service.AssetHandler())
+func (s *Service) AssetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		captchaID, name, ok := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+		if !ok || captchaID == "" || name == "" {
+			http.NotFound(w, r)
+			return
+		}
+		expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		asset, err := s.Asset(r.Context(), captchaID, name, expires, r.URL.Query().Get("sig"))
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrAssetSignatureInvalid):
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		case errors.Is(err, ErrAssetURLExpired), errors.Is(err, ErrCaptchaNotFound):
+			http.Error(w, "Gone", http.StatusGone)
+			return
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		maxAge := expires - time.Now().Unix()
+		w.Header().Set("Content-Type", asset.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(asset.Data)))
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d, immutable", max(maxAge, 0)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(asset.Data)
+		}
+	})
+}
+
+// imageURLs 返回各图片的地址：开启图片地址下发时把图片存入 Store 并返回签名地址，否则返回 data URI
+func (s *Service) imageURLs(ctx context.Context, captchaID string, rendered *renderedCaptcha) (map[string]string, error) {
+	urls := make(map[string]string, len(rendered.images))
+	if s.assetURLs == nil {
+		for name, asset := range rendered.images {
+			urls[name] = asset.dataURI()
+		}
+		return urls, nil
+	}
+
+	ttl := s.assetURLs.TTL
+	if ttl <= 0 || ttl > rendered.expireTime {
+		ttl = rendered.expireTime
+	}
+	expires := time.Now().Add(ttl).Unix()
+
+	for name, asset := range rendered.images {
+		if err := s.storeAsset(ctx, assetKey(captchaID, name), asset, rendered.expireTime); err != nil {
+			return nil, fmt.Errorf("failed to store captcha asset: %w", err)
+		}
+		urls[name] = s.assetURL(captchaID, name, expires)
+	}
+	return urls, nil
+}
+
+// withImageURLs 把图片地址填入验证码数据
+func withImageURLs(data interface{}, urls map[string]string) interface{} {
+	switch d := data.(type) {
+	case CharacterCaptchaData:
+		d.Image = urls["image"]
+		return d
+	case ImageSelectCaptchaData:
+		images := make([]string, len(d.Images))
+		for i := range images {
+			images[i] = urls[imageSelectName(i)]
+		}
+		d.Images = images
+		return d
+	case SlideCaptchaData:
+		d.BackgroundImage = urls["background"]
+		d.TemplateImage = urls["template"]
+		return d
+	case ImageGridCaptchaData:
+		d.Image = urls["image"]
+		return d
+	default:
+		return data
+	}
+}
+
+// imageSelectName 图片选择验证码第 i 张图片的名称
+func imageSelectName(i int) string {
+	return "image-" + strconv.Itoa(i)
+}
+
+// imageBytes 图片编码后的总字节数
+func (r *renderedCaptcha) imageBytes() int {
+	n := 0
+	for _, asset := range r.images {
+		n += len(asset.Data)
+	}
+	return n
+}
+
+// storeAsset 保存图片，Store 实现了 BytesStore 时直接保存 MIME 类型和原始字节，否则保存为 JSON
+func (s *Service) storeAsset(ctx context.Context, key string, asset *Asset, expireTime time.Duration) error {
+	if bytesStore, ok := s.store.(BytesStore); ok {
+		value := make([]byte, 0, len(asset.ContentType)+1+len(asset.Data))
+		value = append(append(append(value, asset.ContentType...), '\n'), asset.Data...)
+		return bytesStore.SetBytes(ctx, key, value, expireTime)
+	}
+	return s.store.Set(ctx, key, asset, expireTime)
+}
+
+// loadAsset 读取 storeAsset 保存的图片
+func (s *Service) loadAsset(ctx context.Context, key string) (*Asset, error) {
+	if bytesStore, ok := s.store.(BytesStore); ok {
+		value, err := bytesStore.GetBytes(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		contentType, data, ok := bytes.Cut(value, []byte("\n"))
+		if !ok {
+			return nil, fmt.Errorf("failed to decode captcha asset: missing content type")
+		}
+		return &Asset{ContentType: string(contentType), Data: data}, nil
+	}
+
+	value, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	var asset Asset
+	if err := json.Unmarshal([]byte(value), &asset); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal captcha asset: %w", err)
+	}
+	return &asset, nil
+}
+
+// assetURL 生成带签名的图片地址
+func (s *Service) assetURL(captchaID, name string, expires int64) string {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("sig", s.signAsset(captchaID, name, expires))
+	return fmt.Sprintf("%s/%s/%s?%s", s.assetURLs.BaseURL, url.PathEscape(captchaID), url.PathEscape(name), query.Encode())
+}
+
+// signAsset 计算图片地址签名
+func (s *Service) signAsset(captchaID, name string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.assetURLs.SigningKey))
+	mac.Write([]byte(captchaID + "/" + name + "/" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,182 @@
+package captcha
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAssetURLTestService 创建开启图片地址下发的服务，生成一个字符验证码并返回图片地址
+func newAssetURLTestService(t *testing.T, store Store) (*Service, *url.URL) {
+	t.Helper()
+	service, err := NewService(store, WithAssetURLs(AssetURLConfig{
+		BaseURL:    "https://example.com/api/captcha/asset/",
+		SigningKey: "secret",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(service.Close)
+
+	resp, err := service.Generate(context.Background(), CaptchaTypeCharacter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := resp.Data.(CharacterCaptchaData).Image
+	if !strings.HasPrefix(image, "https://example.com/api/captcha/asset/"+resp.CaptchaID+"/image?") {
+		t.Fatalf("image = %q, want a signed url", image)
+	}
+	u, err := url.Parse(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, u
+}
+
+// assetParams 从图片地址中解析出 Asset 的参数
+func assetParams(t *testing.T, u *url.URL) (string, string, int64, string) {
+	t.Helper()
+	captchaID, name, _ := strings.Cut(strings.TrimPrefix(u.Path, "/api/captcha/asset/"), "/")
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return captchaID, name, expires, u.Query().Get("sig")
+}
+
+// TestAssetURLSigned 签名地址可以取回 PNG 原始字节，支持 BytesStore 的存储不经过 base64
+func TestAssetURLSigned(t *testing.T) {
+	stores := testStores(t)
+	stores["plain"] = plainStore{store: NewMemStore()}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			service, u := newAssetURLTestService(t, store)
+			captchaID, assetName, expires, sig := assetParams(t, u)
+
+			asset, err := service.Asset(context.Background(), captchaID, assetName, expires, sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if asset.ContentType != "image/png" {
+				t.Errorf("ContentType = %q, want image/png", asset.ContentType)
+			}
+			if !bytes.HasPrefix(asset.Data, []byte("\x89PNG\r\n\x1a\n")) {
+				t.Errorf("Data is not a png image")
+			}
+			if ttl := time.Until(time.Unix(expires, 0)); ttl < 4*time.Minute || ttl > 5*time.Minute+time.Second {
+				t.Errorf("url expires in %s, want the captcha expire time", ttl)
+			}
+
+			if _, ok := store.(BytesStore); !ok {
+				return
+			}
+			raw, err := store.(BytesStore).GetBytes(context.Background(), assetKey(captchaID, assetName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := len("image/png\n") + len(asset.Data); len(raw) != want {
+				t.Errorf("stored %d bytes, want %d", len(raw), want)
+			}
+		})
+	}
+}
+
+// TestAssetURLRejected 签名被篡改、地址过期或验证码ID格式不对时拒绝下载
+func TestAssetURLRejected(t *testing.T) {
+	service, u := newAssetURLTestService(t, NewMemStore())
+	captchaID, name, expires, sig := assetParams(t, u)
+	past := time.Now().Add(-time.Minute).Unix()
+
+	tests := []struct {
+		name      string
+		captchaID string
+		asset     string
+		expires   int64
+		sig       string
+		want      error
+	}{
+		{"签名被篡改", captchaID, name, expires, strings.Repeat("0", len(sig)), ErrAssetSignatureInvalid},
+		{"签名为空", captchaID, name, expires, "", ErrAssetSignatureInvalid},
+		{"延长有效期", captchaID, name, expires + 3600, sig, ErrAssetSignatureInvalid},
+		{"换成其他图片", captchaID, "background", expires, sig, ErrAssetSignatureInvalid},
+		{"地址已过期", captchaID, name, past, service.signAsset(captchaID, name, past), ErrAssetURLExpired},
+		{"ID 不是 UUID", "ticket", name, expires, service.signAsset("ticket", name, expires), ErrCaptchaNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Asset(context.Background(), tt.captchaID, tt.asset, tt.expires, tt.sig); !errors.Is(err, tt.want) {
+				t.Errorf("Asset() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestAssetHandler 下载接口的状态码和响应头
+func TestAssetHandler(t *testing.T) {
+	service, u := newAssetURLTestService(t, NewMemStore())
+	handler := http.StripPrefix("/api/captcha/asset/", service.AssetHandler())
+	tampered := *u
+	query := tampered.Query()
+	query.Set("sig", strings.Repeat("0", 64))
+	tampered.RawQuery = query.Encode()
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantBody   bool
+	}{
+		{"下载", http.MethodGet, u.RequestURI(), http.StatusOK, true},
+		{"HEAD", http.MethodHead, u.RequestURI(), http.StatusOK, false},
+		{"签名被篡改", http.MethodGet, tampered.RequestURI(), http.StatusForbidden, false},
+		{"缺少 expires", http.MethodGet, u.Path, http.StatusNotFound, false},
+		{"缺少图片名", http.MethodGet, "/api/captcha/asset/" + strings.Split(u.Path, "/")[4] + "?" + u.RawQuery, http.StatusNotFound, false},
+		{"不支持的方法", http.MethodPost, u.RequestURI(), http.StatusMethodNotAllowed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != "image/png" {
+				t.Errorf("Content-Type = %q, want image/png", got)
+			}
+			if got := rec.Header().Get("Cache-Control"); !strings.HasPrefix(got, "private, max-age=") {
+				t.Errorf("Cache-Control = %q", got)
+			}
+			if length, _ := strconv.Atoi(rec.Header().Get("Content-Length")); length == 0 {
+				t.Errorf("Content-Length = %q", rec.Header().Get("Content-Length"))
+			}
+			if hasBody := rec.Body.Len() > 0; hasBody != tt.wantBody {
+				t.Errorf("body length = %d, want body %v", rec.Body.Len(), tt.wantBody)
+			}
+		})
+	}
+
+	// 图片已从 Store 删除时返回 410
+	captchaID, name, _, _ := assetParams(t, u)
+	if err := service.store.Del(context.Background(), assetKey(captchaID, name)); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+	if rec.Code != http.StatusGone {
+		t.Errorf("status after delete = %d, want %d", rec.Code, http.StatusGone)
+	}
+}
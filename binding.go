@@ -0,0 +1,148 @@
+package captcha
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+)
+
+// ClientBindingConfig 客户端绑定配置
+//
+// 开启后生成验证码时记录请求方的 IP 网段、User-Agent、会话和设备指纹，
+// 验证时请求方不一致则验证失败，防止验证码被转交给打码平台在其他机器上完成。
+// 生成时记录了会话或设备指纹时，验证请求缺少该字段也视为不一致，中转方不能靠去掉 cookie 或设备指纹绕过。
+type ClientBindingConfig struct {
+	IP         bool `json:",optional"`   // 校验 IP 网段
+	UserAgent  bool `json:",optional"`   // 校验 User-Agent
+	Session    bool `json:",optional"`   // 校验会话 ID
+	Device     bool `json:",optional"`   // 校验设备指纹
+	IPv4Prefix int  `json:",default=24"` // IPv4 网段前缀长度，默认 24，移动网络切换基站时 IP 可能变化；-1 表示不校验 IPv4 地址
+	IPv6Prefix int  `json:",default=48"` // IPv6 网段前缀长度，默认 48；-1 表示不校验 IPv6 地址
+	Strict     bool `json:",optional"`   // 严格模式：生成时记录的 IP 和 User-Agent 在验证时缺失也视为不一致
+}
+
+// bindingPrefixDisabled 网段前缀长度设为该值时不校验对应协议的地址
+const bindingPrefixDisabled = -1
+
+// withDefaults 填充零值字段的默认值
+func (c ClientBindingConfig) withDefaults() ClientBindingConfig {
+	if c.IPv4Prefix == 0 {
+		c.IPv4Prefix = 24
+	}
+	if c.IPv6Prefix == 0 {
+		c.IPv6Prefix = 48
+	}
+	return c
+}
+
+// enabled 是否校验任一字段
+func (c ClientBindingConfig) enabled() bool {
+	return c.IP || c.UserAgent || c.Session || c.Device
+}
+
+// Validate 校验客户端绑定配置
+func (c ClientBindingConfig) Validate() error {
+	c = c.withDefaults()
+	if c.IPv4Prefix < bindingPrefixDisabled || c.IPv4Prefix > 32 {
+		return fmt.Errorf("binding: ipv4 prefix must be -1 or in [1, 32], got %d", c.IPv4Prefix)
+	}
+	if c.IPv6Prefix < bindingPrefixDisabled || c.IPv6Prefix > 128 {
+		return fmt.Errorf("binding: ipv6 prefix must be -1 or in [1, 128], got %d", c.IPv6Prefix)
+	}
+	return nil
+}
+
+// clientBinding 存储在验证码记录中的请求方信息，除 IP 网段外只保存摘要
+type clientBinding struct {
+	IPPrefix  string `json:"ip,omitempty"`      // IP 网段，如 203.0.113.0/24
+	UserAgent string `json:"ua,omitempty"`      // User-Agent 摘要
+	Session   string `json:"session,omitempty"` // 会话 ID 摘要
+	Device    string `json:"device,omitempty"`  // 设备指纹摘要
+}
+
+// bind 按配置提取需要绑定的请求方信息，没有可绑定的字段时返回 nil
+func (c ClientBindingConfig) bind(meta RequestMeta) *clientBinding {
+	c = c.withDefaults()
+
+	var b clientBinding
+	if c.IP {
+		b.IPPrefix = ipPrefix(meta.IP, c.IPv4Prefix, c.IPv6Prefix)
+	}
+	if c.UserAgent {
+		b.UserAgent = bindingDigest(meta.UserAgent)
+	}
+	if c.Session {
+		b.Session = bindingDigest(meta.SessionID)
+	}
+	if c.Device {
+		b.Device = bindingDigest(meta.DeviceID)
+	}
+	if b == (clientBinding{}) {
+		return nil
+	}
+	return &b
+}
+
+// mismatch 返回验证时与生成时不一致的字段名，一致时返回空字符串
+func (c ClientBindingConfig) mismatch(bound *clientBinding, meta RequestMeta) string {
+	if bound == nil {
+		return ""
+	}
+
+	current := c.bind(meta)
+	if current == nil {
+		current = &clientBinding{}
+	}
+
+	fields := []struct {
+		name           string
+		bound, current string
+		required       bool // 验证请求缺少该字段时是否视为不一致
+	}{
+		{"ip", bound.IPPrefix, current.IPPrefix, c.Strict},
+		{"user_agent", bound.UserAgent, current.UserAgent, c.Strict},
+		{"session", bound.Session, current.Session, true},
+		{"device", bound.Device, current.Device, true},
+	}
+	for _, f := range fields {
+		if f.bound == "" || f.bound == f.current {
+			continue
+		}
+		// 宽松模式下验证请求缺少 IP 或 User-Agent 时不校验，会话和设备指纹总是校验
+		if f.current == "" && !f.required {
+			continue
+		}
+		return f.name
+	}
+	return ""
+}
+
+// ipPrefix 返回 IP 所在网段，IP 无效或对应协议不校验时返回空字符串
+func ipPrefix(ip string, v4Prefix, v6Prefix int) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		if v4Prefix == bindingPrefixDisabled {
+			return ""
+		}
+		mask := net.CIDRMask(v4Prefix, 32)
+		return (&net.IPNet{IP: v4.Mask(mask), Mask: mask}).String()
+	}
+	if v6Prefix == bindingPrefixDisabled {
+		return ""
+	}
+	mask := net.CIDRMask(v6Prefix, 128)
+	return (&net.IPNet{IP: parsed.Mask(mask), Mask: mask}).String()
+}
+
+// bindingDigest 计算绑定字段的摘要，值为空时返回空字符串
+func bindingDigest(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:16])
+}
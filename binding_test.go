@@ -0,0 +1,137 @@
+package captcha
+
+import "testing"
+
+// TestClientBindingMismatch 验证时与生成时请求方不一致的字段
+func TestClientBindingMismatch(t *testing.T) {
+	all := ClientBindingConfig{IP: true, UserAgent: true, Session: true, Device: true}
+	generated := RequestMeta{IP: "203.0.113.10", UserAgent: "Mozilla/5.0", SessionID: "s1", DeviceID: "d1"}
+
+	tests := []struct {
+		name   string
+		config ClientBindingConfig
+		meta   RequestMeta // 验证时的请求方
+		want   string
+	}{
+		{name: "一致", config: all, meta: generated},
+		{name: "同一 /24 网段", config: all, meta: RequestMeta{IP: "203.0.113.99", UserAgent: "Mozilla/5.0", SessionID: "s1", DeviceID: "d1"}},
+		{name: "不同网段", config: all, meta: RequestMeta{IP: "198.51.100.1", UserAgent: "Mozilla/5.0", SessionID: "s1", DeviceID: "d1"}, want: "ip"},
+		{name: "User-Agent 不同", config: all, meta: RequestMeta{IP: "203.0.113.10", UserAgent: "curl/8.0", SessionID: "s1", DeviceID: "d1"}, want: "user_agent"},
+		{name: "会话不同", config: all, meta: RequestMeta{IP: "203.0.113.10", UserAgent: "Mozilla/5.0", SessionID: "s2", DeviceID: "d1"}, want: "session"},
+		{name: "宽松模式缺少会话", config: all, meta: RequestMeta{IP: "203.0.113.10", UserAgent: "Mozilla/5.0", DeviceID: "d1"}, want: "session"},
+		{name: "宽松模式缺少设备指纹", config: all, meta: RequestMeta{IP: "203.0.113.10", UserAgent: "Mozilla/5.0", SessionID: "s1"}, want: "device"},
+		{name: "宽松模式缺少 IP 和 User-Agent", config: all, meta: RequestMeta{SessionID: "s1", DeviceID: "d1"}},
+		{
+			name:   "严格模式缺少 IP",
+			config: ClientBindingConfig{IP: true, UserAgent: true, Session: true, Device: true, Strict: true},
+			meta:   RequestMeta{UserAgent: "Mozilla/5.0", SessionID: "s1", DeviceID: "d1"},
+			want:   "ip",
+		},
+		{
+			name:   "严格模式缺少 User-Agent",
+			config: ClientBindingConfig{UserAgent: true, Strict: true},
+			meta:   RequestMeta{IP: "203.0.113.10"},
+			want:   "user_agent",
+		},
+		{
+			name:   "IPv4Prefix 为 -1 不校验 IPv4",
+			config: ClientBindingConfig{IP: true, IPv4Prefix: -1},
+			meta:   RequestMeta{IP: "198.51.100.1"},
+		},
+		{
+			name:   "IPv4Prefix 为 32 校验完整地址",
+			config: ClientBindingConfig{IP: true, IPv4Prefix: 32},
+			meta:   RequestMeta{IP: "203.0.113.11"},
+			want:   "ip",
+		},
+		{
+			name:   "未开启的字段不校验",
+			config: ClientBindingConfig{IP: true},
+			meta:   RequestMeta{IP: "203.0.113.10", UserAgent: "curl/8.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound := tt.config.bind(generated)
+			if got := tt.config.mismatch(bound, tt.meta); got != tt.want {
+				t.Errorf("mismatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClientBindingIPv6 IPv6 按 /48 网段绑定，IPv6Prefix 为 -1 时不校验
+func TestClientBindingIPv6(t *testing.T) {
+	generated := RequestMeta{IP: "2001:db8:1:2::1"}
+	tests := []struct {
+		name   string
+		config ClientBindingConfig
+		ip     string
+		want   string
+	}{
+		{name: "同一 /48", config: ClientBindingConfig{IP: true}, ip: "2001:db8:1:ffff::2"},
+		{name: "不同 /48", config: ClientBindingConfig{IP: true}, ip: "2001:db8:2::1", want: "ip"},
+		{name: "不校验 IPv6", config: ClientBindingConfig{IP: true, IPv6Prefix: -1}, ip: "2001:db8:2::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound := tt.config.bind(generated)
+			if got := tt.config.mismatch(bound, RequestMeta{IP: tt.ip}); got != tt.want {
+				t.Errorf("mismatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClientBindingBind 只保存开启的字段，会话和设备指纹只保存摘要
+func TestClientBindingBind(t *testing.T) {
+	meta := RequestMeta{IP: "203.0.113.10", UserAgent: "Mozilla/5.0", SessionID: "s1", DeviceID: "d1"}
+
+	if b := (ClientBindingConfig{}).bind(meta); b != nil {
+		t.Errorf("bind() without fields = %+v, want nil", b)
+	}
+	if b := (ClientBindingConfig{Session: true}).bind(RequestMeta{}); b != nil {
+		t.Errorf("bind() with empty meta = %+v, want nil", b)
+	}
+
+	b := ClientBindingConfig{IP: true, Session: true}.bind(meta)
+	if b == nil {
+		t.Fatal("bind() = nil")
+	}
+	if b.IPPrefix != "203.0.113.0/24" {
+		t.Errorf("IPPrefix = %q, want 203.0.113.0/24", b.IPPrefix)
+	}
+	if b.Session == "" || b.Session == meta.SessionID {
+		t.Errorf("Session = %q, want digest", b.Session)
+	}
+	if b.UserAgent != "" || b.Device != "" {
+		t.Errorf("未开启的字段被保存: %+v", b)
+	}
+}
+
+// TestClientBindingValidate 网段前缀长度只能是 -1 或有效范围
+func TestClientBindingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ClientBindingConfig
+		wantErr bool
+	}{
+		{name: "默认", config: ClientBindingConfig{IP: true}},
+		{name: "不校验 IPv4", config: ClientBindingConfig{IPv4Prefix: -1}},
+		{name: "IPv4 /32", config: ClientBindingConfig{IPv4Prefix: 32}},
+		{name: "IPv4 过长", config: ClientBindingConfig{IPv4Prefix: 33}, wantErr: true},
+		{name: "IPv4 小于 -1", config: ClientBindingConfig{IPv4Prefix: -2}, wantErr: true},
+		{name: "IPv6 /128", config: ClientBindingConfig{IPv6Prefix: 128}},
+		{name: "IPv6 过长", config: ClientBindingConfig{IPv6Prefix: 129}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package solver
+
+import (
+	"image"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultCharset 字符验证码攻击默认尝试的字符集，验证不区分大小写，只需要大写字母
+const DefaultCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+const (
+	// glyphScale 与 CharacterCaptcha.drawText 一致的放大倍数
+	glyphScale = 2.5
+	// glyphBlock 每个原始像素放大后绘制的方块边长
+	glyphBlock = 2
+)
+
+// CharacterSolver 字符验证码攻击：用 basicfont 渲染字符模板，在每个字符位置附近做模板匹配
+//
+// 攻击者已知验证码的公开绘制方式（字体、放大倍数、字符间距），这是开源项目面临的真实情况。
+type CharacterSolver struct {
+	templates []glyphTemplate
+}
+
+// glyphTemplate 单个字符模板
+type glyphTemplate struct {
+	char          byte
+	points        []image.Point // 笔画像素
+	width, height int           // 模板包围盒尺寸
+}
+
+// NewCharacterSolver 创建字符验证码攻击器，charset 为空时使用 DefaultCharset
+func NewCharacterSolver(charset string) *CharacterSolver {
+	if charset == "" {
+		charset = DefaultCharset
+	}
+
+	s := &CharacterSolver{}
+	for i := 0; i < len(charset); i++ {
+		s.templates = append(s.templates, renderGlyph(charset[i]))
+	}
+	return s
+}
+
+// Solve 识别验证码图片中的 length 个字符
+func (s *CharacterSolver) Solve(img image.Image, length int) string {
+	ink := inkMap(img)
+	height := len(ink)
+	if height == 0 || length <= 0 {
+		return ""
+	}
+	width := len(ink[0])
+
+	// 与 drawText 相同的布局：x = 10 + i*charWidth + [0,10)，y = height/4 + [0,5)
+	charWidth := (width - 20) / length
+	baseY := height / 4
+
+	var answer strings.Builder
+	for i := 0; i < length; i++ {
+		baseX := 10 + i*charWidth
+		best, bestScore := byte('?'), -1.0
+		for _, tmpl := range s.templates {
+			for y := baseY - 2; y <= baseY+6; y++ {
+				for x := baseX - 2; x <= baseX+11; x++ {
+					if score := tmpl.score(ink, x, y); score > bestScore {
+						best, bestScore = tmpl.char, score
+					}
+				}
+			}
+		}
+		answer.WriteByte(best)
+	}
+	return answer.String()
+}
+
+// score 模板放在 (x, y) 处的匹配度：笔画命中率减去包围盒内多余墨迹的比例
+func (t glyphTemplate) score(ink [][]bool, x, y int) float64 {
+	height, width := len(ink), len(ink[0])
+	at := func(px, py int) bool {
+		return px >= 0 && py >= 0 && px < width && py < height && ink[py][px]
+	}
+
+	hits := 0
+	for _, p := range t.points {
+		if at(x+p.X, y+p.Y) {
+			hits++
+		}
+	}
+
+	boxInk := 0
+	for py := 0; py < t.height; py++ {
+		for px := 0; px < t.width; px++ {
+			if at(x+px, y+py) {
+				boxInk++
+			}
+		}
+	}
+
+	extra := boxInk - hits
+	background := t.width*t.height - len(t.points)
+	return float64(hits)/float64(len(t.points)) - float64(extra)/float64(background)
+}
+
+// renderGlyph 按 drawText 的方式渲染放大后的字符
+func renderGlyph(char byte) glyphTemplate {
+	tmp := image.NewAlpha(image.Rect(0, 0, 8, 13))
+	d := font.Drawer{
+		Dst:  tmp,
+		Src:  image.Opaque,
+		Face: basicfont.Face7x13,
+		Dot:  fixed.Point26_6{X: fixed.I(0), Y: fixed.I(12)},
+	}
+	d.DrawString(string(char))
+
+	seen := make(map[image.Point]bool)
+	tmpl := glyphTemplate{char: char}
+	for ty := 0; ty < 13; ty++ {
+		for tx := 0; tx < 8; tx++ {
+			if tmp.AlphaAt(tx, ty).A == 0 {
+				continue
+			}
+			for sy := 0; sy < glyphBlock; sy++ {
+				for sx := 0; sx < glyphBlock; sx++ {
+					p := image.Pt(int(float64(tx)*glyphScale)+sx, int(float64(ty)*glyphScale)+sy)
+					if !seen[p] {
+						seen[p] = true
+						tmpl.points = append(tmpl.points, p)
+						tmpl.width = max(tmpl.width, p.X+1)
+						tmpl.height = max(tmpl.height, p.Y+1)
+					}
+				}
+			}
+		}
+	}
+	return tmpl
+}
+
+// inkMap 二值化：文字颜色的每个通道都小于 128，据此区分文字和白色背景
+func inkMap(img image.Image) [][]bool {
+	bounds := img.Bounds()
+	ink := make([][]bool, bounds.Dy())
+	for y := range ink {
+		ink[y] = make([]bool, bounds.Dx())
+		for x := range ink[y] {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			ink[y][x] = a > 0x8000 && r < 0x8000 && g < 0x8000 && b < 0x8000
+		}
+	}
+	return ink
+}
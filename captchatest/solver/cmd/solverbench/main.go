@@ -0,0 +1,220 @@
+// solverbench 统计各自动化攻击方法对当前验证码配置的破解成功率
+//
+// 用法：
+//
+//	go run ./captchatest/solver/cmd/solverbench -n 200 -complexity 3 -select-dir ./web/images/traffic -perturb-flip
+//
+// 修改 drawText、cutHole 或图片扰动后运行一次，对比成功率的变化。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"math/rand"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gpencil/captcha"
+	"github.com/gpencil/captcha/captchatest/solver"
+)
+
+// result 单个攻击方法的统计结果
+type result struct {
+	attacker string
+	attempts int
+	solved   int
+	failures int // 生成或解码失败的次数
+	elapsed  time.Duration
+}
+
+func main() {
+	n := flag.Int("n", 200, "每种攻击方法的尝试次数")
+	seed := flag.Int64("seed", 0, "随机种子，0 表示使用 crypto/rand")
+
+	length := flag.Int("length", 4, "字符验证码长度")
+	complexity := flag.Int("complexity", 2, "字符验证码复杂度（1-3）")
+	charset := flag.String("charset", "", "OCR 尝试的字符集，默认数字加大写字母")
+
+	slideDir := flag.String("slide-dir", "", "滑动验证码背景图片目录")
+	templateDir := flag.String("template-dir", "", "滑动验证码滑块模板目录")
+
+	selectDir := flag.String("select-dir", "", "图片选择验证码图片目录或清单文件")
+	imageCount := flag.Int("image-count", 4, "图片选择验证码选项图片数量")
+	train := flag.Int("train", 500, "攻击者事先收集并标注的验证码数量，用于构建感知哈希索引")
+	maxDistance := flag.Int("max-distance", solver.DefaultMaxDistance, "感知哈希汉明距离上限")
+
+	perturb := captcha.PerturbConfig{}
+	flag.Float64Var(&perturb.CropRatio, "perturb-crop", 0, "图片扰动：随机裁剪比例")
+	flag.Float64Var(&perturb.ScaleMin, "perturb-scale-min", 0, "图片扰动：缩放下限")
+	flag.Float64Var(&perturb.ScaleMax, "perturb-scale-max", 0, "图片扰动：缩放上限")
+	flag.Float64Var(&perturb.HueShift, "perturb-hue", 0, "图片扰动：色相偏移上限（度）")
+	flag.IntVar(&perturb.Noise, "perturb-noise", 0, "图片扰动：噪声强度")
+	flag.IntVar(&perturb.JPEGQuality, "perturb-jpeg", 0, "图片扰动：JPEG 重压缩质量")
+	flag.Float64Var(&perturb.MaxRotate, "perturb-rotate", 0, "图片扰动：旋转角度上限（度）")
+	flag.BoolVar(&perturb.Flip, "perturb-flip", false, "图片扰动：随机水平翻转")
+	flag.Parse()
+
+	source := func(offset int64) rand.Source {
+		if *seed == 0 {
+			return nil
+		}
+		return captcha.NewSeededSource(*seed + offset)
+	}
+
+	results := []result{
+		benchCharacter(*n, captcha.CharacterConfig{
+			Length:     *length,
+			Complexity: *complexity,
+			Rand:       source(1),
+		}, solver.NewCharacterSolver(*charset)),
+		benchSlide(*n, captcha.SlideConfig{
+			ImageDir:    *slideDir,
+			TemplateDir: *templateDir,
+			Rand:        source(2),
+		}),
+		benchImageSelect(*n, *train, captcha.ImageSelectConfig{
+			ImageCount: *imageCount,
+			ImageDir:   *selectDir,
+			Perturb:    perturb,
+			Rand:       source(3),
+		}, *maxDistance),
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "attacker\tattempts\tsolved\trate\terrors\tavg time\t")
+	for _, r := range results {
+		rate := 0.0
+		if r.attempts > 0 {
+			rate = float64(r.solved) / float64(r.attempts) * 100
+		}
+		avg := time.Duration(0)
+		if r.attempts > 0 {
+			avg = r.elapsed / time.Duration(r.attempts)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f%%\t%d\t%s\t\n", r.attacker, r.attempts, r.solved, rate, r.failures, avg.Round(time.Microsecond))
+	}
+	w.Flush()
+}
+
+// benchCharacter 模板匹配 OCR 攻击字符验证码
+func benchCharacter(n int, config captcha.CharacterConfig, s *solver.CharacterSolver) result {
+	generator := captcha.NewCharacterCaptcha(config)
+	r := result{attacker: "character/ocr"}
+	for i := 0; i < n; i++ {
+		code, dataURI, err := generator.Generate()
+		if err != nil {
+			r.failures++
+			continue
+		}
+		img, err := solver.DecodeDataURI(dataURI)
+		if err != nil {
+			r.failures++
+			continue
+		}
+
+		start := time.Now()
+		answer := s.Solve(img, len(code))
+		r.elapsed += time.Since(start)
+		r.attempts++
+		if generator.Verify(code, answer) {
+			r.solved++
+		}
+	}
+	return r
+}
+
+// benchSlide 边缘检测攻击滑动验证码，轨迹和耗时按真人范围伪造
+func benchSlide(n int, config captcha.SlideConfig) result {
+	generator := captcha.NewSlideCaptcha(config)
+	s := solver.NewSlideSolver()
+	r := result{attacker: "slide/edge"}
+	for i := 0; i < n; i++ {
+		backgroundURI, templateURI, _, targetX, err := generator.Generate()
+		if err != nil {
+			r.failures++
+			continue
+		}
+		background, err := solver.DecodeDataURI(backgroundURI)
+		if err != nil {
+			r.failures++
+			continue
+		}
+		template, err := solver.DecodeDataURI(templateURI)
+		if err != nil {
+			r.failures++
+			continue
+		}
+
+		start := time.Now()
+		x := s.Solve(background, template)
+		r.elapsed += time.Since(start)
+		r.attempts++
+
+		answer := captcha.SlideAnswer{X: x, Duration: 1200}
+		for step := 0; step <= 20; step++ {
+			answer.Track = append(answer.Track, x*step/20)
+		}
+		if generator.Verify(targetX, answer) {
+			r.solved++
+		}
+	}
+	return r
+}
+
+// benchImageSelect 感知哈希攻击图片选择验证码
+//
+// 攻击者先收集 train 个下发过的验证码并人工标注目标图片，构建索引后再攻击新的验证码。
+func benchImageSelect(n, train int, config captcha.ImageSelectConfig, maxDistance int) result {
+	generator := captcha.NewImageSelectCaptcha(config)
+	index := solver.NewImageIndex()
+	r := result{attacker: "image_select/phash"}
+
+	for i := 0; i < train; i++ {
+		_, targetType, dataURIs, targetIndexes, err := generator.Generate()
+		if err != nil {
+			log.Printf("收集训练数据失败: %v", err)
+			continue
+		}
+		for _, idx := range targetIndexes {
+			img, err := solver.DecodeDataURI(dataURIs[idx])
+			if err != nil {
+				continue
+			}
+			index.Add(targetType, img)
+		}
+	}
+
+	s := &solver.ImageSelectSolver{Index: index, MaxDistance: maxDistance}
+	for i := 0; i < n; i++ {
+		_, targetType, dataURIs, targetIndexes, err := generator.Generate()
+		if err != nil {
+			r.failures++
+			continue
+		}
+
+		images := make([]image.Image, 0, len(dataURIs))
+		for _, dataURI := range dataURIs {
+			img, err := solver.DecodeDataURI(dataURI)
+			if err != nil {
+				break
+			}
+			images = append(images, img)
+		}
+		if len(images) != len(dataURIs) {
+			r.failures++
+			continue
+		}
+
+		start := time.Now()
+		selected := s.Solve(targetType, images)
+		r.elapsed += time.Since(start)
+		r.attempts++
+		if generator.Verify(targetIndexes, selected) {
+			r.solved++
+		}
+	}
+	return r
+}
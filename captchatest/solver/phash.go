@@ -0,0 +1,136 @@
+package solver
+
+import (
+	"image"
+	"math"
+	"math/bits"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// DefaultMaxDistance 感知哈希汉明距离不超过该值时认为是同一张图片
+const DefaultMaxDistance = 10
+
+// ImageIndex 感知哈希图片索引，模拟攻击者爬取并人工标注过的数据集
+type ImageIndex struct {
+	entries []indexEntry
+}
+
+// indexEntry 索引中的一张图片
+type indexEntry struct {
+	hash  uint64
+	label string
+}
+
+// NewImageIndex 创建空索引
+func NewImageIndex() *ImageIndex {
+	return &ImageIndex{}
+}
+
+// Add 添加一张已标注的图片
+func (i *ImageIndex) Add(label string, img image.Image) {
+	i.entries = append(i.entries, indexEntry{hash: PHash(img), label: label})
+}
+
+// Len 索引中的图片数量
+func (i *ImageIndex) Len() int {
+	return len(i.entries)
+}
+
+// Lookup 查找最相似的图片，返回其标注和汉明距离，索引为空时 ok 为 false
+func (i *ImageIndex) Lookup(img image.Image) (label string, distance int, ok bool) {
+	hash := PHash(img)
+	distance = math.MaxInt
+	for _, entry := range i.entries {
+		if d := HammingDistance(hash, entry.hash); d < distance {
+			label, distance, ok = entry.label, d, true
+		}
+	}
+	return label, distance, ok
+}
+
+// ImageSelectSolver 图片选择验证码攻击：在索引中查找每张图片，选中标注与目标类型一致的图片
+type ImageSelectSolver struct {
+	Index       *ImageIndex
+	MaxDistance int // 汉明距离上限，为 0 时使用 DefaultMaxDistance
+}
+
+// Solve 返回认为属于 targetType 的图片索引
+func (s *ImageSelectSolver) Solve(targetType string, images []image.Image) []int {
+	maxDistance := s.MaxDistance
+	if maxDistance <= 0 {
+		maxDistance = DefaultMaxDistance
+	}
+
+	var selected []int
+	for i, img := range images {
+		label, distance, ok := s.Index.Lookup(img)
+		if ok && label == targetType && distance <= maxDistance {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// PHash 计算 64 位感知哈希：缩小到 32×32 灰度图，取二维 DCT 左上角 8×8 低频系数（不含直流分量）与中位数比较
+func PHash(img image.Image) uint64 {
+	const size, low = 32, 8
+
+	small := image.NewRGBA(image.Rect(0, 0, size, size))
+	xdraw.BiLinear.Scale(small, small.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	gray := grayscale(small)
+
+	// 可分离的二维 DCT，只计算需要的低频部分
+	rows := make([][low]float64, size)
+	for y := 0; y < size; y++ {
+		for u := 0; u < low; u++ {
+			sum := 0.0
+			for x := 0; x < size; x++ {
+				sum += gray[y][x] * math.Cos(float64((2*x+1)*u)*math.Pi/(2*size))
+			}
+			rows[y][u] = sum
+		}
+	}
+	var coeffs []float64
+	for v := 0; v < low; v++ {
+		for u := 0; u < low; u++ {
+			sum := 0.0
+			for y := 0; y < size; y++ {
+				sum += rows[y][u] * math.Cos(float64((2*y+1)*v)*math.Pi/(2*size))
+			}
+			coeffs = append(coeffs, sum)
+		}
+	}
+
+	// 去掉直流分量后取中位数
+	ac := append([]float64(nil), coeffs[1:]...)
+	median := medianOf(ac)
+
+	var hash uint64
+	for i, c := range coeffs {
+		if c > median {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// HammingDistance 两个哈希不同的位数
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// medianOf 中位数，会修改入参顺序
+func medianOf(values []float64) float64 {
+	// 插入排序，最多 63 个元素
+	for i := 1; i < len(values); i++ {
+		for j := i; j > 0 && values[j] < values[j-1]; j-- {
+			values[j], values[j-1] = values[j-1], values[j]
+		}
+	}
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
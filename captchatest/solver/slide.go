@@ -0,0 +1,86 @@
+package solver
+
+import (
+	"image"
+	"math"
+)
+
+// SlideSolver 滑动验证码攻击：对背景图做边缘检测，寻找与滑块轮廓最吻合的位置
+type SlideSolver struct{}
+
+// NewSlideSolver 创建滑动验证码攻击器
+func NewSlideSolver() *SlideSolver {
+	return &SlideSolver{}
+}
+
+// Solve 返回缺口的 X 坐标
+func (s *SlideSolver) Solve(background, template image.Image) int {
+	contour := templateContour(template)
+	if len(contour) == 0 {
+		return 0
+	}
+	edges := sobel(grayscale(background))
+
+	height, width := len(edges), len(edges[0])
+	tb := template.Bounds()
+	bestX, bestScore := 0, -1.0
+	for y := 0; y+tb.Dy() <= height; y++ {
+		for x := 0; x+tb.Dx() <= width; x++ {
+			score := 0.0
+			for _, p := range contour {
+				score += edges[y+p.Y][x+p.X]
+			}
+			if score > bestScore {
+				bestX, bestScore = x, score
+			}
+		}
+	}
+	return bestX
+}
+
+// templateContour 滑块形状的轮廓点：不透明且至少有一个相邻像素透明，忽略模板图片最外圈的边框
+func templateContour(template image.Image) []image.Point {
+	bounds := template.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	inside := func(x, y int) bool {
+		if x <= 0 || y <= 0 || x >= width-1 || y >= height-1 {
+			return false
+		}
+		_, _, _, a := template.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return a >= 0x8000
+	}
+
+	var contour []image.Point
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			if inside(x, y) && (!inside(x-1, y) || !inside(x+1, y) || !inside(x, y-1) || !inside(x, y+1)) {
+				contour = append(contour, image.Pt(x, y))
+			}
+		}
+	}
+	return contour
+}
+
+// sobel 计算亮度梯度幅值
+func sobel(gray [][]float64) [][]float64 {
+	height := len(gray)
+	width := 0
+	if height > 0 {
+		width = len(gray[0])
+	}
+
+	edges := make([][]float64, height)
+	for y := range edges {
+		edges[y] = make([]float64, width)
+	}
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			gx := gray[y-1][x+1] + 2*gray[y][x+1] + gray[y+1][x+1] -
+				gray[y-1][x-1] - 2*gray[y][x-1] - gray[y+1][x-1]
+			gy := gray[y+1][x-1] + 2*gray[y+1][x] + gray[y+1][x+1] -
+				gray[y-1][x-1] - 2*gray[y-1][x] - gray[y-1][x+1]
+			edges[y][x] = math.Hypot(gx, gy)
+		}
+	}
+	return edges
+}
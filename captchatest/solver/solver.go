@@ -0,0 +1,59 @@
+// Package solver 验证码自攻击工具
+//
+// 包含几种朴素的自动化攻击方法：字符验证码的 basicfont 模板匹配 OCR、滑动验证码的边缘检测缺口定位、
+// 图片选择验证码的感知哈希查找。攻击者只使用前端能拿到的数据（图片、问题、目标类型），
+// 配合 cmd/solverbench 统计各攻击方法的成功率，用于评估绘制逻辑和图片扰动的改动是否真正提高了破解难度。
+//
+// 仅用于测试自己部署的验证码，不要用于攻击他人的服务。
+package solver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+)
+
+// DecodeDataURI 解码 data:<mime>;base64,<payload> 格式的图片
+func DecodeDataURI(dataURI string) (image.Image, error) {
+	_, payload, ok := strings.Cut(dataURI, ";base64,")
+	if !ok {
+		return nil, fmt.Errorf("not a base64 data uri")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data uri: %w", err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// luminance 把像素合成到白色背景上后计算亮度（0-255）
+func luminance(c color.Color) float64 {
+	r, g, b, a := c.RGBA()
+	white := float64(0xffff - a)
+	return (0.299*(float64(r)+white) + 0.587*(float64(g)+white) + 0.114*(float64(b)+white)) / 257
+}
+
+// grayscale 转换为亮度矩阵，坐标从 (0,0) 开始
+func grayscale(img image.Image) [][]float64 {
+	bounds := img.Bounds()
+	gray := make([][]float64, bounds.Dy())
+	for y := range gray {
+		gray[y] = make([]float64, bounds.Dx())
+		for x := range gray[y] {
+			gray[y][x] = luminance(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return gray
+}
@@ -0,0 +1,141 @@
+package solver_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/gpencil/captcha"
+	"github.com/gpencil/captcha/captchatest/solver"
+)
+
+// decode 解码 data URI 图片
+func decode(t *testing.T, dataURI string) image.Image {
+	t.Helper()
+	img, err := solver.DecodeDataURI(dataURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// TestCharacterSolver 模板匹配 OCR 能识别固定种子生成的低复杂度字符验证码
+func TestCharacterSolver(t *testing.T) {
+	generator := captcha.NewCharacterCaptcha(captcha.CharacterConfig{Complexity: 1, Rand: captcha.NewSeededSource(1)})
+	s := solver.NewCharacterSolver("")
+
+	const n = 10
+	solved := 0
+	for i := 0; i < n; i++ {
+		code, dataURI, err := generator.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		answer := s.Solve(decode(t, dataURI), len(code))
+		if len(answer) != len(code) {
+			t.Fatalf("Solve() = %q, want %d characters", answer, len(code))
+		}
+		if generator.Verify(code, answer) {
+			solved++
+		}
+	}
+	if solved < n*8/10 {
+		t.Errorf("solved %d/%d, want at least 80%%", solved, n)
+	}
+}
+
+// TestSlideSolver 边缘检测能定位固定种子生成的滑动验证码缺口
+func TestSlideSolver(t *testing.T) {
+	generator := captcha.NewSlideCaptcha(captcha.SlideConfig{Rand: captcha.NewSeededSource(1)})
+	s := solver.NewSlideSolver()
+
+	const n = 10
+	solved := 0
+	for i := 0; i < n; i++ {
+		backgroundURI, templateURI, _, targetX, err := generator.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		x := s.Solve(decode(t, backgroundURI), decode(t, templateURI))
+
+		answer := captcha.SlideAnswer{X: x, Duration: 1200}
+		for step := 0; step <= 20; step++ {
+			answer.Track = append(answer.Track, x*step/20)
+		}
+		if generator.Verify(targetX, answer) {
+			solved++
+		}
+	}
+	if solved < n/2 {
+		t.Errorf("solved %d/%d, want at least 50%%", solved, n)
+	}
+}
+
+// TestImageSelectSolver 用下发过的验证码构建索引后，感知哈希能识别未扰动的图片
+func TestImageSelectSolver(t *testing.T) {
+	generator := captcha.NewImageSelectCaptcha(captcha.ImageSelectConfig{Rand: captcha.NewSeededSource(1)})
+	index := solver.NewImageIndex()
+
+	for i := 0; i < 20; i++ {
+		_, targetType, dataURIs, targetIndexes, err := generator.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, idx := range targetIndexes {
+			index.Add(targetType, decode(t, dataURIs[idx]))
+		}
+	}
+	if index.Len() == 0 {
+		t.Fatal("index is empty")
+	}
+
+	s := &solver.ImageSelectSolver{Index: index}
+	const n = 10
+	solved := 0
+	for i := 0; i < n; i++ {
+		_, targetType, dataURIs, targetIndexes, err := generator.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		images := make([]image.Image, len(dataURIs))
+		for j, dataURI := range dataURIs {
+			images[j] = decode(t, dataURI)
+		}
+		if generator.Verify(targetIndexes, s.Solve(targetType, images)) {
+			solved++
+		}
+	}
+	if solved < n*8/10 {
+		t.Errorf("solved %d/%d, want at least 80%%", solved, n)
+	}
+}
+
+// TestImageIndexLookup 空索引查找失败，相同图片汉明距离为 0
+func TestImageIndexLookup(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, color.Gray{Y: uint8(x * 8)})
+		}
+	}
+
+	index := solver.NewImageIndex()
+	if _, _, ok := index.Lookup(img); ok {
+		t.Error("Lookup() on empty index ok = true")
+	}
+
+	index.Add("gradient", img)
+	label, distance, ok := index.Lookup(img)
+	if !ok || label != "gradient" || distance != 0 {
+		t.Errorf("Lookup() = %q, %d, %v, want gradient, 0, true", label, distance, ok)
+	}
+}
+
+// TestDecodeDataURI 不是 base64 data URI 或图片无法解码时返回错误
+func TestDecodeDataURI(t *testing.T) {
+	for _, dataURI := range []string{"", "https://example.com/a.png", "data:image/png;base64,!!!", "data:image/png;base64,aGVsbG8="} {
+		if _, err := solver.DecodeDataURI(dataURI); err == nil {
+			t.Errorf("DecodeDataURI(%q) error = nil", dataURI)
+		}
+	}
+}
@@ -1,13 +1,9 @@
 package captcha
 
 import (
-	"bytes"
-	"encoding/base64"
-	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
 	"math/rand"
 	"strings"
 	"time"
@@ -20,54 +16,67 @@ import (
 
 // CharacterCaptcha 字符验证码
 type CharacterCaptcha struct {
-	config CharacterConfig
+	config  CharacterConfig
+	encoder Encoder
+	rng     *rand.Rand
 }
 
 // NewCharacterCaptcha 创建字符验证码
 func NewCharacterCaptcha(config CharacterConfig) *CharacterCaptcha {
-	if config.Width == 0 {
-		config.Width = 160
+	config = config.withDefaults()
+
+	return &CharacterCaptcha{
+		config:  config,
+		encoder: NewEncoder(config.Encode),
+		rng:     newRand(config.Rand),
 	}
-	if config.Height == 0 {
-		config.Height = 60
+}
+
+// withDefaults 填充零值字段的默认值
+func (c CharacterConfig) withDefaults() CharacterConfig {
+	if c.Width == 0 {
+		c.Width = 160
 	}
-	if config.Length == 0 {
-		config.Length = 4
+	if c.Height == 0 {
+		c.Height = 60
 	}
-	if config.ExpireTime == 0 {
-		config.ExpireTime = 5 * time.Minute
+	if c.Length == 0 {
+		c.Length = 4
 	}
-	if config.Complexity == 0 {
-		config.Complexity = 2
+	if c.ExpireTime == 0 {
+		c.ExpireTime = 5 * time.Minute
 	}
-
-	return &CharacterCaptcha{
-		config: config,
+	if c.Complexity == 0 {
+		c.Complexity = 2
 	}
+	return c
 }
 
-// Generate 生成验证码
+// Generate 生成验证码，返回验证码和 data URI 格式的图片
 func (c *CharacterCaptcha) Generate() (string, string, error) {
+	code, encoded, err := c.generate()
+	if err != nil {
+		return "", "", err
+	}
+	return code, encoded.dataURI(), nil
+}
+
+// generate 生成验证码，返回验证码和编码后的图片
+func (c *CharacterCaptcha) generate() (string, *Asset, error) {
 	// 生成随机验证码
 	code := c.generateCode()
 
-	// 生成图片
-	imageBytes, err := c.generateImage(code)
+	// 生成图片并编码
+	encoded, err := encodeImage(c.encoder, c.generateImage(code))
 	if err != nil {
-		return "", "", err
+		return "", nil, err
 	}
 
-	// 转换为 Base64
-	base64Image := base64.StdEncoding.EncodeToString(imageBytes)
-	// 添加 data URI 前缀
-	base64Image = "data:image/png;base64," + base64Image
-
-	return code, base64Image, nil
+	return code, encoded, nil
 }
 
 // Verify 验证验证码
 func (c *CharacterCaptcha) Verify(code, answer string) bool {
-	fmt.Println("验证验证码:", code, "===", answer)
 	// 不区分大小写比较
 	return strings.EqualFold(code, answer)
 }
@@ -75,17 +84,16 @@ func (c *CharacterCaptcha) Verify(code, answer string) bool {
 // generateCode 生成随机验证码
 func (c *CharacterCaptcha) generateCode() string {
 	charset := "23456789ABCDEFGHKMNPRSTUVWXYZ"
-	rand.Seed(time.Now().UnixNano())
 	code := make([]byte, c.config.Length)
 	for i := range code {
-		code[i] = charset[rand.Intn(len(charset))]
+		code[i] = charset[c.rng.Intn(len(charset))]
 	}
 
 	return string(code)
 }
 
 // generateImage 生成验证码图片
-func (c *CharacterCaptcha) generateImage(code string) ([]byte, error) {
+func (c *CharacterCaptcha) generateImage(code string) *image.RGBA {
 	// 创建 RGBA 图片
 	img := image.NewRGBA(image.Rect(0, 0, c.config.Width, c.config.Height))
 
@@ -101,14 +109,7 @@ func (c *CharacterCaptcha) generateImage(code string) ([]byte, error) {
 	// 添加文字
 	c.drawText(img, code)
 
-	// 编码为 PNG
-	var buf bytes.Buffer
-	err := png.Encode(&buf, img)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encode image: %w", err)
-	}
-
-	return buf.Bytes(), nil
+	return img
 }
 
 // drawText 绘制文字
@@ -123,18 +124,16 @@ func (c *CharacterCaptcha) drawText(img *image.RGBA, code string) {
 	charWidth := (c.config.Width - 20) / c.config.Length
 	charHeight := c.config.Height / 4 // 调整为 1/4，让字体位置更靠上，/3往下， /5往上
 
-	rand.Seed(time.Now().UnixNano())
-
 	for i, ch := range code {
 		// 随机位置
-		x := 10 + i*charWidth + rand.Intn(10)
-		y := charHeight + rand.Intn(5)
+		x := 10 + i*charWidth + c.rng.Intn(10)
+		y := charHeight + c.rng.Intn(5)
 
 		// 随机颜色
 		textColor := color.RGBA{
-			R: uint8(rand.Intn(128)),
-			G: uint8(rand.Intn(128)),
-			B: uint8(rand.Intn(128)),
+			R: uint8(c.rng.Intn(128)),
+			G: uint8(c.rng.Intn(128)),
+			B: uint8(c.rng.Intn(128)),
 			A: 255,
 		}
 
@@ -174,17 +173,16 @@ func (c *CharacterCaptcha) drawText(img *image.RGBA, code string) {
 
 // addNoise 添加噪点
 func (c *CharacterCaptcha) addNoise(img *image.RGBA) {
-	rand.Seed(time.Now().UnixNano())
 	noiseDensity := 50 * c.config.Complexity
 
 	for i := 0; i < noiseDensity; i++ {
-		x := rand.Intn(c.config.Width)
-		y := rand.Intn(c.config.Height)
+		x := c.rng.Intn(c.config.Width)
+		y := c.rng.Intn(c.config.Height)
 
 		img.Set(x, y, color.RGBA{
-			R: uint8(rand.Intn(256)),
-			G: uint8(rand.Intn(256)),
-			B: uint8(rand.Intn(256)),
+			R: uint8(c.rng.Intn(256)),
+			G: uint8(c.rng.Intn(256)),
+			B: uint8(c.rng.Intn(256)),
 			A: 255,
 		})
 	}
@@ -192,19 +190,18 @@ func (c *CharacterCaptcha) addNoise(img *image.RGBA) {
 
 // addLines 添加干扰线
 func (c *CharacterCaptcha) addLines(img *image.RGBA) {
-	rand.Seed(time.Now().UnixNano())
 	lineCount := c.config.Complexity * 2
 
 	for i := 0; i < lineCount; i++ {
-		x1 := rand.Intn(c.config.Width)
-		y1 := rand.Intn(c.config.Height)
-		x2 := rand.Intn(c.config.Width)
-		y2 := rand.Intn(c.config.Height)
+		x1 := c.rng.Intn(c.config.Width)
+		y1 := c.rng.Intn(c.config.Height)
+		x2 := c.rng.Intn(c.config.Width)
+		y2 := c.rng.Intn(c.config.Height)
 
 		lineColor := color.RGBA{
-			R: uint8(rand.Intn(256)),
-			G: uint8(rand.Intn(256)),
-			B: uint8(rand.Intn(256)),
+			R: uint8(c.rng.Intn(256)),
+			G: uint8(c.rng.Intn(256)),
+			B: uint8(c.rng.Intn(256)),
 			A: 255,
 		}
 
@@ -0,0 +1,142 @@
+package captcha
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// 存储类型
+const (
+	StoreTypeMemory = "memory" // 内存存储，仅适用于单实例和测试
+	StoreTypeRedis  = "redis"  // Redis 存储
+)
+
+// Conf 验证码服务配置，字段带有 go-zero conf 标签，可以直接嵌入服务配置中：
+//
+//	type Config struct {
+//		rest.RestConf
+//		Captcha captcha.Conf
+//	}
+//
+//	var c Config
+//	conf.MustLoad("etc/dev/tts.yaml", &c)
+//	service := captcha.MustNewServiceFromConf(c.Captcha)
+type Conf struct {
+	Type        CaptchaType            `json:",default=character,options=character|image_select|slide|image_grid|pow"` // 默认验证码类型，Generate 传入空类型时使用
+	Store       StoreConf              `json:",optional"`                                                              // 验证码存储
+	Character   CharacterConfig        `json:",optional"`                                                              // 字符验证码配置
+	ImageSelect ImageSelectConfig      `json:",optional"`                                                              // 图片选择验证码配置
+	Slide       SlideConfig            `json:",optional"`                                                              // 滑动验证码配置
+	ImageGrid   ImageGridConfig        `json:",optional"`                                                              // 宫格图片选择验证码配置
+	Pow         PowConfig              `json:",optional"`                                                              // 工作量证明验证码配置
+	Assets      AssetLibraryConfig     `json:",optional"`                                                              // 图片资源库配置
+	AssetURL    AssetURLConfig         `json:",optional"`                                                              // 图片地址下发配置，BaseURL 为空时不开启
+	Pools       map[string]PoolConfig  `json:",optional"`                                                              // 预生成验证码池，key 为验证码类型
+	Scenes      map[string]SceneConfig `json:",optional"`                                                              // 场景，key 为场景名
+	Risk        RiskConfig             `json:",optional"`                                                              // 自适应难度，Bands 为空时不开启
+	RateLimit   RateLimitConfig        `json:",optional"`                                                              // 限流，Limit 都为 0 时不开启
+	HumanCheck  HumanCheckConfig       `json:",optional"`                                                              // 提交检查
+	Binding     ClientBindingConfig    `json:",optional"`                                                              // 客户端绑定
+	Invisible   *InvisibleConfig       `json:",optional"`                                                              // 无感验证，省略时不开启
+}
+
+// StoreConf 验证码存储配置
+type StoreConf struct {
+	Type   string    `json:",default=memory,options=memory|redis"` // 存储类型: memory、redis
+	Prefix string    `json:",default=captcha:"`                    // Redis key 前缀
+	Redis  RedisConf `json:",optional"`                            // Redis 连接配置，Type 为 redis 时使用，省略时连接 127.0.0.1:6379
+}
+
+// RedisConf Redis 连接配置
+type RedisConf struct {
+	Addr     string `json:",default=127.0.0.1:6379"` // 地址
+	Password string `json:",optional"`               // 密码
+	DB       int    `json:",default=0"`              // 数据库
+}
+
+// defaultRedisAddr 省略 Redis 配置块时连接的地址
+const defaultRedisAddr = "127.0.0.1:6379"
+
+// withDefaults 填充零值字段的默认值
+//
+// 省略整个 Redis 配置块时 go-zero 不会填充其中的默认值，这里补上。
+func (c RedisConf) withDefaults() RedisConf {
+	if c.Addr == "" {
+		c.Addr = defaultRedisAddr
+	}
+	return c
+}
+
+// NewStoreFromConf 根据配置创建验证码存储
+func NewStoreFromConf(c StoreConf) (Store, error) {
+	switch c.Type {
+	case "", StoreTypeMemory:
+		return NewMemStore(), nil
+	case StoreTypeRedis:
+		c.Redis = c.Redis.withDefaults()
+		client := redis.NewClient(&redis.Options{
+			Addr:     c.Redis.Addr,
+			Password: c.Redis.Password,
+			DB:       c.Redis.DB,
+		})
+		return NewRedisStore(client, c.Prefix), nil
+	default:
+		return nil, fmt.Errorf("captcha store: unknown type %q", c.Type)
+	}
+}
+
+// NewServiceFromConf 根据配置创建存储和验证码服务，opts 在配置之后应用，可以覆盖配置中的值
+func NewServiceFromConf(c Conf, opts ...Option) (*Service, error) {
+	store, err := NewStoreFromConf(c.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []Option{
+		WithDefaultType(c.Type),
+		WithCharacterConfig(c.Character),
+		WithImageSelectConfig(c.ImageSelect),
+		WithSlideConfig(c.Slide),
+		WithImageGridConfig(c.ImageGrid),
+		WithPowConfig(c.Pow),
+		WithClientBinding(c.Binding),
+		WithHumanCheck(c.HumanCheck),
+	}
+	// 省略 Assets 时 go-zero 不会填充其中的默认值，沿用 NewService 的默认轮询间隔
+	if c.Assets != (AssetLibraryConfig{}) {
+		options = append(options, WithAssetLibraryConfig(c.Assets))
+	}
+	if c.AssetURL.BaseURL != "" {
+		options = append(options, WithAssetURLs(c.AssetURL))
+	}
+	for captchaType, pool := range c.Pools {
+		options = append(options, WithPool(CaptchaType(captchaType), pool))
+	}
+	for name, sc := range c.Scenes {
+		options = append(options, WithScene(name, sc))
+	}
+	if len(c.Risk.Bands) > 0 {
+		options = append(options, WithRiskConfig(c.Risk))
+	}
+	if c.Invisible != nil {
+		options = append(options, WithInvisible(*c.Invisible))
+	}
+	if c.RateLimit.enabled() {
+		options = append(options, WithRateLimit(c.RateLimit))
+		// 使用 Redis 存储时限流计数也放在 Redis 中，多实例共享
+		if redisStore, ok := store.(*RedisStore); ok {
+			options = append(options, WithRateLimiter(NewRedisRateLimiter(redisStore.client, redisStore.prefix+"ratelimit:")))
+		}
+	}
+
+	return NewService(store, append(options, opts...)...)
+}
+
+// MustNewServiceFromConf 根据配置创建存储和验证码服务，配置无效时退出进程
+func MustNewServiceFromConf(c Conf, opts ...Option) *Service {
+	service, err := NewServiceFromConf(c, opts...)
+	logx.Must(err)
+	return service
+}
@@ -0,0 +1,101 @@
+package captcha
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+// TestNewStoreFromConfRedisAddr 省略 Redis 配置块时使用默认地址，填写时使用配置的地址
+func TestNewStoreFromConfRedisAddr(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		addr string
+	}{
+		{
+			name: "省略 Redis 配置块",
+			yaml: "Store:\n  Type: redis\n",
+			addr: "127.0.0.1:6379",
+		},
+		{
+			name: "Redis 配置块只填密码",
+			yaml: "Store:\n  Type: redis\n  Redis:\n    Password: secret\n",
+			addr: "127.0.0.1:6379",
+		},
+		{
+			name: "配置了地址",
+			yaml: "Store:\n  Type: redis\n  Redis:\n    Addr: redis.internal:6380\n    DB: 2\n",
+			addr: "redis.internal:6380",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Conf
+			if err := conf.LoadFromYamlBytes([]byte(tt.yaml), &c); err != nil {
+				t.Fatal(err)
+			}
+			store, err := NewStoreFromConf(c.Store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			redisStore, ok := store.(*RedisStore)
+			if !ok {
+				t.Fatalf("store = %T, want *RedisStore", store)
+			}
+			defer redisStore.client.Close()
+			if addr := redisStore.client.Options().Addr; addr != tt.addr {
+				t.Errorf("addr = %q, want %q", addr, tt.addr)
+			}
+			if redisStore.prefix != "captcha:" {
+				t.Errorf("prefix = %q, want %q", redisStore.prefix, "captcha:")
+			}
+		})
+	}
+}
+
+// TestNewServiceFromConfDefaultType Conf.Type 作为 Generate 传入空类型时的默认类型
+func TestNewServiceFromConfDefaultType(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want CaptchaType
+	}{
+		{name: "省略类型", yaml: "Store:\n  Type: memory\n", want: CaptchaTypeCharacter},
+		{name: "配置类型", yaml: "Type: pow\n", want: CaptchaTypePow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Conf
+			if err := conf.LoadFromYamlBytes([]byte(tt.yaml), &c); err != nil {
+				t.Fatal(err)
+			}
+			service, err := NewServiceFromConf(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer service.Close()
+
+			if got := service.DefaultType(); got != tt.want {
+				t.Errorf("DefaultType() = %q, want %q", got, tt.want)
+			}
+			resp, err := service.Generate(context.Background(), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.CaptchaType != tt.want {
+				t.Errorf("Generate 类型 = %q, want %q", resp.CaptchaType, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewServiceRejectsUnknownDefaultType 默认类型不受支持时创建服务失败
+func TestNewServiceRejectsUnknownDefaultType(t *testing.T) {
+	if _, err := NewService(NewMemStore(), WithDefaultType("audio")); err == nil {
+		t.Fatal("expected error for unknown default type")
+	}
+}
@@ -0,0 +1,149 @@
+package captcha
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// ImageFormat 图片编码格式
+type ImageFormat string
+
+const (
+	// ImageFormatPNG PNG，无损，默认格式
+	ImageFormatPNG ImageFormat = "png"
+	// ImageFormatJPEG JPEG，有损，体积最小，不支持透明
+	ImageFormatJPEG ImageFormat = "jpeg"
+	// ImageFormatGIF GIF，调色板压缩，只支持 1 位透明
+	ImageFormatGIF ImageFormat = "gif"
+)
+
+// PNGCompression PNG 压缩级别
+type PNGCompression string
+
+const (
+	// PNGCompressionDefault 默认压缩
+	PNGCompressionDefault PNGCompression = "default"
+	// PNGCompressionNone 不压缩，编码最快、体积最大
+	PNGCompressionNone PNGCompression = "none"
+	// PNGCompressionSpeed 快速压缩
+	PNGCompressionSpeed PNGCompression = "speed"
+	// PNGCompressionBest 最佳压缩，编码最慢、体积最小
+	PNGCompressionBest PNGCompression = "best"
+)
+
+// EncodeConfig 图片编码配置
+type EncodeConfig struct {
+	Format      ImageFormat    `json:",default=png,options=png|jpeg|gif"`                // 编码格式: png、jpeg、gif，默认 png
+	Compression PNGCompression `json:",default=default,options=default|none|speed|best"` // PNG 压缩级别: default、none、speed、best，默认 default
+	Quality     int            `json:",default=80"`                                      // JPEG 质量 1-100，默认 80
+	Colors      int            `json:",default=256"`                                     // GIF 调色板颜色数 2-256，默认 256
+}
+
+// Encoder 图片编码器
+type Encoder interface {
+	// Encode 把图片编码写入 w
+	Encode(w io.Writer, img image.Image) error
+	// MIMEType 编码结果的 MIME 类型，用于 data URI 和 Content-Type
+	MIMEType() string
+}
+
+// NewEncoder 根据配置创建图片编码器，未知格式按 PNG 处理
+func NewEncoder(config EncodeConfig) Encoder {
+	switch config.Format {
+	case ImageFormatJPEG:
+		quality := config.Quality
+		if quality <= 0 || quality > 100 {
+			quality = 80
+		}
+		return &jpegEncoder{quality: quality}
+	case ImageFormatGIF:
+		colors := config.Colors
+		if colors < 2 || colors > 256 {
+			colors = 256
+		}
+		return &gifEncoder{colors: colors}
+	default:
+		return newPNGEncoder(config.Compression)
+	}
+}
+
+// newTransparentEncoder 创建需要保留透明通道的编码器（如滑块模板），JPEG 退化为同压缩级别的 PNG
+func newTransparentEncoder(config EncodeConfig) Encoder {
+	if config.Format == ImageFormatJPEG {
+		return newPNGEncoder(config.Compression)
+	}
+	return NewEncoder(config)
+}
+
+// pngEncoder PNG 编码器
+type pngEncoder struct {
+	encoder png.Encoder
+}
+
+// newPNGEncoder 创建 PNG 编码器
+func newPNGEncoder(compression PNGCompression) *pngEncoder {
+	level := png.DefaultCompression
+	switch compression {
+	case PNGCompressionNone:
+		level = png.NoCompression
+	case PNGCompressionSpeed:
+		level = png.BestSpeed
+	case PNGCompressionBest:
+		level = png.BestCompression
+	}
+	return &pngEncoder{encoder: png.Encoder{CompressionLevel: level}}
+}
+
+func (e *pngEncoder) Encode(w io.Writer, img image.Image) error {
+	return e.encoder.Encode(w, img)
+}
+
+func (e *pngEncoder) MIMEType() string {
+	return "image/png"
+}
+
+// jpegEncoder JPEG 编码器
+type jpegEncoder struct {
+	quality int
+}
+
+func (e *jpegEncoder) Encode(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: e.quality})
+}
+
+func (e *jpegEncoder) MIMEType() string {
+	return "image/jpeg"
+}
+
+// gifEncoder GIF 编码器
+type gifEncoder struct {
+	colors int
+}
+
+func (e *gifEncoder) Encode(w io.Writer, img image.Image) error {
+	return gif.Encode(w, img, &gif.Options{NumColors: e.colors})
+}
+
+func (e *gifEncoder) MIMEType() string {
+	return "image/gif"
+}
+
+// encodeImage 编码图片，返回原始字节和 MIME 类型
+func encodeImage(encoder Encoder, img image.Image) (*Asset, error) {
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return &Asset{ContentType: encoder.MIMEType(), Data: buf.Bytes()}, nil
+}
+
+// dataURI 返回 data:<mime>;base64,<payload> 格式的图片
+func (a *Asset) dataURI() string {
+	return "data:" + a.ContentType + ";base64," + base64.StdEncoding.EncodeToString(a.Data)
+}
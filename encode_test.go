@@ -0,0 +1,93 @@
+package captcha
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// TestNewEncoder 各编码格式的 MIME 类型与编码结果一致
+func TestNewEncoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   EncodeConfig
+		wantMIME string
+		decode   func(*bytes.Reader) (image.Image, error)
+	}{
+		{"默认", EncodeConfig{}, "image/png", func(r *bytes.Reader) (image.Image, error) { return png.Decode(r) }},
+		{"png 最佳压缩", EncodeConfig{Format: ImageFormatPNG, Compression: PNGCompressionBest}, "image/png", func(r *bytes.Reader) (image.Image, error) { return png.Decode(r) }},
+		{"jpeg", EncodeConfig{Format: ImageFormatJPEG, Quality: 50}, "image/jpeg", func(r *bytes.Reader) (image.Image, error) { return jpeg.Decode(r) }},
+		{"jpeg 质量越界", EncodeConfig{Format: ImageFormatJPEG, Quality: 500}, "image/jpeg", func(r *bytes.Reader) (image.Image, error) { return jpeg.Decode(r) }},
+		{"gif", EncodeConfig{Format: ImageFormatGIF, Colors: 16}, "image/gif", func(r *bytes.Reader) (image.Image, error) { return gif.Decode(r) }},
+		{"未知格式按 png", EncodeConfig{Format: "webp"}, "image/png", func(r *bytes.Reader) (image.Image, error) { return png.Decode(r) }},
+	}
+
+	src := gradientImage(40, 30)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset, err := encodeImage(NewEncoder(tt.config), src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if asset.ContentType != tt.wantMIME {
+				t.Errorf("ContentType = %q, want %q", asset.ContentType, tt.wantMIME)
+			}
+			img, err := tt.decode(bytes.NewReader(asset.Data))
+			if err != nil {
+				t.Fatalf("decode as %s: %v", tt.wantMIME, err)
+			}
+			if img.Bounds().Size() != src.Bounds().Size() {
+				t.Errorf("size = %v, want %v", img.Bounds().Size(), src.Bounds().Size())
+			}
+			if uri := asset.dataURI(); !bytes.HasPrefix([]byte(uri), []byte("data:"+tt.wantMIME+";base64,")) {
+				t.Errorf("dataURI() = %.40q", uri)
+			}
+		})
+	}
+}
+
+// TestSlideTemplateKeepsTransparency 背景图使用 JPEG 时滑块模板仍为 PNG，保留透明通道
+func TestSlideTemplateKeepsTransparency(t *testing.T) {
+	for _, format := range []ImageFormat{ImageFormatPNG, ImageFormatJPEG, ImageFormatGIF} {
+		t.Run(string(format), func(t *testing.T) {
+			c := NewSlideCaptcha(SlideConfig{Encode: EncodeConfig{Format: format}, Rand: NewSeededSource(1)})
+			background, template, _, err := c.generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := NewEncoder(EncodeConfig{Format: format}).MIMEType(); background.ContentType != want {
+				t.Errorf("background ContentType = %q, want %q", background.ContentType, want)
+			}
+
+			wantTemplate := "image/" + string(format)
+			if format == ImageFormatJPEG {
+				wantTemplate = "image/png"
+			}
+			if template.ContentType != wantTemplate {
+				t.Errorf("template ContentType = %q, want %q", template.ContentType, wantTemplate)
+			}
+			if format != ImageFormatJPEG {
+				return
+			}
+
+			img, err := png.Decode(bytes.NewReader(template.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transparent := false
+			bounds := img.Bounds()
+			for y := bounds.Min.Y; y < bounds.Max.Y && !transparent; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X && !transparent; x++ {
+					_, _, _, a := img.At(x, y).RGBA()
+					transparent = a == 0
+				}
+			}
+			if !transparent {
+				t.Error("template has no transparent pixel")
+			}
+		})
+	}
+}
@@ -17,4 +17,16 @@ var (
 
 	// ErrCaptchaAnswerFormatWrong 答案格式错误
 	ErrCaptchaAnswerFormatWrong = errors.New("captcha answer format wrong")
+
+	// ErrSceneNotFound 场景不存在
+	ErrSceneNotFound = errors.New("captcha scene not found")
+
+	// ErrRiskNotEnabled 未开启自适应难度
+	ErrRiskNotEnabled = errors.New("captcha adaptive difficulty not enabled")
+
+	// ErrRateLimited 请求过于频繁
+	ErrRateLimited = errors.New("captcha rate limited")
+
+	// ErrInvisibleNotEnabled 未开启无感验证
+	ErrInvisibleNotEnabled = errors.New("captcha invisible mode not enabled")
 )
@@ -23,23 +23,23 @@ func Example_character() {
 	store := captcha.NewRedisStore(redisClient, "captcha:")
 
 	// 3. 创建验证码服务（支持所有三种类型）
-	service := captcha.NewService(
+	service, err := captcha.NewService(
 		store,
-		captcha.CharacterConfig{
+		captcha.WithCharacterConfig(captcha.CharacterConfig{
 			Width:      160,
 			Height:     60,
 			Length:     4,
 			ExpireTime: 5 * time.Minute,
 			Complexity: 2,
-		},
-		captcha.ImageSelectConfig{
+		}),
+		captcha.WithImageSelectConfig(captcha.ImageSelectConfig{
 			ImageCount:  4,
 			SelectCount: 1,
 			ExpireTime:  5 * time.Minute,
 			Category:    "traffic",
 			ImageDir:    "/path/to/images",
-		},
-		captcha.SlideConfig{
+		}),
+		captcha.WithSlideConfig(captcha.SlideConfig{
 			Width:          350,
 			Height:         200,
 			TemplateWidth:  60,
@@ -47,8 +47,12 @@ func Example_character() {
 			ExpireTime:     5 * time.Minute,
 			ImageDir:       "/path/to/backgrounds",
 			TemplateDir:    "/path/to/templates",
-		},
+		}),
 	)
+	if err != nil {
+		panic(err) // 配置无效
+	}
+	defer service.Close()
 
 	// 4. 生成字符验证码
 	resp, err := service.Generate(ctx, captcha.CaptchaTypeCharacter)
@@ -86,18 +90,20 @@ func Example_imageSelect() {
 		DB:       0,
 	})
 	store := captcha.NewRedisStore(redisClient, "captcha:")
-	service := captcha.NewService(
+	service, err := captcha.NewService(
 		store,
-		captcha.CharacterConfig{},
-		captcha.ImageSelectConfig{
+		captcha.WithImageSelectConfig(captcha.ImageSelectConfig{
 			ImageCount:  4,
 			SelectCount: 1,
 			ExpireTime:  5 * time.Minute,
 			Category:    "traffic",
 			ImageDir:    "/path/to/images",
-		},
-		captcha.SlideConfig{},
+		}),
 	)
+	if err != nil {
+		panic(err)
+	}
+	defer service.Close()
 
 	// 1. 生成图片选择验证码
 	resp, err := service.Generate(ctx, captcha.CaptchaTypeImageSelect)
@@ -141,11 +147,9 @@ func Example_slide() {
 		DB:       0,
 	})
 	store := captcha.NewRedisStore(redisClient, "captcha:")
-	service := captcha.NewService(
+	service, err := captcha.NewService(
 		store,
-		captcha.CharacterConfig{},
-		captcha.ImageSelectConfig{},
-		captcha.SlideConfig{
+		captcha.WithSlideConfig(captcha.SlideConfig{
 			Width:          350,
 			Height:         200,
 			TemplateWidth:  60,
@@ -153,8 +157,12 @@ func Example_slide() {
 			ExpireTime:     5 * time.Minute,
 			ImageDir:       "/path/to/backgrounds",
 			TemplateDir:    "/path/to/templates",
-		},
+		}),
 	)
+	if err != nil {
+		panic(err)
+	}
+	defer service.Close()
 
 	// 1. 生成滑动验证码
 	resp, err := service.Generate(ctx, captcha.CaptchaType("slide"))
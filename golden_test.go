@@ -0,0 +1,193 @@
+package captcha_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gpencil/captcha"
+)
+
+var update = flag.Bool("update", false, "重新生成 testdata/golden 下的基准图片")
+
+const (
+	// goldenPixelDelta 单个通道的差值超过该值时认为像素不同
+	goldenPixelDelta = 24
+	// goldenMaxDiffRatio 允许不同的像素比例，用于容忍缩放、编码等实现细节的微小差异
+	goldenMaxDiffRatio = 0.002
+)
+
+// TestGolden 使用固定种子渲染每种验证码，与 testdata/golden 下的基准图片比较
+//
+// 修改绘制逻辑后使用 go test -run TestGolden -update . 重新生成基准图片，并检查图片变化是否符合预期。
+func TestGolden(t *testing.T) {
+	cases := []struct {
+		name   string
+		render func(seed int64) ([]string, error)
+	}{
+		{"character_complexity1", renderCharacter(1)},
+		{"character_complexity2", renderCharacter(2)},
+		{"character_complexity3", renderCharacter(3)},
+		{"image_select", func(seed int64) ([]string, error) {
+			_, _, images, _, err := captcha.NewImageSelectCaptcha(captcha.ImageSelectConfig{
+				ImageCount: 4,
+				Rand:       captcha.NewSeededSource(seed),
+			}).Generate()
+			return images, err
+		}},
+		// 宫格尺寸只影响答案不影响图片，只需要一张基准图片
+		{"image_grid", func(seed int64) ([]string, error) {
+			_, _, img, _, _, err := captcha.NewImageGridCaptcha(captcha.ImageGridConfig{
+				Rand: captcha.NewSeededSource(seed),
+			}).Generate()
+			return []string{img}, err
+		}},
+		{"slide", func(seed int64) ([]string, error) {
+			background, template, _, _, err := captcha.NewSlideCaptcha(captcha.SlideConfig{
+				Rand: captcha.NewSeededSource(seed),
+			}).Generate()
+			return []string{background, template}, err
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dataURIs, err := tc.render(1)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+
+			images := make([]image.Image, 0, len(dataURIs))
+			for _, dataURI := range dataURIs {
+				img, err := decodeDataURI(dataURI)
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				images = append(images, img)
+			}
+			got := hconcat(images)
+
+			path := filepath.Join("testdata", "golden", tc.name+".png")
+			if *update {
+				if err := writePNG(path, got); err != nil {
+					t.Fatalf("update golden: %v", err)
+				}
+				return
+			}
+
+			want, err := readPNG(path)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create): %v", err)
+			}
+			if err := compareImages(want, got); err != nil {
+				actual := filepath.Join(t.TempDir(), tc.name+".png")
+				if writeErr := writePNG(actual, got); writeErr == nil {
+					t.Logf("actual output written to %s", actual)
+				}
+				t.Fatalf("%s differs from golden: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func renderCharacter(complexity int) func(seed int64) ([]string, error) {
+	return func(seed int64) ([]string, error) {
+		_, img, err := captcha.NewCharacterCaptcha(captcha.CharacterConfig{
+			Complexity: complexity,
+			Rand:       captcha.NewSeededSource(seed),
+		}).Generate()
+		return []string{img}, err
+	}
+}
+
+// decodeDataURI 解码 data URI 图片
+func decodeDataURI(dataURI string) (image.Image, error) {
+	_, payload, ok := strings.Cut(dataURI, ";base64,")
+	if !ok {
+		return nil, fmt.Errorf("not a base64 data uri")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// hconcat 把多张图片从左到右拼成一张
+func hconcat(images []image.Image) *image.RGBA {
+	width, height := 0, 0
+	for _, img := range images {
+		width += img.Bounds().Dx()
+		height = max(height, img.Bounds().Dy())
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	x := 0
+	for _, img := range images {
+		bounds := img.Bounds()
+		draw.Draw(dst, image.Rect(x, 0, x+bounds.Dx(), bounds.Dy()), img, bounds.Min, draw.Src)
+		x += bounds.Dx()
+	}
+	return dst
+}
+
+// compareImages 逐像素比较，尺寸不同或不同像素的比例超过 goldenMaxDiffRatio 时返回错误
+func compareImages(want, got image.Image) error {
+	if want.Bounds().Size() != got.Bounds().Size() {
+		return fmt.Errorf("size %v, want %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+
+	wantRGBA, gotRGBA := toNRGBA(want), toNRGBA(got)
+	diff := 0
+	for i := 0; i < len(wantRGBA.Pix); i += 4 {
+		for ch := 0; ch < 4; ch++ {
+			d := int(wantRGBA.Pix[i+ch]) - int(gotRGBA.Pix[i+ch])
+			if d > goldenPixelDelta || d < -goldenPixelDelta {
+				diff++
+				break
+			}
+		}
+	}
+
+	total := len(wantRGBA.Pix) / 4
+	if ratio := float64(diff) / float64(total); ratio > goldenMaxDiffRatio {
+		return fmt.Errorf("%d of %d pixels differ (%.2f%%)", diff, total, ratio*100)
+	}
+	return nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+func readPNG(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return png.Decode(file)
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,61 @@
+package captcha
+
+import (
+	"fmt"
+	"time"
+)
+
+// 疑似机器提交时的处理方式
+const (
+	HumanCheckReject = "reject" // 验证失败
+	HumanCheckFlag   = "flag"   // 照常验证，记录日志并计入失败次数
+)
+
+// HumanCheckConfig 提交检查配置
+//
+// 蜜罐字段是前端对用户隐藏的输入框，正常用户不会填写；最短作答时间是从生成到验证的最短间隔，
+// 人看清图片并作答至少需要一段时间，脚本和打码平台回传的答案往往更快。
+//
+// 工作量证明验证码不检查最短作答时间：它由前端在后台自动计算，没有人工作答的过程，
+// 性能好的设备在低难度下几毫秒就能算完，检查作答时间只会误伤正常用户；它的成本由难度决定，而不是作答时间。
+// 蜜罐字段对所有类型都检查。
+type HumanCheckConfig struct {
+	MinSolveTime time.Duration `json:",optional"`                           // 最短作答时间，0 表示不检查，场景可以单独设置
+	Action       string        `json:",default=reject,options=reject|flag"` // 疑似机器提交时的处理方式，默认 reject
+}
+
+// withDefaults 填充零值字段的默认值
+func (c HumanCheckConfig) withDefaults() HumanCheckConfig {
+	if c.Action == "" {
+		c.Action = HumanCheckReject
+	}
+	return c
+}
+
+// Validate 校验提交检查配置
+func (c HumanCheckConfig) Validate() error {
+	c = c.withDefaults()
+	if c.MinSolveTime < 0 {
+		return fmt.Errorf("human check: min solve time must not be negative")
+	}
+	if c.Action != HumanCheckReject && c.Action != HumanCheckFlag {
+		return fmt.Errorf("human check: unknown action %q", c.Action)
+	}
+	return nil
+}
+
+// suspect 检查蜜罐字段和作答时间，返回疑似机器提交的原因，正常时返回空，工作量证明验证码不检查作答时间
+func (s *Service) suspect(record *captchaRecord, req *VerifyRequest, now time.Time) VerifyReason {
+	if req.Honeypot != "" {
+		return VerifyReasonHoneypot
+	}
+
+	minSolveTime := s.humanCheck.MinSolveTime
+	if sc, ok := s.scenes[record.Scene]; ok && sc.config.MinSolveTime > 0 {
+		minSolveTime = sc.config.MinSolveTime
+	}
+	if minSolveTime > 0 && record.Type != CaptchaTypePow && now.Sub(time.UnixMilli(record.CreatedAt)) < minSolveTime {
+		return VerifyReasonTooFast
+	}
+	return ""
+}
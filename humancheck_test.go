@@ -0,0 +1,113 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// characterCode 从 Store 中读出字符验证码的答案
+func characterCode(t *testing.T, store Store, captchaID string) string {
+	t.Helper()
+	value, err := store.Get(context.Background(), captchaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record captchaRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		t.Fatal(err)
+	}
+	var data CharacterData
+	if err := json.Unmarshal(record.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	return data.Code
+}
+
+// TestSuspect 蜜罐字段、最短作答时间和工作量证明验证码的豁免
+func TestSuspect(t *testing.T) {
+	now := time.Now()
+	s := &Service{
+		humanCheck: HumanCheckConfig{MinSolveTime: 2 * time.Second}.withDefaults(),
+		scenes: map[string]*scene{
+			"slow": {config: SceneConfig{Type: CaptchaTypeCharacter, MinSolveTime: 10 * time.Second}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		typ      CaptchaType
+		scene    string
+		elapsed  time.Duration // 从生成到验证的时间
+		honeypot string
+		want     VerifyReason
+	}{
+		{name: "正常提交", typ: CaptchaTypeCharacter, elapsed: 5 * time.Second},
+		{name: "蜜罐字段被填写", typ: CaptchaTypeCharacter, elapsed: 5 * time.Second, honeypot: "x", want: VerifyReasonHoneypot},
+		{name: "作答过快", typ: CaptchaTypeCharacter, elapsed: time.Second, want: VerifyReasonTooFast},
+		{name: "恰好达到最短作答时间", typ: CaptchaTypeCharacter, elapsed: 2 * time.Second},
+		{name: "场景的最短作答时间", typ: CaptchaTypeCharacter, scene: "slow", elapsed: 5 * time.Second, want: VerifyReasonTooFast},
+		{name: "工作量证明不检查作答时间", typ: CaptchaTypePow, elapsed: time.Millisecond},
+		{name: "工作量证明仍检查蜜罐字段", typ: CaptchaTypePow, elapsed: time.Millisecond, honeypot: "x", want: VerifyReasonHoneypot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &captchaRecord{Type: tt.typ, Scene: tt.scene, CreatedAt: now.Add(-tt.elapsed).UnixMilli()}
+			req := &VerifyRequest{CaptchaType: tt.typ, Honeypot: tt.honeypot}
+			if got := s.suspect(record, req, now); got != tt.want {
+				t.Errorf("suspect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHumanCheckAction reject 时验证失败，flag 时照常验证并记录原因
+func TestHumanCheckAction(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    string
+		honeypot  string
+		wantOK    bool
+		wantFlags []VerifyReason
+		reason    VerifyReason
+	}{
+		{name: "reject 作答过快", action: HumanCheckReject, reason: VerifyReasonTooFast},
+		{name: "reject 蜜罐字段", action: HumanCheckReject, honeypot: "x", reason: VerifyReasonHoneypot},
+		{name: "flag 作答过快", action: HumanCheckFlag, wantOK: true, reason: VerifyReasonOK, wantFlags: []VerifyReason{VerifyReasonTooFast}},
+		{name: "flag 蜜罐字段", action: HumanCheckFlag, honeypot: "x", wantOK: true, reason: VerifyReasonOK, wantFlags: []VerifyReason{VerifyReasonHoneypot}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemStore()
+			service, err := NewService(store, WithHumanCheck(HumanCheckConfig{MinSolveTime: time.Hour, Action: tt.action}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer service.Close()
+
+			ctx := context.Background()
+			resp, err := service.Generate(ctx, CaptchaTypeCharacter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := service.VerifyDetailed(ctx, &VerifyRequest{
+				CaptchaID:   resp.CaptchaID,
+				CaptchaType: CaptchaTypeCharacter,
+				Answer:      CharacterAnswer{Code: characterCode(t, store, resp.CaptchaID)},
+				Honeypot:    tt.honeypot,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.OK != tt.wantOK || result.Reason != tt.reason {
+				t.Errorf("result = OK %v reason %q, want OK %v reason %q", result.OK, result.Reason, tt.wantOK, tt.reason)
+			}
+			if len(result.Flags) != len(tt.wantFlags) || (len(tt.wantFlags) > 0 && result.Flags[0] != tt.wantFlags[0]) {
+				t.Errorf("Flags = %v, want %v", result.Flags, tt.wantFlags)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package captcha
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	xdraw "golang.org/x/image/draw"
+)
+
+// coverageSamples 计算方格覆盖率时每个方格每个方向的采样点数
+const coverageSamples = 16
+
+// ImageGridCaptcha 宫格图片选择验证码（一张场景图切分为 N×N 方格）
+type ImageGridCaptcha struct {
+	config  ImageGridConfig
+	encoder Encoder
+	rng     *rand.Rand
+
+	assets     *AssetLibrary
+	assetsOnce sync.Once
+}
+
+// GridAnnotation 场景图片标注文件（与图片同名的 .json 文件）
+//
+// 标注可以用多边形（图片像素坐标）描述目标区域，也可以直接给出
+// 每种宫格尺寸下各方格的覆盖比例（0-1），两者同时存在时优先使用方格覆盖比例。
+type GridAnnotation struct {
+	Target   string               `json:"target"`   // 目标类型，如 bus
+	Question string               `json:"question"` // 问题文本，为空时根据目标类型生成
+	Polygons [][][2]float64       `json:"polygons"` // 目标多边形列表，每个点为 [x, y]
+	Tiles    map[string][]float64 `json:"tiles"`    // 方格覆盖比例，key 为宫格边长，如 "3"、"4"
+	Width    int                  `json:"width"`    // 标注时的图片宽度，图片被缩放后据此换算多边形坐标，为空时与图片一致
+	Height   int                  `json:"height"`   // 标注时的图片高度
+}
+
+// gridScene 已加载的场景图片及标注
+type gridScene struct {
+	image      image.Image
+	annotation GridAnnotation
+}
+
+// NewImageGridCaptcha 创建宫格图片选择验证码
+func NewImageGridCaptcha(config ImageGridConfig) *ImageGridCaptcha {
+	config = config.withDefaults()
+
+	return &ImageGridCaptcha{
+		config:  config,
+		encoder: NewEncoder(config.Encode),
+		rng:     newRand(config.Rand),
+	}
+}
+
+// withDefaults 填充零值字段的默认值
+func (c ImageGridConfig) withDefaults() ImageGridConfig {
+	if c.GridSize == 0 {
+		c.GridSize = 3
+	}
+	if c.Width == 0 {
+		c.Width = 300
+	}
+	if c.Height == 0 {
+		c.Height = 300
+	}
+	if c.ExpireTime == 0 {
+		c.ExpireTime = 5 * time.Minute
+	}
+	if c.MinOverlap == 0 {
+		c.MinOverlap = 0.2
+	}
+	return c
+}
+
+// WithAssetLibrary 使用共享的图片资源库，不设置时首次生成验证码时扫描 ImageDir 创建私有资源库
+func (c *ImageGridCaptcha) WithAssetLibrary(assets *AssetLibrary) *ImageGridCaptcha {
+	c.assets = assets
+	return c
+}
+
+// assetLibrary 返回图片资源库
+func (c *ImageGridCaptcha) assetLibrary() *AssetLibrary {
+	c.assetsOnce.Do(func() {
+		if c.assets == nil {
+			c.assets = NewAssetLibrary(AssetLibraryConfig{}, c.config.ImageDir)
+		}
+	})
+	return c.assets
+}
+
+// Generate 生成验证码，返回问题、目标类型、data URI 格式的图片、必须选中的方格和可选方格
+func (c *ImageGridCaptcha) Generate() (string, string, string, []int, []int, error) {
+	question, targetType, encoded, required, optional, err := c.generate()
+	if err != nil {
+		return "", "", "", nil, nil, err
+	}
+	return question, targetType, encoded.dataURI(), required, optional, nil
+}
+
+// generate 生成验证码，返回问题、目标类型、编码后的图片、必须选中的方格和可选方格
+func (c *ImageGridCaptcha) generate() (string, string, *Asset, []int, []int, error) {
+	scene, err := c.loadScene()
+	if err != nil {
+		logx.Errorf("未找到宫格场景图片，使用占位场景: %v", err)
+		scene = c.generatePlaceholderScene()
+	}
+
+	coverage, err := c.tileCoverage(scene)
+	if err != nil {
+		return "", "", nil, nil, nil, err
+	}
+
+	required, optional := c.classifyTiles(coverage)
+	if len(required) == 0 {
+		return "", "", nil, nil, nil, fmt.Errorf("scene has no tile covering target %q", scene.annotation.Target)
+	}
+
+	// 缩放到输出尺寸并编码
+	dst := image.NewRGBA(image.Rect(0, 0, c.config.Width, c.config.Height))
+	xdraw.BiLinear.Scale(dst, dst.Bounds(), scene.image, scene.image.Bounds(), xdraw.Src, nil)
+
+	encoded, err := encodeImage(c.encoder, dst)
+	if err != nil {
+		return "", "", nil, nil, nil, err
+	}
+
+	question := scene.annotation.Question
+	if question == "" {
+		question = fmt.Sprintf("请选择所有包含%s的方格", targetDisplayName(scene.annotation.Target))
+	}
+
+	return question, scene.annotation.Target, encoded, required, optional, nil
+}
+
+// Verify 验证验证码
+//
+// 覆盖比例达到 MinOverlap 的方格必须全部选中；覆盖比例大于 0 但未达到
+// MinOverlap 的方格（目标边缘）选不选均可；未覆盖目标的方格不能选中。
+func (c *ImageGridCaptcha) Verify(requiredTiles, optionalTiles, selectedTiles []int) bool {
+	allowed := make(map[int]bool, len(requiredTiles)+len(optionalTiles))
+	for _, idx := range requiredTiles {
+		allowed[idx] = true
+	}
+	for _, idx := range optionalTiles {
+		allowed[idx] = true
+	}
+
+	selectedMap := make(map[int]bool, len(selectedTiles))
+	for _, idx := range selectedTiles {
+		if !allowed[idx] {
+			return false
+		}
+		selectedMap[idx] = true
+	}
+
+	for _, idx := range requiredTiles {
+		if !selectedMap[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// classifyTiles 根据覆盖比例划分必选方格和可选方格
+func (c *ImageGridCaptcha) classifyTiles(coverage []float64) ([]int, []int) {
+	var required, optional []int
+	for idx, ratio := range coverage {
+		switch {
+		case ratio >= c.config.MinOverlap:
+			required = append(required, idx)
+		case ratio > 0:
+			optional = append(optional, idx)
+		}
+	}
+	return required, optional
+}
+
+// tileCoverage 计算每个方格被目标覆盖的比例，按行优先顺序返回
+func (c *ImageGridCaptcha) tileCoverage(scene *gridScene) ([]float64, error) {
+	n := c.config.GridSize
+
+	if tiles, ok := scene.annotation.Tiles[strconv.Itoa(n)]; ok {
+		if len(tiles) != n*n {
+			return nil, fmt.Errorf("tile mask for grid %d has %d entries, want %d", n, len(tiles), n*n)
+		}
+		return tiles, nil
+	}
+
+	if len(scene.annotation.Polygons) == 0 {
+		return nil, fmt.Errorf("annotation has neither polygons nor tile mask for grid %d", n)
+	}
+
+	// 在标注坐标系中计算，图片被资源库缩放过时以标注记录的尺寸为准
+	bounds := scene.image.Bounds()
+	if scene.annotation.Width > 0 && scene.annotation.Height > 0 {
+		bounds = image.Rect(0, 0, scene.annotation.Width, scene.annotation.Height)
+	}
+	tileW := float64(bounds.Dx()) / float64(n)
+	tileH := float64(bounds.Dy()) / float64(n)
+
+	coverage := make([]float64, n*n)
+	for row := 0; row < n; row++ {
+		for col := 0; col < n; col++ {
+			hits := 0
+			for sy := 0; sy < coverageSamples; sy++ {
+				for sx := 0; sx < coverageSamples; sx++ {
+					px := float64(bounds.Min.X) + (float64(col)+(float64(sx)+0.5)/coverageSamples)*tileW
+					py := float64(bounds.Min.Y) + (float64(row)+(float64(sy)+0.5)/coverageSamples)*tileH
+					for _, polygon := range scene.annotation.Polygons {
+						if pointInPolygon(px, py, polygon) {
+							hits++
+							break
+						}
+					}
+				}
+			}
+			coverage[row*n+col] = float64(hits) / float64(coverageSamples*coverageSamples)
+		}
+	}
+
+	return coverage, nil
+}
+
+// pointInPolygon 射线法判断点是否在多边形内
+func pointInPolygon(x, y float64, polygon [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		xi, yi := polygon[i][0], polygon[i][1]
+		xj, yj := polygon[j][0], polygon[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// loadScene 从 ImageDir 随机加载一张带标注的场景图片
+func (c *ImageGridCaptcha) loadScene() (*gridScene, error) {
+	if c.config.ImageDir == "" {
+		return nil, fmt.Errorf("image dir not configured")
+	}
+
+	assets := c.assetLibrary()
+
+	// 只保留存在同名标注文件的图片
+	var candidates []string
+	for _, imagePath := range assets.Images(c.config.ImageDir) {
+		if assets.Exists(annotationPath(imagePath)) {
+			candidates = append(candidates, imagePath)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("目录 %s 中没有带标注的场景图片", c.config.ImageDir)
+	}
+
+	imagePath := candidates[c.rng.Intn(len(candidates))]
+
+	raw, err := assets.ReadFile(annotationPath(imagePath))
+	if err != nil {
+		return nil, fmt.Errorf("读取标注文件失败: %w", err)
+	}
+
+	var annotation GridAnnotation
+	if err := json.Unmarshal(raw, &annotation); err != nil {
+		return nil, fmt.Errorf("解析标注文件失败: %s, %w", annotationPath(imagePath), err)
+	}
+
+	img, err := assets.Image(imagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gridScene{
+		image:      img,
+		annotation: annotation,
+	}, nil
+}
+
+// annotationPath 返回图片对应的标注文件路径
+func annotationPath(imagePath string) string {
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".json"
+}
+
+// generatePlaceholderScene 生成占位场景（回退方案）：道路背景上随机位置的一辆公交车
+func (c *ImageGridCaptcha) generatePlaceholderScene() *gridScene {
+	width, height := 300, 300
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// 天空和道路
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if y < height/2 {
+				img.Set(x, y, color.RGBA{R: 160, G: 210, B: 240, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{R: 90, G: 90, B: 90, A: 255})
+			}
+		}
+	}
+
+	// 随机大小和位置的车身
+	busW := 90 + c.rng.Intn(90)
+	busH := 50 + c.rng.Intn(40)
+	busX := c.rng.Intn(width - busW)
+	busY := height/2 - busH/2 + c.rng.Intn(height/4)
+	for y := busY; y < busY+busH; y++ {
+		for x := busX; x < busX+busW; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 50, B: 50, A: 255})
+		}
+	}
+	// 车窗
+	for y := busY + 8; y < busY+busH/2; y++ {
+		for x := busX + 8; x < busX+busW-8; x++ {
+			if (x-busX)%24 < 18 {
+				img.Set(x, y, color.RGBA{R: 135, G: 206, B: 250, A: 255})
+			}
+		}
+	}
+
+	polygon := [][2]float64{
+		{float64(busX), float64(busY)},
+		{float64(busX + busW), float64(busY)},
+		{float64(busX + busW), float64(busY + busH)},
+		{float64(busX), float64(busY + busH)},
+	}
+
+	return &gridScene{
+		image: img,
+		annotation: GridAnnotation{
+			Target:   "bus",
+			Polygons: [][][2]float64{polygon},
+		},
+	}
+}
+
+// targetDisplayName 目标类型的中文名称
+func targetDisplayName(target string) string {
+	names := map[string]string{
+		"bus":   "公交车",
+		"car":   "汽车",
+		"bike":  "自行车",
+		"light": "红绿灯",
+	}
+	if name, ok := names[target]; ok {
+		return name
+	}
+	return target
+}
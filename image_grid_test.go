@@ -0,0 +1,165 @@
+package captcha
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+// TestPointInPolygon 射线法判断点与多边形的位置关系
+func TestPointInPolygon(t *testing.T) {
+	square := [][2]float64{{10, 10}, {50, 10}, {50, 50}, {10, 50}}
+	triangle := [][2]float64{{0, 0}, {100, 0}, {0, 100}}
+
+	tests := []struct {
+		name    string
+		x, y    float64
+		polygon [][2]float64
+		want    bool
+	}{
+		{"正方形内部", 30, 30, square, true},
+		{"正方形外部", 60, 30, square, false},
+		{"正方形上方", 30, 5, square, false},
+		{"贴近左边内侧", 10.01, 30, square, true},
+		{"贴近左边外侧", 9.99, 30, square, false},
+		{"三角形内部", 20, 20, triangle, true},
+		{"三角形斜边外侧", 60, 60, triangle, false},
+		{"三角形斜边内侧", 49, 49, triangle, true},
+		{"空多边形", 0, 0, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pointInPolygon(tt.x, tt.y, tt.polygon); got != tt.want {
+				t.Errorf("pointInPolygon(%v, %v) = %v, want %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestImageGridTileCoverage 多边形覆盖比例和 MinOverlap 边界上的方格划分
+func TestImageGridTileCoverage(t *testing.T) {
+	tests := []struct {
+		name         string
+		annotation   GridAnnotation
+		wantCoverage []float64
+		wantRequired []int
+		wantOptional []int
+	}{
+		{
+			name: "覆盖左半边",
+			annotation: GridAnnotation{
+				Polygons: [][][2]float64{{{0, 0}, {50, 0}, {50, 100}, {0, 100}}},
+			},
+			wantCoverage: []float64{1, 0, 1, 0},
+			wantRequired: []int{0, 2},
+		},
+		{
+			// 右侧方格每行 16 个采样点中有 3 个落在 x < 60 内，覆盖 3/16 < 0.2
+			name: "右侧方格低于 MinOverlap",
+			annotation: GridAnnotation{
+				Polygons: [][][2]float64{{{0, 0}, {60, 0}, {60, 100}, {0, 100}}},
+			},
+			wantCoverage: []float64{1, 0.1875, 1, 0.1875},
+			wantRequired: []int{0, 2},
+			wantOptional: []int{1, 3},
+		},
+		{
+			// 右侧方格 4/16 = 0.25 达到 MinOverlap
+			name: "右侧方格达到 MinOverlap",
+			annotation: GridAnnotation{
+				Polygons: [][][2]float64{{{0, 0}, {62.5, 0}, {62.5, 100}, {0, 100}}},
+			},
+			wantCoverage: []float64{1, 0.25, 1, 0.25},
+			wantRequired: []int{0, 1, 2, 3},
+		},
+		{
+			name: "按标注尺寸换算",
+			annotation: GridAnnotation{
+				Polygons: [][][2]float64{{{0, 0}, {100, 0}, {100, 100}, {0, 100}}},
+				Width:    200,
+				Height:   200,
+			},
+			wantCoverage: []float64{1, 0, 0, 0},
+			wantRequired: []int{0},
+		},
+		{
+			name: "方格覆盖比例恰好等于 MinOverlap",
+			annotation: GridAnnotation{
+				Tiles:    map[string][]float64{"2": {0.2, 0.19, 0, 1}},
+				Polygons: [][][2]float64{{{0, 0}, {100, 0}, {100, 100}, {0, 100}}},
+			},
+			wantCoverage: []float64{0.2, 0.19, 0, 1},
+			wantRequired: []int{0, 3},
+			wantOptional: []int{1},
+		},
+	}
+
+	c := NewImageGridCaptcha(ImageGridConfig{GridSize: 2, MinOverlap: 0.2})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scene := &gridScene{image: image.NewRGBA(image.Rect(0, 0, 100, 100)), annotation: tt.annotation}
+			coverage, err := c.tileCoverage(scene)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(coverage, tt.wantCoverage) {
+				t.Errorf("tileCoverage() = %v, want %v", coverage, tt.wantCoverage)
+			}
+
+			required, optional := c.classifyTiles(coverage)
+			if !reflect.DeepEqual(required, tt.wantRequired) {
+				t.Errorf("required = %v, want %v", required, tt.wantRequired)
+			}
+			if !reflect.DeepEqual(optional, tt.wantOptional) {
+				t.Errorf("optional = %v, want %v", optional, tt.wantOptional)
+			}
+		})
+	}
+}
+
+// TestImageGridTileCoverageInvalid 方格覆盖比例数量不对或没有标注时返回错误
+func TestImageGridTileCoverageInvalid(t *testing.T) {
+	c := NewImageGridCaptcha(ImageGridConfig{GridSize: 2})
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+
+	for name, annotation := range map[string]GridAnnotation{
+		"数量不对": {Tiles: map[string][]float64{"2": {1, 0, 0}}},
+		"没有标注": {},
+	} {
+		if _, err := c.tileCoverage(&gridScene{image: img, annotation: annotation}); err == nil {
+			t.Errorf("%s: tileCoverage() error = nil", name)
+		}
+	}
+}
+
+// TestImageGridVerify 必选方格必须全部选中，可选方格选不选均可，其他方格不能选中
+func TestImageGridVerify(t *testing.T) {
+	c := NewImageGridCaptcha(ImageGridConfig{})
+	required := []int{0, 1, 3}
+	optional := []int{4}
+
+	tests := []struct {
+		name     string
+		selected []int
+		want     bool
+	}{
+		{"恰好选中必选方格", []int{0, 1, 3}, true},
+		{"顺序无关", []int{3, 0, 1}, true},
+		{"加选可选方格", []int{0, 1, 3, 4}, true},
+		{"重复选中", []int{0, 1, 3, 3}, true},
+		{"漏选必选方格", []int{0, 1}, false},
+		{"只选可选方格", []int{4}, false},
+		{"多选无关方格", []int{0, 1, 3, 8}, false},
+		{"全部选中", []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, false},
+		{"未选择", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Verify(required, optional, tt.selected); got != tt.want {
+				t.Errorf("Verify(%v) = %v, want %v", tt.selected, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,17 +1,16 @@
 package captcha
 
 import (
-	"bytes"
-	"encoding/base64"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
+	_ "image/jpeg"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/zeromicro/go-zero/core/logx"
@@ -22,302 +21,467 @@ import (
 
 // ImageSelectCaptcha 图片选择验证码
 type ImageSelectCaptcha struct {
-	config ImageSelectConfig
+	config  ImageSelectConfig
+	encoder Encoder
+	rng     *rand.Rand
+
+	assets     *AssetLibrary
+	assetsOnce sync.Once
+
+	datasetMu      sync.Mutex
+	dataset        *imageDataset
+	datasetErr     error
+	datasetVersion uint64
+	datasetLoaded  bool
 }
 
 // NewImageSelectCaptcha 创建图片选择验证码
 func NewImageSelectCaptcha(config ImageSelectConfig) *ImageSelectCaptcha {
-	// 设置默认值
-	if config.ImageCount == 0 {
-		config.ImageCount = 4 // 默认4张图片
+	config = config.withDefaults()
+
+	return &ImageSelectCaptcha{
+		config:  config,
+		encoder: NewEncoder(config.Encode),
+		rng:     newRand(config.Rand),
+	}
+}
+
+// withDefaults 填充零值字段的默认值
+func (c ImageSelectConfig) withDefaults() ImageSelectConfig {
+	if c.ImageCount == 0 {
+		c.ImageCount = 4 // 默认4张图片
+	}
+	if c.SelectCount == 0 {
+		c.SelectCount = 1 // 默认选择1张
 	}
-	if config.SelectCount == 0 {
-		config.SelectCount = 1 // 默认选择1张
+	if c.MaxSelectCount > 0 && c.MinSelectCount == 0 {
+		c.MinSelectCount = 1
 	}
-	if config.ExpireTime == 0 {
-		config.ExpireTime = 5 * time.Minute
+	if c.ExpireTime == 0 {
+		c.ExpireTime = 5 * time.Minute
 	}
+	if c.Language == "" {
+		c.Language = "zh"
+	}
+	return c
+}
 
-	return &ImageSelectCaptcha{
-		config: config,
+// WithAssetLibrary 使用共享的图片资源库，不设置时首次生成验证码时扫描 ImageDir 创建私有资源库
+func (c *ImageSelectCaptcha) WithAssetLibrary(assets *AssetLibrary) *ImageSelectCaptcha {
+	c.assets = assets
+	return c
+}
+
+// assetLibrary 返回图片资源库
+func (c *ImageSelectCaptcha) assetLibrary() *AssetLibrary {
+	c.assetsOnce.Do(func() {
+		if c.assets == nil {
+			c.assets = NewAssetLibrary(AssetLibraryConfig{}, c.config.ImageDir, c.config.Manifest)
+		}
+	})
+	return c.assets
+}
+
+// imageSelectItem 一道题中的一个选项
+type imageSelectItem struct {
+	category string // 所属类别
+	path     string // 图片路径，占位图片为空
+	isTarget bool   // 是否为目标类别
+}
+
+// placeholderCategories 占位图片支持的类别
+var placeholderCategories = []string{"bus", "bike", "light", "car"}
+
+// Generate 生成验证码，返回问题、目标类别、data URI 格式的图片列表和目标图片索引
+func (c *ImageSelectCaptcha) Generate() (string, string, []string, []int, error) {
+	question, targetType, images, targetIndexes, err := c.generate()
+	if err != nil {
+		return "", "", nil, nil, err
 	}
+	dataURIs := make([]string, len(images))
+	for i, encoded := range images {
+		dataURIs[i] = encoded.dataURI()
+	}
+	return question, targetType, dataURIs, targetIndexes, nil
 }
 
-// Generate 生成验证码
-func (c *ImageSelectCaptcha) Generate() (string, []string, []int, error) {
-	// 1. 随机选择目标图片索引
-	targetIndexes := c.selectTargetIndexes()
+// generate 生成验证码，返回问题、目标类别、编码后的图片列表和目标图片索引
+func (c *ImageSelectCaptcha) generate() (string, string, []*Asset, []int, error) {
+	targetCount := c.targetCount()
+	if targetCount > c.config.ImageCount {
+		return "", "", nil, nil, fmt.Errorf("select count %d exceeds image count %d", targetCount, c.config.ImageCount)
+	}
 
-	// 2. 加载图片并编码为Base64
-	images, err := c.loadImages(targetIndexes)
+	// 1. 先从带标签的数据集中选出目标类别和选项
+	dataset, err := c.loadDataset()
+	var items []imageSelectItem
+	if err == nil {
+		items, err = c.sampleRealItems(dataset, targetCount)
+	}
 	if err != nil {
-		return "", nil, nil, fmt.Errorf("failed to load images: %w", err)
+		// 如果没有真实图片，使用占位图片（回退方案）
+		logx.Errorf("未找到真实图片，使用占位图片: %v", err)
+		items = c.samplePlaceholderItems(targetCount)
+	}
+
+	// 2. 打乱顺序并记录目标位置
+	c.rng.Shuffle(len(items), func(i, j int) {
+		items[i], items[j] = items[j], items[i]
+	})
+
+	targetType := ""
+	var targetIndexes []int
+	for i, item := range items {
+		if item.isTarget {
+			targetType = item.category
+			targetIndexes = append(targetIndexes, i)
+		}
+	}
+
+	// 3. 加载图片并编码
+	images := make([]*Asset, 0, len(items))
+	for _, item := range items {
+		var encoded *Asset
+		if item.path != "" {
+			encoded, err = c.loadAndEncodeImage(item.path)
+		} else {
+			encoded, err = encodeImage(c.encoder, c.drawPlaceholder(item.category))
+		}
+		if err != nil {
+			return "", "", nil, nil, fmt.Errorf("failed to load images: %w", err)
+		}
+		images = append(images, encoded)
 	}
 
-	// 3. 生成问题文本
-	question := c.generateQuestion()
+	// 4. 根据目标类别生成问题文本
+	question := c.generateQuestion(dataset, targetType)
 
-	return question, images, targetIndexes, nil
+	return question, targetType, images, targetIndexes, nil
 }
 
-// Verify 验证验证码
+// Verify 验证验证码，选中的图片必须与目标图片完全一致（不能漏选也不能多选）
 func (c *ImageSelectCaptcha) Verify(targetIndexes, selectedIndex []int) bool {
-	if len(selectedIndex) != c.config.SelectCount {
-		return false
+	targetMap := make(map[int]bool, len(targetIndexes))
+	for _, idx := range targetIndexes {
+		targetMap[idx] = true
 	}
 
 	// 检查选中的索引是否都在目标索引中
-	selectedMap := make(map[int]bool)
+	selectedMap := make(map[int]bool, len(selectedIndex))
 	for _, idx := range selectedIndex {
-		selectedMap[idx] = true
-	}
-
-	for _, targetIdx := range targetIndexes {
-		if !selectedMap[targetIdx] {
+		if !targetMap[idx] {
 			return false
 		}
+		selectedMap[idx] = true
 	}
 
-	return true
+	return len(selectedMap) == len(targetMap)
 }
 
-// selectTargetIndexes 随机选择目标图片索引
-func (c *ImageSelectCaptcha) selectTargetIndexes() []int {
-	rand.Seed(time.Now().UnixNano())
+// HidesSelectCount 是否不向前端公开目标数量
+func (c *ImageSelectCaptcha) HidesSelectCount() bool {
+	return c.config.MaxSelectCount > 0
+}
 
-	// 创建0到ImageCount-1的索引数组
-	allIndexes := make([]int, c.config.ImageCount)
-	for i := 0; i < c.config.ImageCount; i++ {
-		allIndexes[i] = i
+// targetCount 本题的目标图片数量
+func (c *ImageSelectCaptcha) targetCount() int {
+	if !c.HidesSelectCount() {
+		return c.config.SelectCount
+	}
+	if c.config.MaxSelectCount <= c.config.MinSelectCount {
+		return c.config.MinSelectCount
 	}
+	return c.config.MinSelectCount + c.rng.Intn(c.config.MaxSelectCount-c.config.MinSelectCount+1)
+}
 
-	// 随机打乱
-	rand.Shuffle(len(allIndexes), func(i, j int) {
-		allIndexes[i], allIndexes[j] = allIndexes[j], allIndexes[i]
-	})
+// loadDataset 获取数据集，资源库内容不变时复用上次构建的结果
+func (c *ImageSelectCaptcha) loadDataset() (*imageDataset, error) {
+	assets := c.assetLibrary()
+	version := assets.Version()
 
-	// 取前SelectCount个作为目标
-	return allIndexes[:c.config.SelectCount]
-}
+	c.datasetMu.Lock()
+	defer c.datasetMu.Unlock()
 
-// loadImages 加载图片
-func (c *ImageSelectCaptcha) loadImages(targetIndexes []int) ([]string, error) {
-	// 首先尝试从文件系统加载真实图片
-	realImages, err := c.loadRealImages()
-	if err == nil && len(realImages) > 0 {
-		return realImages, nil
+	if !c.datasetLoaded || c.datasetVersion != version {
+		c.dataset, c.datasetErr = c.buildDataset(assets)
+		c.datasetVersion = version
+		c.datasetLoaded = true
 	}
 
-	// 如果没有真实图片，使用占位图片（回退方案）
-	logx.Infof("未找到真实图片，使用占位图片")
-	return c.generatePlaceholderImages()
+	return c.dataset, c.datasetErr
 }
 
-// loadRealImages 从文件系统加载真实图片
-func (c *ImageSelectCaptcha) loadRealImages() ([]string, error) {
-	var loadedImages []string
+// buildDataset 构建数据集：优先使用清单文件，没有清单时按 ImageDir 下的类别目录构建
+func (c *ImageSelectCaptcha) buildDataset(assets *AssetLibrary) (*imageDataset, error) {
+	var dataset *imageDataset
+	if path, ok := findManifest(c.config, assets.Exists); ok {
+		content, err := assets.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+		}
+		manifest, err := parseImageManifest(path, content)
+		if err != nil {
+			return nil, err
+		}
+		dataset = newManifestDataset(manifest, filepath.Dir(path))
+	} else {
+		dataset = c.scanCategories(assets)
+	}
 
-	// 定义图片类型和对应的目录
-	imageTypes := []struct {
-		dirName string
-	}{
-		{"bus"},
-		{"car"},
-		{"bike"},
-		{"light"},
+	dataset, err := dataset.filterGroup(c.config.Category)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataset.categories) < 2 {
+		return nil, fmt.Errorf("数据集中至少需要两个图片类别")
 	}
 
-	// 为每种类型随机选择一张图片
-	for _, imgType := range imageTypes {
-		// 查找该类型的图片文件
-		dirPath := filepath.Join(c.config.ImageDir, imgType.dirName)
+	return dataset, nil
+}
 
-		// 查找目录中的所有图片文件
-		files, err := os.ReadDir(dirPath)
-		if err != nil {
-			logx.Errorf("读取目录失败: %s, error: %v", dirPath, err)
-			continue // 跳过这个类型，尝试下一个
+// sampleRealItems 从数据集中抽取目标图片和干扰图片
+//
+// 先随机选出一个图片数量足够的目标类别，从中抽取 targetCount 张目标图片，
+// 再从其他类别中抽取 ImageCount-targetCount 张干扰图片，容易与目标混淆的类别优先。
+func (c *ImageSelectCaptcha) sampleRealItems(dataset *imageDataset, targetCount int) ([]imageSelectItem, error) {
+	distractorCount := c.config.ImageCount - targetCount
+
+	// 找出可以作为目标的类别
+	var candidates []string
+	for _, name := range dataset.names() {
+		if len(dataset.categories[name].images) < targetCount {
+			continue
 		}
-
-		// 过滤出图片文件
-		var imageFiles []string
-		for _, file := range files {
-			if !file.IsDir() {
-				name := strings.ToLower(file.Name())
-				if strings.HasSuffix(name, ".jpg") || strings.HasSuffix(name, ".jpeg") ||
-					strings.HasSuffix(name, ".png") {
-					imageFiles = append(imageFiles, filepath.Join(dirPath, file.Name()))
-				}
+		others := 0
+		for other, category := range dataset.categories {
+			if other != name {
+				others += len(category.images)
 			}
 		}
+		if others >= distractorCount {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("没有图片数量足够的类别")
+	}
+	target := dataset.categories[candidates[c.rng.Intn(len(candidates))]]
+
+	items := make([]imageSelectItem, 0, c.config.ImageCount)
+	for _, path := range c.samplePaths(target.images, targetCount) {
+		items = append(items, imageSelectItem{category: target.name, path: path, isTarget: true})
+	}
 
-		if len(imageFiles) == 0 {
-			logx.Infof("目录 %s 中没有找到图片文件，跳过", dirPath)
+	confusable := make(map[string]bool, len(target.confusable))
+	for _, name := range target.confusable {
+		confusable[name] = true
+	}
+
+	var preferred, others []imageSelectItem
+	for _, name := range dataset.names() {
+		if name == target.name {
 			continue
 		}
+		for _, path := range dataset.categories[name].images {
+			item := imageSelectItem{category: name, path: path}
+			if confusable[name] {
+				preferred = append(preferred, item)
+			} else {
+				others = append(others, item)
+			}
+		}
+	}
+	for _, group := range [][]imageSelectItem{preferred, others} {
+		c.rng.Shuffle(len(group), func(i, j int) {
+			group[i], group[j] = group[j], group[i]
+		})
+	}
+	distractors := append(preferred, others...)
 
-		// 随机选择一张图片
-		selectedFile := imageFiles[rand.Intn(len(imageFiles))]
+	return append(items, distractors[:distractorCount]...), nil
+}
 
-		// 读取并编码图片
-		base64Img, err := c.loadAndEncodeImage(selectedFile)
-		if err != nil {
-			logx.Errorf("加载图片失败: %s, error: %v", selectedFile, err)
-			continue
+// scanCategories 按 ImageDir 下的类别目录构建数据集，每个子目录是一个类别（目录名即类别名）
+func (c *ImageSelectCaptcha) scanCategories(assets *AssetLibrary) *imageDataset {
+	dataset := &imageDataset{categories: make(map[string]*datasetCategory)}
+	if c.config.ImageDir == "" {
+		return dataset
+	}
+
+	for _, name := range assets.Subdirs(c.config.ImageDir) {
+		dataset.categories[name] = &datasetCategory{
+			name:   name,
+			images: assets.Images(filepath.Join(c.config.ImageDir, name)),
 		}
+	}
+
+	return dataset
+}
+
+// samplePaths 不重复地随机抽取 n 个路径
+func (c *ImageSelectCaptcha) samplePaths(paths []string, n int) []string {
+	shuffled := append([]string(nil), paths...)
+	c.rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled[:n]
+}
 
-		loadedImages = append(loadedImages, base64Img)
+// samplePlaceholderItems 使用占位图片类别生成选项
+func (c *ImageSelectCaptcha) samplePlaceholderItems(targetCount int) []imageSelectItem {
+	target := placeholderCategories[c.rng.Intn(len(placeholderCategories))]
+
+	var others []string
+	for _, category := range placeholderCategories {
+		if category != target {
+			others = append(others, category)
+		}
 	}
 
-	if len(loadedImages) == 0 {
-		return nil, fmt.Errorf("没有成功加载任何图片")
+	items := make([]imageSelectItem, 0, c.config.ImageCount)
+	for i := 0; i < targetCount; i++ {
+		items = append(items, imageSelectItem{category: target, isTarget: true})
+	}
+	for i := targetCount; i < c.config.ImageCount; i++ {
+		items = append(items, imageSelectItem{category: others[c.rng.Intn(len(others))]})
 	}
 
-	return loadedImages, nil
+	return items
 }
 
 // loadAndEncodeImage 加载并编码单张图片
-func (c *ImageSelectCaptcha) loadAndEncodeImage(filePath string) (string, error) {
-	// 打开图片文件
-	file, err := os.Open(filePath)
+func (c *ImageSelectCaptcha) loadAndEncodeImage(filePath string) (*Asset, error) {
+	// 从资源库获取已解码的图片
+	img, err := c.assetLibrary().Image(filePath)
 	if err != nil {
-		return "", fmt.Errorf("打开文件失败: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	// 解码图片
-	img, _, err := image.Decode(file)
+	// 每次下发前随机扰动，避免与原图哈希匹配
+	img, err = applyPipeline(img, c.config.Perturb.Pipeline(c.rng))
 	if err != nil {
-		return "", fmt.Errorf("解码图片失败: %w", err)
+		return nil, fmt.Errorf("扰动图片失败: %w", err)
 	}
 
-	// 编码为 PNG 并转换为 Base64
-	return c.encodeImageToBase64(img, 0)
+	return encodeImage(c.encoder, img)
 }
 
-// generatePlaceholderImages 生成占位图片（回退方案）
-func (c *ImageSelectCaptcha) generatePlaceholderImages() ([]string, error) {
-	var images []string
-
-	for i := 0; i < c.config.ImageCount; i++ {
-		// 创建占位图片
-		img := image.NewRGBA(image.Rect(0, 0, 200, 150))
-
-		// 白色背景
-		draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{255, 255, 255, 255}}, image.Point{}, draw.Src)
-
-		// 根据索引绘制不同的图形
-		switch i {
-		case 0:
-			// 绘制类似公交车的图形（矩形 + 圆形轮子）
-			// 车身
-			for y := 50; y < 100; y++ {
-				for x := 30; x < 170; x++ {
-					img.Set(x, y, color.RGBA{200, 50, 50, 255}) // 红色车身
-				}
+// drawPlaceholder 绘制指定类别的占位图片（回退方案）
+func (c *ImageSelectCaptcha) drawPlaceholder(category string) *image.RGBA {
+	// 创建占位图片
+	img := image.NewRGBA(image.Rect(0, 0, 200, 150))
+
+	// 随机浅色背景，避免同类别的图片完全相同
+	background := color.RGBA{
+		R: uint8(225 + c.rng.Intn(31)),
+		G: uint8(225 + c.rng.Intn(31)),
+		B: uint8(225 + c.rng.Intn(31)),
+		A: 255,
+	}
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+
+	// 根据类别绘制不同的图形
+	switch category {
+	case "bus":
+		// 绘制类似公交车的图形（矩形 + 圆形轮子）
+		// 车身
+		for y := 50; y < 100; y++ {
+			for x := 30; x < 170; x++ {
+				img.Set(x, y, color.RGBA{200, 50, 50, 255}) // 红色车身
 			}
-			// 车窗
-			for y := 55; y < 75; y++ {
-				for x := 40; x < 80; x++ {
-					img.Set(x, y, color.RGBA{135, 206, 250, 255}) // 蓝色窗户
-				}
-				for x := 90; x < 160; x++ {
-					img.Set(x, y, color.RGBA{135, 206, 250, 255})
-				}
+		}
+		// 车窗
+		for y := 55; y < 75; y++ {
+			for x := 40; x < 80; x++ {
+				img.Set(x, y, color.RGBA{135, 206, 250, 255}) // 蓝色窗户
 			}
-			// 轮子
-			drawCircle(img, 60, 100, 15, color.RGBA{50, 50, 50, 255})  // 左轮
-			drawCircle(img, 140, 100, 15, color.RGBA{50, 50, 50, 255}) // 右轮
-
-		case 1:
-			// 绘制类似自行车的图形
-			// 车架（简单的线条和形状）
-			// 后轮
-			drawCircle(img, 50, 90, 18, color.RGBA{50, 50, 50, 255})
-			// 前轮
-			drawCircle(img, 150, 90, 18, color.RGBA{50, 50, 50, 255})
-			// 车架（菱形）
-			for y := 70; y < 90; y++ {
-				for x := 95; x < 105; x++ {
-					img.Set(x, y, color.RGBA{100, 150, 255, 255})
-				}
+			for x := 90; x < 160; x++ {
+				img.Set(x, y, color.RGBA{135, 206, 250, 255})
 			}
-			// 座位
-			for y := 68; y < 72; y++ {
-				for x := 80; x < 110; x++ {
-					img.Set(x, y, color.RGBA{100, 150, 255, 255})
-				}
+		}
+		// 轮子
+		drawCircle(img, 60, 100, 15, color.RGBA{50, 50, 50, 255})  // 左轮
+		drawCircle(img, 140, 100, 15, color.RGBA{50, 50, 50, 255}) // 右轮
+
+	case "bike":
+		// 绘制类似自行车的图形
+		// 车架（简单的线条和形状）
+		// 后轮
+		drawCircle(img, 50, 90, 18, color.RGBA{50, 50, 50, 255})
+		// 前轮
+		drawCircle(img, 150, 90, 18, color.RGBA{50, 50, 50, 255})
+		// 车架（菱形）
+		for y := 70; y < 90; y++ {
+			for x := 95; x < 105; x++ {
+				img.Set(x, y, color.RGBA{100, 150, 255, 255})
 			}
-			// 把手
-			for y := 55; y < 72; y++ {
-				for x := 145; x < 155; x++ {
-					img.Set(x, y, color.RGBA{100, 150, 255, 255})
-				}
+		}
+		// 座位
+		for y := 68; y < 72; y++ {
+			for x := 80; x < 110; x++ {
+				img.Set(x, y, color.RGBA{100, 150, 255, 255})
 			}
-
-		case 2:
-			// 绘制红绿灯（竖直排列的圆形）
-			// 外框
-			for y := 30; y < 120; y++ {
-				for x := 85; x < 115; x++ {
-					img.Set(x, y, color.RGBA{80, 80, 80, 255})
-				}
+		}
+		// 把手
+		for y := 55; y < 72; y++ {
+			for x := 145; x < 155; x++ {
+				img.Set(x, y, color.RGBA{100, 150, 255, 255})
 			}
-			// 红灯（上）
-			drawCircle(img, 100, 50, 12, color.RGBA{255, 0, 0, 255})
-			// 黄灯（中）
-			drawCircle(img, 100, 75, 12, color.RGBA{255, 255, 0, 255})
-			// 绿灯（下）
-			drawCircle(img, 100, 100, 12, color.RGBA{0, 255, 0, 255})
-
-		case 3:
-			// 绘制汽车的图形
-			// 车身
-			for y := 60; y < 110; y++ {
-				for x := 40; x < 160; x++ {
-					img.Set(x, y, color.RGBA{50, 100, 200, 255}) // 蓝色车身
-				}
+		}
+
+	case "light":
+		// 绘制红绿灯（竖直排列的圆形）
+		// 外框
+		for y := 30; y < 120; y++ {
+			for x := 85; x < 115; x++ {
+				img.Set(x, y, color.RGBA{80, 80, 80, 255})
 			}
-			// 车顶
-			for y := 45; y < 65; y++ {
-				for x := 60; x < 140; x++ {
-					img.Set(x, y, color.RGBA{50, 100, 200, 255})
-				}
+		}
+		// 红灯（上）
+		drawCircle(img, 100, 50, 12, color.RGBA{255, 0, 0, 255})
+		// 黄灯（中）
+		drawCircle(img, 100, 75, 12, color.RGBA{255, 255, 0, 255})
+		// 绿灯（下）
+		drawCircle(img, 100, 100, 12, color.RGBA{0, 255, 0, 255})
+
+	case "car":
+		// 绘制汽车的图形
+		// 车身
+		for y := 60; y < 110; y++ {
+			for x := 40; x < 160; x++ {
+				img.Set(x, y, color.RGBA{50, 100, 200, 255}) // 蓝色车身
 			}
-			// 车窗
-			for y := 50; y < 60; y++ {
-				for x := 70; x < 100; x++ {
-					img.Set(x, y, color.RGBA{135, 206, 250, 255})
-				}
-				for x := 110; x < 130; x++ {
-					img.Set(x, y, color.RGBA{135, 206, 250, 255})
-				}
+		}
+		// 车顶
+		for y := 45; y < 65; y++ {
+			for x := 60; x < 140; x++ {
+				img.Set(x, y, color.RGBA{50, 100, 200, 255})
 			}
-			// 轮子
-			drawCircle(img, 70, 110, 12, color.RGBA{30, 30, 30, 255})
-			drawCircle(img, 130, 110, 12, color.RGBA{30, 30, 30, 255})
 		}
-
-		// 添加图片标签（底部小字）
-		face := basicfont.Face7x13
-		labels := []string{"BUS", "BIKE", "LIGHT", "CAR"}
-
-		drawer := &font.Drawer{
-			Dst:  img,
-			Src:  &image.Uniform{color.RGBA{0, 0, 0, 255}},
-			Face: face,
-			Dot:  fixed.Point26_6{X: fixed.I(85), Y: fixed.I(135)},
+		// 车窗
+		for y := 50; y < 60; y++ {
+			for x := 70; x < 100; x++ {
+				img.Set(x, y, color.RGBA{135, 206, 250, 255})
+			}
+			for x := 110; x < 130; x++ {
+				img.Set(x, y, color.RGBA{135, 206, 250, 255})
+			}
 		}
-		drawer.DrawString(labels[i])
+		// 轮子
+		drawCircle(img, 70, 110, 12, color.RGBA{30, 30, 30, 255})
+		drawCircle(img, 130, 110, 12, color.RGBA{30, 30, 30, 255})
+	}
 
-		base64Img, err := c.encodeImageToBase64(img, i)
-		if err != nil {
-			return nil, err
-		}
-		images = append(images, base64Img)
+	// 添加图片标签（底部小字）
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  &image.Uniform{color.RGBA{0, 0, 0, 255}},
+		Face: basicfont.Face7x13,
+		Dot:  fixed.Point26_6{X: fixed.I(85), Y: fixed.I(135)},
 	}
+	drawer.DrawString(strings.ToUpper(category))
 
-	return images, nil
+	return img
 }
 
 // drawCircle 辅助函数：绘制填充圆
@@ -335,29 +499,14 @@ func drawCircle(img *image.RGBA, centerX, centerY, radius int, c color.RGBA) {
 	}
 }
 
-// encodeImageToBase64 将图片编码为Base64
-func (c *ImageSelectCaptcha) encodeImageToBase64(img image.Image, index int) (string, error) {
-	var buf bytes.Buffer
-	err := png.Encode(&buf, img)
-	if err != nil {
-		return "", fmt.Errorf("failed to encode image: %w", err)
-	}
-
-	base64Str := base64.StdEncoding.EncodeToString(buf.Bytes())
-	return fmt.Sprintf("data:image/png;base64,%s", base64Str), nil
-}
-
-// generateQuestion 生成问题文本
-func (c *ImageSelectCaptcha) generateQuestion() string {
-	questions := []string{
-		"请选择所有的公交车",
-		"请选择所有的红绿灯",
-		"请选择所有的自行车",
-		"请选择所有的汽车",
+// generateQuestion 根据目标类别生成问题文本，优先使用清单中配置的本地化文本
+func (c *ImageSelectCaptcha) generateQuestion(dataset *imageDataset, targetType string) string {
+	if dataset != nil {
+		if question := dataset.question(targetType, c.config.Language); question != "" {
+			return question
+		}
 	}
-
-	rand.Seed(time.Now().UnixNano())
-	return questions[rand.Intn(len(questions))]
+	return fmt.Sprintf("请选择所有的%s", targetDisplayName(targetType))
 }
 
 // loadImageFromFile 从文件加载图片
@@ -405,8 +554,7 @@ func (c *ImageSelectCaptcha) loadImagesFromDir(dir string, count int) ([]image.I
 	}
 
 	// 随机打乱
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(images), func(i, j int) {
+	c.rng.Shuffle(len(images), func(i, j int) {
 		images[i], images[j] = images[j], images[i]
 	})
 
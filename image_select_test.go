@@ -0,0 +1,148 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// imageSelectTargets 从 Store 中读出图片选择验证码的目标索引
+func imageSelectTargets(t *testing.T, store Store, captchaID string) []int {
+	t.Helper()
+	value, err := store.Get(context.Background(), captchaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record captchaRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		t.Fatal(err)
+	}
+	var data ImageSelectData
+	if err := json.Unmarshal(record.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	return data.TargetIndexes
+}
+
+// TestImageSelectHiddenCount 目标数量不公开时响应中没有 selectCount，目标数量在范围内
+func TestImageSelectHiddenCount(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     ImageSelectConfig
+		wantHidden bool
+		min, max   int
+	}{
+		{name: "固定数量", config: ImageSelectConfig{ImageCount: 6, SelectCount: 2}, min: 2, max: 2},
+		{name: "数量范围", config: ImageSelectConfig{ImageCount: 6, MinSelectCount: 1, MaxSelectCount: 3}, wantHidden: true, min: 1, max: 3},
+		{name: "下限默认为 1", config: ImageSelectConfig{ImageCount: 6, MaxSelectCount: 2}, wantHidden: true, min: 1, max: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemStore()
+			service, err := NewService(store, WithImageSelectConfig(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer service.Close()
+
+			counts := make(map[int]bool)
+			for i := 0; i < 30; i++ {
+				resp, err := service.Generate(context.Background(), CaptchaTypeImageSelect)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, err := json.Marshal(resp)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hidden := !strings.Contains(string(body), `"selectCount"`); hidden != tt.wantHidden {
+					t.Fatalf("selectCount hidden = %v, want %v: %s", hidden, tt.wantHidden, body)
+				}
+
+				targets := imageSelectTargets(t, store, resp.CaptchaID)
+				if len(targets) < tt.min || len(targets) > tt.max {
+					t.Fatalf("target count = %d, want in [%d, %d]", len(targets), tt.min, tt.max)
+				}
+				if data := resp.Data.(ImageSelectCaptchaData); !tt.wantHidden && data.SelectCount != len(targets) {
+					t.Errorf("SelectCount = %d, want %d", data.SelectCount, len(targets))
+				}
+				counts[len(targets)] = true
+			}
+			if len(counts) != tt.max-tt.min+1 {
+				t.Errorf("target counts = %v, want every count in [%d, %d]", counts, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+// TestImageSelectVerifyExactSet 选中的图片必须与目标完全一致
+func TestImageSelectVerifyExactSet(t *testing.T) {
+	c := NewImageSelectCaptcha(ImageSelectConfig{ImageCount: 6, MaxSelectCount: 3})
+	targets := []int{1, 3, 4}
+
+	tests := []struct {
+		name     string
+		selected []int
+		want     bool
+	}{
+		{"完全一致", []int{1, 3, 4}, true},
+		{"顺序无关", []int{4, 1, 3}, true},
+		{"漏选", []int{1, 3}, false},
+		{"多选", []int{1, 3, 4, 5}, false},
+		{"选错", []int{1, 3, 5}, false},
+		{"重复选中凑数", []int{1, 3, 3}, false},
+		{"未选择", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Verify(targets, tt.selected); got != tt.want {
+				t.Errorf("Verify(%v) = %v, want %v", tt.selected, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestImageSelectVerifyThroughService 通过服务验证时同样要求完全一致
+func TestImageSelectVerifyThroughService(t *testing.T) {
+	store := NewMemStore()
+	service, err := NewService(store, WithImageSelectConfig(ImageSelectConfig{ImageCount: 6, MinSelectCount: 2, MaxSelectCount: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+	ctx := context.Background()
+
+	verify := func(captchaID string, selected []int) bool {
+		t.Helper()
+		ok, err := service.Verify(ctx, &VerifyRequest{
+			CaptchaID:   captchaID,
+			CaptchaType: CaptchaTypeImageSelect,
+			Answer:      ImageSelectAnswer{SelectedIndexes: selected},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	resp, err := service.Generate(ctx, CaptchaTypeImageSelect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := imageSelectTargets(t, store, resp.CaptchaID)
+	if verify(resp.CaptchaID, targets[:len(targets)-1]) {
+		t.Error("Verify() with a missing target = true")
+	}
+
+	resp, err = service.Generate(ctx, CaptchaTypeImageSelect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets = imageSelectTargets(t, store, resp.CaptchaID)
+	if !verify(resp.CaptchaID, targets) {
+		t.Error("Verify() with the exact targets = false")
+	}
+}
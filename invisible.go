@@ -0,0 +1,262 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Telemetry 前端采集的交互数据，用于无感验证
+type Telemetry struct {
+	Pointer   []TelemetryPoint `json:"pointer"`   // 鼠标或触摸轨迹，按时间顺序
+	Keys      []int64          `json:"keys"`      // 相邻两次按键的间隔（毫秒）
+	Focus     int              `json:"focus"`     // 获得和失去焦点的次数
+	Scroll    int              `json:"scroll"`    // 滚动次数
+	Duration  int64            `json:"duration"`  // 从页面加载到提交的时间（毫秒）
+	Webdriver bool             `json:"webdriver"` // navigator.webdriver，自动化工具控制的浏览器为 true
+}
+
+// TelemetryPoint 轨迹点
+type TelemetryPoint struct {
+	X int   `json:"x"`
+	Y int   `json:"y"`
+	T int64 `json:"t"` // 相对页面加载的时间（毫秒）
+}
+
+// TelemetryScorer 交互数据评分接口，返回 0-1 的风险分，分数越高越可能是机器
+type TelemetryScorer interface {
+	Score(ctx context.Context, telemetry Telemetry) (float64, error)
+}
+
+// HeuristicScorer 默认的启发式评分
+//
+// 只能拦截简单的脚本，不能替代可见验证码；风险分较高时应回退到可见验证码。
+type HeuristicScorer struct{}
+
+// Score 按自动化标记、停留时间、轨迹和按键节奏计分
+func (HeuristicScorer) Score(ctx context.Context, t Telemetry) (float64, error) {
+	if t.Webdriver {
+		return 1, nil
+	}
+
+	score := 0.0
+	// 人在页面上至少需要一两秒
+	if t.Duration < 1500 {
+		score += 0.4
+	}
+	// 没有任何交互
+	if len(t.Pointer) < 5 && len(t.Keys) == 0 && t.Scroll == 0 {
+		score += 0.4
+	}
+	// 轨迹是直线或匀速，通常由脚本生成
+	if len(t.Pointer) >= 5 && (isStraightPath(t.Pointer) || isUniform(pointerIntervals(t.Pointer))) {
+		score += 0.3
+	}
+	// 按键间隔几乎相同
+	if len(t.Keys) >= 4 && isUniform(t.Keys) {
+		score += 0.3
+	}
+	return min(score, 1), nil
+}
+
+// isStraightPath 所有轨迹点到首尾连线的距离都小于 2 像素
+func isStraightPath(points []TelemetryPoint) bool {
+	first, last := points[0], points[len(points)-1]
+	dx, dy := float64(last.X-first.X), float64(last.Y-first.Y)
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return true
+	}
+
+	for _, p := range points[1 : len(points)-1] {
+		distance := math.Abs(dy*float64(p.X-first.X)-dx*float64(p.Y-first.Y)) / length
+		if distance >= 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// pointerIntervals 相邻轨迹点的时间间隔
+func pointerIntervals(points []TelemetryPoint) []int64 {
+	intervals := make([]int64, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		intervals = append(intervals, points[i].T-points[i-1].T)
+	}
+	return intervals
+}
+
+// isUniform 间隔的标准差小于 3 毫秒
+func isUniform(intervals []int64) bool {
+	if len(intervals) < 2 {
+		return false
+	}
+
+	var sum float64
+	for _, v := range intervals {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(intervals))
+
+	var variance float64
+	for _, v := range intervals {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+	return math.Sqrt(variance/float64(len(intervals))) < 3
+}
+
+// InvisibleConfig 无感验证配置
+type InvisibleConfig struct {
+	Threshold      float64       `json:",default=0.5"`                                                       // 风险分低于该值时直接通过，默认 0.5；为 0 时按默认值处理，需要关闭直接通过时使用 AlwaysFallback
+	AlwaysFallback bool          `json:",optional"`                                                          // 为 true 时相当于阈值为 0，不签发通过凭证，所有请求都回退到可见验证码，忽略 Threshold
+	TicketTTL      time.Duration `json:",default=2m"`                                                        // 通过凭证的有效期，默认 2 分钟
+	FallbackType   CaptchaType   `json:",default=slide,options=character|image_select|slide|image_grid|pow"` // 未通过时回退的验证码类型，默认 slide
+	FallbackScene  string        `json:",optional"`                                                          // 未通过时回退的场景，优先于 FallbackType
+}
+
+// withDefaults 填充零值字段的默认值
+func (c InvisibleConfig) withDefaults() InvisibleConfig {
+	if c.AlwaysFallback {
+		c.Threshold = 0
+	} else if c.Threshold == 0 {
+		c.Threshold = 0.5
+	}
+	if c.TicketTTL == 0 {
+		c.TicketTTL = 2 * time.Minute
+	}
+	if c.FallbackType == "" {
+		c.FallbackType = SlideTypeSelect
+	}
+	return c
+}
+
+// Validate 校验无感验证配置，回退场景是否存在在创建服务时校验
+func (c InvisibleConfig) Validate() error {
+	c = c.withDefaults()
+	if c.Threshold < 0 || c.Threshold > 1 {
+		return fmt.Errorf("invisible: threshold must be in [0, 1], got %g", c.Threshold)
+	}
+	if c.TicketTTL < 0 {
+		return fmt.Errorf("invisible: ticket ttl must not be negative")
+	}
+	switch c.FallbackType {
+	case CaptchaTypeCharacter, CaptchaTypeImageSelect, SlideTypeSelect, CaptchaTypeImageGrid, CaptchaTypePow:
+	default:
+		return fmt.Errorf("invisible: %w: %q", ErrCaptchaTypeNotSupported, c.FallbackType)
+	}
+	return nil
+}
+
+// InvisibleResult 无感验证结果
+type InvisibleResult struct {
+	Passed  bool             `json:"passed"`            // 是否直接通过
+	Ticket  string           `json:"ticket,omitempty"`  // 通过凭证，业务接口使用 Service.VerifyTicket 校验
+	Score   float64          `json:"score"`             // 风险分
+	Captcha *CaptchaResponse `json:"captcha,omitempty"` // 未通过时回退的可见验证码
+}
+
+// passTicket 存储在 Store 中的通过凭证
+type passTicket struct {
+	Score     float64        `json:"score"`
+	CreatedAt int64          `json:"createdAt"`
+	Client    *clientBinding `json:"client,omitempty"`
+}
+
+// invisible 已开启的无感验证
+type invisible struct {
+	config InvisibleConfig
+	scorer TelemetryScorer
+}
+
+// Challenge 无感验证：按交互数据评分，风险分低于阈值时签发通过凭证，否则生成可见验证码
+//
+// 开启自适应难度时，风险分取交互评分和 EvaluateRisk 中较高的一个。
+func (s *Service) Challenge(ctx context.Context, telemetry Telemetry) (*InvisibleResult, error) {
+	if s.invisible == nil {
+		return nil, ErrInvisibleNotEnabled
+	}
+
+	score, err := s.invisible.scorer.Score(ctx, telemetry)
+	if err != nil {
+		logx.Errorf("交互数据评分失败，回退到可见验证码: %v", err)
+		score = 1
+	}
+	if risk, err := s.EvaluateRisk(ctx); err != nil {
+		logx.Errorf("风险评估失败，回退到可见验证码: %v", err)
+		score = 1
+	} else {
+		score = max(score, risk)
+	}
+
+	config := s.invisible.config
+	if score < config.Threshold {
+		// 回退时由 Generate 限流，签发凭证同样计入生成次数
+		if err := s.checkRate(ctx, rateActionGenerate); err != nil {
+			return nil, err
+		}
+		ticket, err := s.issueTicket(ctx, score, config.TicketTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &InvisibleResult{Passed: true, Ticket: ticket, Score: score}, nil
+	}
+
+	var resp *CaptchaResponse
+	if config.FallbackScene != "" {
+		resp, err = s.GenerateForScene(ctx, config.FallbackScene)
+	} else {
+		resp, err = s.Generate(ctx, config.FallbackType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &InvisibleResult{Score: score, Captcha: resp}, nil
+}
+
+// VerifyTicket 校验并作废通过凭证，开启客户端绑定时请求方必须与签发时一致
+func (s *Service) VerifyTicket(ctx context.Context, ticket string) (bool, error) {
+	// 读取和删除是原子的，并发提交同一个凭证时只有一个能通过
+	value, err := getDel(ctx, s.store, ticketKey(ticket))
+	if err != nil {
+		if errors.Is(err, ErrCaptchaNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get pass ticket: %w", err)
+	}
+
+	var t passTicket
+	if err := json.Unmarshal([]byte(value), &t); err != nil {
+		return false, fmt.Errorf("failed to unmarshal pass ticket: %w", err)
+	}
+
+	meta, _ := RequestMetaFromContext(ctx)
+	if field := s.binding.mismatch(t.Client, meta); field != "" {
+		logx.Infof("通过凭证请求方不一致: %s, 字段 %s", ticket, field)
+		return false, nil
+	}
+	return true, nil
+}
+
+// issueTicket 签发通过凭证
+func (s *Service) issueTicket(ctx context.Context, score float64, ttl time.Duration) (string, error) {
+	ticket := uuid.New().String()
+	t := passTicket{
+		Score:     score,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	if s.binding.enabled() {
+		meta, _ := RequestMetaFromContext(ctx)
+		t.Client = s.binding.bind(meta)
+	}
+
+	if err := s.store.Set(ctx, ticketKey(ticket), t, ttl); err != nil {
+		return "", fmt.Errorf("failed to store pass ticket: %w", err)
+	}
+	return ticket, nil
+}
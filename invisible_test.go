@@ -0,0 +1,177 @@
+package captcha
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fixedScorer 总是返回固定风险分的评分
+type fixedScorer float64
+
+// Score 返回固定的风险分
+func (s fixedScorer) Score(ctx context.Context, telemetry Telemetry) (float64, error) {
+	return float64(s), nil
+}
+
+// TestHeuristicScorer 各项异常分别计分，总分不超过 1
+func TestHeuristicScorer(t *testing.T) {
+	humanPath := []TelemetryPoint{{0, 0, 0}, {10, 5, 17}, {25, 7, 40}, {40, 20, 52}, {60, 22, 80}, {70, 40, 101}}
+	straightPath := []TelemetryPoint{{0, 0, 0}, {10, 10, 16}, {20, 20, 32}, {30, 30, 48}, {40, 40, 64}, {50, 50, 80}}
+
+	tests := []struct {
+		name      string
+		telemetry Telemetry
+		want      float64
+	}{
+		{"正常操作", Telemetry{Pointer: humanPath, Keys: []int64{120, 95, 180, 140}, Duration: 8000}, 0},
+		{"只有滚动", Telemetry{Scroll: 3, Duration: 5000}, 0},
+		{"自动化浏览器", Telemetry{Pointer: humanPath, Duration: 8000, Webdriver: true}, 1},
+		{"停留过短", Telemetry{Pointer: humanPath, Duration: 800}, 0.4},
+		{"没有交互", Telemetry{Pointer: humanPath[:4], Duration: 8000}, 0.4},
+		{"停留过短且没有交互", Telemetry{Duration: 500}, 0.8},
+		{"直线轨迹", Telemetry{Pointer: straightPath, Duration: 8000}, 0.3},
+		{"匀速按键", Telemetry{Keys: []int64{100, 101, 100, 99}, Duration: 8000}, 0.3},
+		{"按键少于 4 次不判断节奏", Telemetry{Keys: []int64{100, 100, 100}, Duration: 8000}, 0},
+		{"全部异常", Telemetry{Pointer: straightPath, Keys: []int64{50, 50, 50, 50}, Duration: 100}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := HeuristicScorer{}.Score(context.Background(), tt.telemetry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(score-tt.want) > 1e-9 {
+				t.Errorf("Score() = %g, want %g", score, tt.want)
+			}
+		})
+	}
+}
+
+// TestChallengeThreshold 风险分低于阈值时直接通过，AlwaysFallback 时总是回退到可见验证码
+func TestChallengeThreshold(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     InvisibleConfig
+		score      float64
+		wantPassed bool
+	}{
+		{"默认阈值 低风险", InvisibleConfig{}, 0.2, true},
+		{"默认阈值 等于阈值", InvisibleConfig{}, 0.5, false},
+		{"自定义阈值", InvisibleConfig{Threshold: 0.8}, 0.6, true},
+		{"总是回退 风险分为 0", InvisibleConfig{AlwaysFallback: true}, 0, false},
+		{"总是回退 忽略阈值", InvisibleConfig{Threshold: 0.9, AlwaysFallback: true}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewService(NewMemStore(), WithInvisible(tt.config), WithTelemetryScorer(fixedScorer(tt.score)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer service.Close()
+
+			result, err := service.Challenge(context.Background(), Telemetry{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Passed != tt.wantPassed {
+				t.Fatalf("Passed = %v, want %v", result.Passed, tt.wantPassed)
+			}
+			if tt.wantPassed && result.Ticket == "" {
+				t.Error("通过时没有凭证")
+			}
+			if !tt.wantPassed && (result.Ticket != "" || result.Captcha == nil || result.Captcha.CaptchaType != SlideTypeSelect) {
+				t.Errorf("未通过时 = %+v, want a slide captcha and no ticket", result)
+			}
+		})
+	}
+}
+
+// TestVerifyTicketSingleUse 凭证只能使用一次，并发校验时只有一个通过，未实现 AtomicStore 的存储结果相同
+func TestVerifyTicketSingleUse(t *testing.T) {
+	stores := testStores(t)
+	stores["plain"] = plainStore{store: NewMemStore()}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			service, err := NewService(store, WithInvisible(InvisibleConfig{}), WithTelemetryScorer(fixedScorer(0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer service.Close()
+			ctx := context.Background()
+
+			issue := func() string {
+				t.Helper()
+				result, err := service.Challenge(ctx, Telemetry{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return result.Ticket
+			}
+
+			ticket := issue()
+			for i, want := range []bool{true, false} {
+				if ok, err := service.VerifyTicket(ctx, ticket); err != nil || ok != want {
+					t.Errorf("第 %d 次 VerifyTicket() = %v, %v, want %v", i+1, ok, err, want)
+				}
+			}
+			if ok, err := service.VerifyTicket(ctx, "unknown"); err != nil || ok {
+				t.Errorf("未知凭证 VerifyTicket() = %v, %v, want false", ok, err)
+			}
+
+			// plainStore 的 Get+Del 不是原子的，只校验顺序使用
+			if name == "plain" {
+				return
+			}
+			ticket = issue()
+			var passed atomic.Int32
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if ok, err := service.VerifyTicket(ctx, ticket); err != nil {
+						t.Error(err)
+					} else if ok {
+						passed.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			if got := passed.Load(); got != 1 {
+				t.Errorf("并发校验通过 %d 次, want 1", got)
+			}
+		})
+	}
+}
+
+// TestVerifyTicketClientBinding 开启客户端绑定时凭证只能由签发时的请求方使用
+func TestVerifyTicketClientBinding(t *testing.T) {
+	service, err := NewService(NewMemStore(),
+		WithInvisible(InvisibleConfig{}),
+		WithTelemetryScorer(fixedScorer(0)),
+		WithClientBinding(ClientBindingConfig{IP: true}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	issuer := withMeta(RequestMeta{IP: "203.0.113.10"})
+	result, err := service.Challenge(issuer, Telemetry{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := service.VerifyTicket(withMeta(RequestMeta{IP: "198.51.100.10"}), result.Ticket); err != nil || ok {
+		t.Errorf("其他请求方 VerifyTicket() = %v, %v, want false", ok, err)
+	}
+	// 请求方不一致时凭证同样作废
+	if ok, err := service.VerifyTicket(issuer, result.Ticket); err != nil || ok {
+		t.Errorf("被盗用后 VerifyTicket() = %v, %v, want false", ok, err)
+	}
+}
@@ -0,0 +1,49 @@
+package captcha
+
+import "github.com/google/uuid"
+
+// Store 中各类数据的 key
+//
+// 验证码记录直接以验证码ID（UUID）为 key，其他数据各自带有前缀。验证时先用 validCaptchaID 校验ID，
+// 请求方无法通过构造验证码ID读到作废标记、通过凭证或失败计数。
+const (
+	markerKeyPrefix   = "marker:"        // 作废标记
+	assetKeyPrefix    = "asset:"         // 以地址下发的图片
+	attemptsKeyPrefix = "attempts:"      // 验证次数
+	ticketKeyPrefix   = "ticket:"        // 无感验证的通过凭证
+	failureKeyPrefix  = "risk:failures:" // 自适应难度的失败计数
+)
+
+// validCaptchaID 是否为服务生成的验证码ID格式，只接受带连字符的 36 位 UUID
+func validCaptchaID(captchaID string) bool {
+	if len(captchaID) != 36 {
+		return false
+	}
+	_, err := uuid.Parse(captchaID)
+	return err == nil
+}
+
+// markerKey 作废标记在 Store 中的 key
+func markerKey(captchaID string) string {
+	return markerKeyPrefix + captchaID
+}
+
+// attemptsKey 验证次数在 Store 中的 key
+func attemptsKey(captchaID string) string {
+	return attemptsKeyPrefix + captchaID
+}
+
+// assetKey 图片在 Store 中的 key
+func assetKey(captchaID, name string) string {
+	return assetKeyPrefix + captchaID + ":" + name
+}
+
+// ticketKey 通过凭证在 Store 中的 key
+func ticketKey(ticket string) string {
+	return ticketKeyPrefix + ticket
+}
+
+// failureKey 失败次数在 Store 中的 key，kind 为 ip、device 或 account
+func failureKey(kind, value string) string {
+	return failureKeyPrefix + kind + ":" + value
+}
@@ -0,0 +1,254 @@
+package captcha
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+// manifestNames ImageDir 下默认查找的清单文件名
+var manifestNames = []string{"manifest.json", "manifest.yaml", "manifest.yml"}
+
+// ImageManifest 图片选择验证码数据集清单
+//
+// 清单描述数据集中的类别、各语言的问题文本、图片文件及其授权信息，
+// 以及哪些类别容易混淆，运维人员新增类别时只需修改清单和图片目录。
+type ImageManifest struct {
+	Categories []ManifestCategory `json:"categories"`
+}
+
+// ManifestCategory 清单中的一个类别
+type ManifestCategory struct {
+	Name       string            `json:"name"`                // 类别名，如 bus
+	Group      string            `json:"group,optional"`      // 分组，与 ImageSelectConfig.Category 匹配，如 traffic
+	Question   map[string]string `json:"question,optional"`   // 各语言的问题文本，key 为语言，如 zh、en
+	Images     []ManifestImage   `json:"images"`              // 图片列表
+	Confusable []string          `json:"confusable,optional"` // 容易与该类别混淆的类别，优先作为干扰项
+}
+
+// ManifestImage 清单中的一张图片
+type ManifestImage struct {
+	File    string `json:"file"`             // 图片路径，相对路径基于清单文件所在目录
+	License string `json:"license,optional"` // 授权协议，如 CC0、CC-BY-4.0
+	Source  string `json:"source,optional"`  // 图片来源
+}
+
+// imageDataset 图片选择验证码使用的数据集
+type imageDataset struct {
+	categories map[string]*datasetCategory
+}
+
+// datasetCategory 数据集中的一个类别
+type datasetCategory struct {
+	name       string
+	group      string
+	questions  map[string]string
+	images     []string
+	confusable []string
+}
+
+// LoadImageManifest 加载数据集清单，支持 json、yaml、yml 格式
+func LoadImageManifest(path string) (*ImageManifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifest %s: %w", path, err)
+	}
+	return parseImageManifest(path, content)
+}
+
+// parseImageManifest 按文件扩展名解析清单内容
+func parseImageManifest(path string, content []byte) (*ImageManifest, error) {
+	var manifest ImageManifest
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = conf.LoadFromJsonBytes(content, &manifest)
+	case ".yaml", ".yml":
+		err = conf.LoadFromYamlBytes(content, &manifest)
+	default:
+		err = fmt.Errorf("unrecognized file type")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifest %s: %w", path, err)
+	}
+
+	if err := manifest.validate(); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", path, err)
+	}
+
+	return &manifest, nil
+}
+
+// validate 校验类别名不为空且不重复、每个类别至少有一张图片、混淆类别都存在
+func (m *ImageManifest) validate() error {
+	if len(m.Categories) == 0 {
+		return fmt.Errorf("no categories")
+	}
+
+	seen := make(map[string]bool, len(m.Categories))
+	for _, category := range m.Categories {
+		if category.Name == "" {
+			return fmt.Errorf("category without name")
+		}
+		if seen[category.Name] {
+			return fmt.Errorf("duplicate category %q", category.Name)
+		}
+		seen[category.Name] = true
+
+		if len(category.Images) == 0 {
+			return fmt.Errorf("category %q has no images", category.Name)
+		}
+		for _, img := range category.Images {
+			if img.File == "" {
+				return fmt.Errorf("category %q has an image without file", category.Name)
+			}
+		}
+	}
+
+	for _, category := range m.Categories {
+		for _, name := range category.Confusable {
+			if !seen[name] {
+				return fmt.Errorf("category %q: unknown confusable category %q", category.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkGroup 校验清单中存在属于 group 的类别，清单没有使用分组或 group 为空时不校验
+func (m *ImageManifest) checkGroup(group string) error {
+	if group == "" {
+		return nil
+	}
+
+	grouped := false
+	for _, category := range m.Categories {
+		if category.Group == group {
+			return nil
+		}
+		grouped = grouped || category.Group != ""
+	}
+	if !grouped {
+		return nil
+	}
+	return fmt.Errorf("no category in group %q", group)
+}
+
+// findManifest 查找清单文件：Manifest 优先，其次 ImageDir 本身是清单文件，最后在 ImageDir 下查找默认文件名
+func findManifest(config ImageSelectConfig, exists func(path string) bool) (string, bool) {
+	if config.Manifest != "" {
+		return config.Manifest, true
+	}
+	if config.ImageDir == "" {
+		return "", false
+	}
+
+	switch strings.ToLower(filepath.Ext(config.ImageDir)) {
+	case ".json", ".yaml", ".yml":
+		return config.ImageDir, true
+	}
+
+	for _, name := range manifestNames {
+		path := filepath.Join(config.ImageDir, name)
+		if exists(path) {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// newManifestDataset 根据清单构建数据集
+func newManifestDataset(manifest *ImageManifest, baseDir string) *imageDataset {
+	dataset := &imageDataset{categories: make(map[string]*datasetCategory)}
+	for _, category := range manifest.Categories {
+		images := make([]string, 0, len(category.Images))
+		for _, img := range category.Images {
+			path := img.File
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(baseDir, path)
+			}
+			images = append(images, path)
+		}
+		dataset.categories[category.Name] = &datasetCategory{
+			name:       category.Name,
+			group:      category.Group,
+			questions:  category.Question,
+			images:     images,
+			confusable: category.Confusable,
+		}
+	}
+	return dataset
+}
+
+// filterGroup 只保留指定分组的类别，没有任何类别属于该分组时返回错误
+//
+// 按目录构建的数据集没有分组，group 为空或数据集没有分组时保持不变。
+func (d *imageDataset) filterGroup(group string) (*imageDataset, error) {
+	if group == "" {
+		return d, nil
+	}
+
+	grouped := false
+	filtered := &imageDataset{categories: make(map[string]*datasetCategory)}
+	for name, category := range d.categories {
+		if category.group == group {
+			filtered.categories[name] = category
+		}
+		grouped = grouped || category.group != ""
+	}
+	if !grouped {
+		return d, nil
+	}
+	if len(filtered.categories) == 0 {
+		return nil, fmt.Errorf("no category in group %q", group)
+	}
+	return filtered, nil
+}
+
+// validateManifest 加载并校验配置对应的清单文件，没有清单时不校验
+func (c ImageSelectConfig) validateManifest() error {
+	path, ok := findManifest(c, func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+	if !ok {
+		return nil
+	}
+
+	manifest, err := LoadImageManifest(path)
+	if err != nil {
+		return err
+	}
+	if err := manifest.checkGroup(c.Category); err != nil {
+		return fmt.Errorf("manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// names 按名称排序的类别列表
+func (d *imageDataset) names() []string {
+	names := make([]string, 0, len(d.categories))
+	for name := range d.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// question 返回类别在指定语言下的问题文本，没有配置时返回空字符串
+func (d *imageDataset) question(name, language string) string {
+	category, ok := d.categories[name]
+	if !ok {
+		return ""
+	}
+	if question, ok := category.questions[language]; ok {
+		return question
+	}
+	return ""
+}
@@ -0,0 +1,194 @@
+package captcha
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeManifest 在临时目录中写入清单文件，返回文件路径
+func writeManifest(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testManifestJSON = `{
+  "categories": [
+    {"name": "bus", "group": "traffic", "question": {"zh": "请选择公交车"}, "images": [{"file": "bus/1.jpg", "license": "CC0"}], "confusable": ["car"]},
+    {"name": "car", "group": "traffic", "images": [{"file": "car/1.jpg"}]},
+    {"name": "cat", "group": "animal", "images": [{"file": "/data/cat/1.jpg"}]}
+  ]
+}`
+
+const testManifestYAML = `categories:
+  - name: bus
+    group: traffic
+    question:
+      zh: 请选择公交车
+    images:
+      - file: bus/1.jpg
+        license: CC0
+    confusable: [car]
+  - name: car
+    group: traffic
+    images:
+      - file: car/1.jpg
+  - name: cat
+    group: animal
+    images:
+      - file: /data/cat/1.jpg
+`
+
+// TestParseImageManifest json 和 yaml 清单解析结果一致
+func TestParseImageManifest(t *testing.T) {
+	want := &ImageManifest{Categories: []ManifestCategory{
+		{
+			Name:       "bus",
+			Group:      "traffic",
+			Question:   map[string]string{"zh": "请选择公交车"},
+			Images:     []ManifestImage{{File: "bus/1.jpg", License: "CC0"}},
+			Confusable: []string{"car"},
+		},
+		{Name: "car", Group: "traffic", Images: []ManifestImage{{File: "car/1.jpg"}}},
+		{Name: "cat", Group: "animal", Images: []ManifestImage{{File: "/data/cat/1.jpg"}}},
+	}}
+
+	for name, content := range map[string]string{
+		"manifest.json": testManifestJSON,
+		"manifest.yaml": testManifestYAML,
+		"manifest.yml":  testManifestYAML,
+	} {
+		t.Run(name, func(t *testing.T) {
+			manifest, err := LoadImageManifest(writeManifest(t, name, content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(manifest, want) {
+				t.Errorf("manifest = %+v, want %+v", manifest, want)
+			}
+
+			dataset := newManifestDataset(manifest, "/base")
+			if got := dataset.categories["bus"].images; !reflect.DeepEqual(got, []string{"/base/bus/1.jpg"}) {
+				t.Errorf("bus images = %v", got)
+			}
+			if got := dataset.categories["cat"].images; !reflect.DeepEqual(got, []string{"/data/cat/1.jpg"}) {
+				t.Errorf("cat images = %v", got)
+			}
+		})
+	}
+}
+
+// TestParseImageManifestInvalid 格式或内容不合法的清单返回错误
+func TestParseImageManifestInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"不支持的扩展名", "manifest.txt", testManifestJSON, "unrecognized file type"},
+		{"json 语法错误", "manifest.json", `{"categories": [`, "failed to load manifest"},
+		{"字段类型错误", "manifest.json", `{"categories": [{"name": "bus", "images": "bus.jpg"}]}`, "failed to load manifest"},
+		{"缺少图片列表", "manifest.yaml", "categories:\n  - name: bus\n", "failed to load manifest"},
+		{"没有类别", "manifest.json", `{"categories": []}`, "no categories"},
+		{"类别名为空", "manifest.json", `{"categories": [{"name": "", "images": [{"file": "a.jpg"}]}]}`, "category without name"},
+		{"类别重复", "manifest.json", `{"categories": [{"name": "bus", "images": [{"file": "a.jpg"}]}, {"name": "bus", "images": [{"file": "b.jpg"}]}]}`, `duplicate category "bus"`},
+		{"空类别", "manifest.json", `{"categories": [{"name": "bus", "images": []}]}`, `category "bus" has no images`},
+		{"图片路径为空", "manifest.json", `{"categories": [{"name": "bus", "images": [{"license": "CC0"}]}]}`, "failed to load manifest"},
+		{"未知的混淆类别", "manifest.json", `{"categories": [{"name": "bus", "images": [{"file": "a.jpg"}], "confusable": ["truck"]}]}`, `unknown confusable category "truck"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadImageManifest(writeManifest(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadImageManifest() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestImageDatasetFilterGroup 分组过滤，未知分组返回错误
+func TestImageDatasetFilterGroup(t *testing.T) {
+	path := writeManifest(t, "manifest.json", testManifestJSON)
+	manifest, err := LoadImageManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset := newManifestDataset(manifest, filepath.Dir(path))
+
+	tests := []struct {
+		group   string
+		want    []string
+		wantErr bool
+	}{
+		{group: "", want: []string{"bus", "car", "cat"}},
+		{group: "traffic", want: []string{"bus", "car"}},
+		{group: "animal", want: []string{"cat"}},
+		{group: "food", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		filtered, err := dataset.filterGroup(tt.group)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("filterGroup(%q) error = %v, wantErr %v", tt.group, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(filtered.names(), tt.want) {
+			t.Errorf("filterGroup(%q) = %v, want %v", tt.group, filtered.names(), tt.want)
+		}
+	}
+
+	// 按目录构建的数据集没有分组，Category 不生效
+	scanned := &imageDataset{categories: map[string]*datasetCategory{"bus": {name: "bus"}, "car": {name: "car"}}}
+	if filtered, err := scanned.filterGroup("traffic"); err != nil || len(filtered.categories) != 2 {
+		t.Errorf("filterGroup() on scanned dataset = %v, %v", filtered, err)
+	}
+}
+
+// TestImageSelectConfigValidateManifest NewService 校验清单文件和 Category
+func TestImageSelectConfigValidateManifest(t *testing.T) {
+	valid := writeManifest(t, "manifest.json", testManifestJSON)
+	invalid := writeManifest(t, "manifest.yaml", "categories:\n  - name: bus\n    images: []\n")
+
+	tests := []struct {
+		name    string
+		config  ImageSelectConfig
+		wantErr string
+	}{
+		{name: "清单有效", config: ImageSelectConfig{Manifest: valid, Category: "traffic"}},
+		{name: "Category 为空使用全部分组", config: ImageSelectConfig{Manifest: valid}},
+		{name: "ImageDir 指向清单", config: ImageSelectConfig{ImageDir: valid, Category: "animal"}},
+		{name: "在 ImageDir 下查找清单", config: ImageSelectConfig{ImageDir: filepath.Dir(valid), Category: "traffic"}},
+		{name: "没有清单不校验 Category", config: ImageSelectConfig{ImageDir: t.TempDir(), Category: "traffic"}},
+		{name: "未知分组", config: ImageSelectConfig{Manifest: valid, Category: "food"}, wantErr: `no category in group "food"`},
+		{name: "清单不合法", config: ImageSelectConfig{Manifest: invalid}, wantErr: `category "bus" has no images`},
+		{name: "清单不存在", config: ImageSelectConfig{Manifest: filepath.Join(t.TempDir(), "missing.json")}, wantErr: "failed to load manifest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewService(NewMemStore(), WithImageSelectConfig(tt.config))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewService() error = %v", err)
+				}
+				service.Close()
+				return
+			}
+			if err == nil {
+				service.Close()
+				t.Fatalf("NewService() error = nil, want %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewService() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
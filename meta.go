@@ -0,0 +1,27 @@
+package captcha
+
+import "context"
+
+// RequestMeta 请求方信息，由接入层通过 WithRequestMeta 放入 context，
+// 用于风险评估、限流和客户端绑定
+type RequestMeta struct {
+	IP        string // 客户端 IP
+	UserAgent string // User-Agent
+	Account   string // 账号，未登录时为空
+	DeviceID  string // 设备指纹，没有时为空
+	SessionID string // 会话 ID，没有时为空
+}
+
+// requestMetaKey context 中 RequestMeta 的 key
+type requestMetaKey struct{}
+
+// WithRequestMeta 返回带有请求方信息的 context
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFromContext 从 context 中取出请求方信息
+func RequestMetaFromContext(ctx context.Context) (RequestMeta, bool) {
+	meta, ok := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta, ok
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"github.com/gpencil/captcha"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serviceCollector 采集时读取服务的验证码池状态、事件丢弃数和限流器出错次数
+type serviceCollector struct {
+	service *captcha.Service
+
+	poolCapacity  *prometheus.Desc
+	poolAvailable *prometheus.Desc
+	poolHits      *prometheus.Desc
+	poolMisses    *prometheus.Desc
+	poolGenerated *prometheus.Desc
+	poolExpired   *prometheus.Desc
+	poolFailures  *prometheus.Desc
+	eventsDropped *prometheus.Desc
+	limiterErrors *prometheus.Desc
+}
+
+// newServiceCollector 创建服务状态采集器
+func newServiceCollector(namespace string, service *captcha.Service) *serviceCollector {
+	return &serviceCollector{
+		service: service,
+		poolCapacity: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", "capacity"),
+			"Capacity of the captcha pool.", []string{"type"}, nil),
+		poolAvailable: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", "available"),
+			"Number of pre-rendered captchas available in the pool.", []string{"type"}, nil),
+		poolHits: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", "hits_total"),
+			"Number of captchas taken from the pool.", []string{"type"}, nil),
+		poolMisses: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", "misses_total"),
+			"Number of captchas rendered synchronously because the pool was empty.", []string{"type"}, nil),
+		poolGenerated: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", "generated_total"),
+			"Number of captchas rendered by pool workers.", []string{"type"}, nil),
+		poolExpired: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", "expired_total"),
+			"Number of pooled captchas discarded because they exceeded MaxAge.", []string{"type"}, nil),
+		poolFailures: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", "failures_total"),
+			"Number of failed background renders.", []string{"type"}, nil),
+		eventsDropped: prometheus.NewDesc(prometheus.BuildFQName(namespace, "events", "dropped_total"),
+			"Number of events dropped because the observer buffer was full.", nil, nil),
+		limiterErrors: prometheus.NewDesc(prometheus.BuildFQName(namespace, "rate_limiter", "errors_total"),
+			"Number of rate limiter errors.", nil, nil),
+	}
+}
+
+// Describe 实现 prometheus.Collector
+func (c *serviceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.poolCapacity
+	ch <- c.poolAvailable
+	ch <- c.poolHits
+	ch <- c.poolMisses
+	ch <- c.poolGenerated
+	ch <- c.poolExpired
+	ch <- c.poolFailures
+	ch <- c.eventsDropped
+	ch <- c.limiterErrors
+}
+
+// Collect 实现 prometheus.Collector
+func (c *serviceCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.service.PoolStats() {
+		captchaType := string(stats.Type)
+		ch <- prometheus.MustNewConstMetric(c.poolCapacity, prometheus.GaugeValue, float64(stats.Capacity), captchaType)
+		ch <- prometheus.MustNewConstMetric(c.poolAvailable, prometheus.GaugeValue, float64(stats.Available), captchaType)
+		ch <- prometheus.MustNewConstMetric(c.poolHits, prometheus.CounterValue, float64(stats.Hits), captchaType)
+		ch <- prometheus.MustNewConstMetric(c.poolMisses, prometheus.CounterValue, float64(stats.Misses), captchaType)
+		ch <- prometheus.MustNewConstMetric(c.poolGenerated, prometheus.CounterValue, float64(stats.Generated), captchaType)
+		ch <- prometheus.MustNewConstMetric(c.poolExpired, prometheus.CounterValue, float64(stats.Expired), captchaType)
+		ch <- prometheus.MustNewConstMetric(c.poolFailures, prometheus.CounterValue, float64(stats.Failures), captchaType)
+	}
+	ch <- prometheus.MustNewConstMetric(c.eventsDropped, prometheus.CounterValue, float64(c.service.DroppedEvents()))
+	ch <- prometheus.MustNewConstMetric(c.limiterErrors, prometheus.CounterValue, float64(c.service.RateLimiterErrors()))
+}
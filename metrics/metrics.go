@@ -0,0 +1,248 @@
+// Package metrics 验证码服务的 Prometheus 指标
+//
+// Metrics 同时是 captcha.Observer 和 Store 包装器：通过 captcha.WithObserver 接收生成、验证、限流事件，
+// 统计各类型和场景的生成数、通过数、失败数、生成耗时、图片大小和通过率；用 Store 包装存储后统计存储耗时和错误；
+// 创建服务后调用 RegisterService 导出验证码池、事件丢弃和限流器出错的状态。
+//
+//	registry := prometheus.NewRegistry()
+//	m, err := metrics.New(registry, metrics.Config{})
+//	service, err := captcha.NewService(m.Store(store), captcha.WithObserver(m))
+//	err = m.RegisterService(service)
+//	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gpencil/captcha"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Config 指标配置
+type Config struct {
+	Namespace       string    `json:",default=captcha"` // 指标名前缀，默认 captcha
+	LatencyBuckets  []float64 `json:",optional"`        // 生成耗时和存储耗时的分桶（秒），默认 prometheus.DefBuckets
+	SizeBuckets     []float64 `json:",optional"`        // 图片大小的分桶（字节），默认 1KB 到 1MB 按 4 倍递增
+	PassRateSamples int       `json:",default=1000"`    // 通过率按最近多少次验证计算，默认 1000
+}
+
+// withDefaults 填充零值字段的默认值
+func (c Config) withDefaults() Config {
+	if c.Namespace == "" {
+		c.Namespace = "captcha"
+	}
+	if len(c.LatencyBuckets) == 0 {
+		c.LatencyBuckets = prometheus.DefBuckets
+	}
+	if len(c.SizeBuckets) == 0 {
+		c.SizeBuckets = prometheus.ExponentialBuckets(1024, 4, 6)
+	}
+	if c.PassRateSamples == 0 {
+		c.PassRateSamples = 1000
+	}
+	return c
+}
+
+// Validate 校验指标配置
+func (c Config) Validate() error {
+	if c.PassRateSamples < 0 {
+		return fmt.Errorf("metrics: pass rate samples must not be negative")
+	}
+	return nil
+}
+
+// Metrics 验证码服务指标
+type Metrics struct {
+	registerer prometheus.Registerer
+	namespace  string
+
+	generated   *prometheus.CounterVec
+	verified    *prometheus.CounterVec
+	failed      *prometheus.CounterVec
+	rateLimited *prometheus.CounterVec
+	genLatency  *prometheus.HistogramVec
+	imageBytes  *prometheus.HistogramVec
+	passRate    *prometheus.GaugeVec
+
+	storeLatency *prometheus.HistogramVec
+	storeErrors  *prometheus.CounterVec
+
+	samples int
+	mu      sync.Mutex
+	windows map[[2]string]*passWindow // key 为类型和场景
+}
+
+// New 创建指标并注册到 registerer
+func New(registerer prometheus.Registerer, config Config) (*Metrics, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.withDefaults()
+	ns := config.Namespace
+
+	m := &Metrics{
+		registerer: registerer,
+		namespace:  ns,
+		generated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "generated_total",
+			Help:      "Number of captchas generated.",
+		}, []string{"type", "scene"}),
+		verified: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "verified_total",
+			Help:      "Number of captchas verified successfully.",
+		}, []string{"type", "scene"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "failed_total",
+			Help:      "Number of failed verifications by reason.",
+		}, []string{"type", "scene", "reason"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "rate_limited_total",
+			Help:      "Number of rate limited requests.",
+		}, []string{"action"}),
+		genLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Name:      "generate_duration_seconds",
+			Help:      "Time spent generating a captcha, including rendering and storing.",
+			Buckets:   config.LatencyBuckets,
+		}, []string{"type", "scene"}),
+		imageBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Name:      "image_bytes",
+			Help:      "Total decoded image size of a generated captcha.",
+			Buckets:   config.SizeBuckets,
+		}, []string{"type", "scene"}),
+		passRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns,
+			Name:      "pass_rate",
+			Help:      fmt.Sprintf("Pass rate of the last %d verifications.", config.PassRateSamples),
+		}, []string{"type", "scene"}),
+		storeLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Name:      "store_duration_seconds",
+			Help:      "Time spent on store operations.",
+			Buckets:   config.LatencyBuckets,
+		}, []string{"op"}),
+		storeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "store_errors_total",
+			Help:      "Number of failed store operations, not including missing keys.",
+		}, []string{"op"}),
+		samples: config.PassRateSamples,
+		windows: make(map[[2]string]*passWindow),
+	}
+
+	collectors := []prometheus.Collector{
+		m.generated, m.verified, m.failed, m.rateLimited, m.genLatency,
+		m.imageBytes, m.passRate, m.storeLatency, m.storeErrors,
+	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register captcha metrics: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// unknownType 验证码不存在、无法得知类型时使用的 type 标签
+const unknownType = "unknown"
+
+// typeLabel 返回 type 标签，类型为空时为 unknown
+func typeLabel(captchaType captcha.CaptchaType) string {
+	if captchaType == "" {
+		return unknownType
+	}
+	return string(captchaType)
+}
+
+// OnGenerate 统计生成数、生成耗时和图片大小
+func (m *Metrics) OnGenerate(event captcha.Event) {
+	labels := prometheus.Labels{"type": typeLabel(event.CaptchaType), "scene": event.Scene}
+	m.generated.With(labels).Inc()
+	m.genLatency.With(labels).Observe(event.Duration.Seconds())
+	if event.ImageBytes > 0 {
+		m.imageBytes.With(labels).Observe(float64(event.ImageBytes))
+	}
+}
+
+// OnVerify 统计通过数、失败数和通过率，验证码不存在时类型为 unknown，不计入通过率
+func (m *Metrics) OnVerify(event captcha.Event) {
+	if event.Result.OK {
+		m.verified.WithLabelValues(typeLabel(event.CaptchaType), event.Scene).Inc()
+	} else {
+		m.failed.WithLabelValues(typeLabel(event.CaptchaType), event.Scene, string(event.Result.Reason)).Inc()
+	}
+	if event.CaptchaType != "" {
+		m.observePass(event.CaptchaType, event.Scene, event.Result.OK)
+	}
+}
+
+// OnExpire 过期计入失败数和通过率
+func (m *Metrics) OnExpire(event captcha.Event) {
+	m.failed.WithLabelValues(typeLabel(event.CaptchaType), event.Scene, string(captcha.VerifyReasonExpired)).Inc()
+	if event.CaptchaType != "" {
+		m.observePass(event.CaptchaType, event.Scene, false)
+	}
+}
+
+// OnRateLimited 统计限流次数
+func (m *Metrics) OnRateLimited(event captcha.Event) {
+	m.rateLimited.WithLabelValues(event.Action).Inc()
+}
+
+// observePass 记录一次验证结果并更新通过率
+func (m *Metrics) observePass(captchaType captcha.CaptchaType, scene string, ok bool) {
+	key := [2]string{string(captchaType), scene}
+
+	m.mu.Lock()
+	w, exists := m.windows[key]
+	if !exists {
+		w = &passWindow{results: make([]bool, m.samples)}
+		m.windows[key] = w
+	}
+	rate := w.add(ok)
+	m.mu.Unlock()
+
+	m.passRate.WithLabelValues(key[0], key[1]).Set(rate)
+}
+
+// passWindow 最近若干次验证结果的环形缓冲区
+type passWindow struct {
+	results []bool
+	next    int
+	count   int
+	passed  int
+}
+
+// add 记录一次验证结果，返回窗口内的通过率
+func (w *passWindow) add(ok bool) float64 {
+	if w.count == len(w.results) {
+		if w.results[w.next] {
+			w.passed--
+		}
+	} else {
+		w.count++
+	}
+	w.results[w.next] = ok
+	if ok {
+		w.passed++
+	}
+	w.next = (w.next + 1) % len(w.results)
+	return float64(w.passed) / float64(w.count)
+}
+
+// RegisterService 导出验证码池容量、可用数量、命中、未命中、后台生成和过期丢弃次数，因缓冲区已满丢弃的事件数和限流器出错次数
+func (m *Metrics) RegisterService(service *captcha.Service) error {
+	if service == nil {
+		return errors.New("metrics: service is required")
+	}
+	if err := m.registerer.Register(newServiceCollector(m.namespace, service)); err != nil {
+		return fmt.Errorf("failed to register captcha service metrics: %w", err)
+	}
+	return nil
+}
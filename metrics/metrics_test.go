@@ -0,0 +1,308 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpencil/captcha"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// TestPassWindow 通过率按最近若干次验证计算，写满后覆盖最早的结果
+func TestPassWindow(t *testing.T) {
+	w := &passWindow{results: make([]bool, 3)}
+	steps := []struct {
+		ok   bool
+		want float64
+	}{
+		{ok: true, want: 1},
+		{ok: false, want: 1.0 / 2},
+		{ok: false, want: 1.0 / 3},
+		{ok: true, want: 1.0 / 3}, // 覆盖第 1 次的通过
+		{ok: true, want: 2.0 / 3}, // 覆盖第 2 次的失败
+		{ok: true, want: 1},
+		{ok: false, want: 2.0 / 3},
+	}
+	for i, step := range steps {
+		if got := w.add(step.ok); math.Abs(got-step.want) > 1e-9 {
+			t.Errorf("第 %d 次 add(%v) = %v, want %v", i+1, step.ok, got, step.want)
+		}
+	}
+}
+
+// failingStore 所有操作都返回错误的存储
+type failingStore struct{}
+
+var errStoreDown = errors.New("store down")
+
+func (failingStore) Set(context.Context, string, interface{}, time.Duration) error {
+	return errStoreDown
+}
+func (failingStore) Get(context.Context, string) (string, error)    { return "", errStoreDown }
+func (failingStore) Del(context.Context, string) error              { return errStoreDown }
+func (failingStore) GetDel(context.Context, string) (string, error) { return "", errStoreDown }
+func (failingStore) Incr(context.Context, string, time.Duration) (int64, error) {
+	return 0, errStoreDown
+}
+
+// TestInstrumentedStore 每次操作记录耗时，出错时计数，key 不存在不计为错误
+func TestInstrumentedStore(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := New(registry, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	store, ok := m.Store(captcha.NewMemStore()).(captcha.AtomicStore)
+	if !ok {
+		t.Fatal("instrumented MemStore does not implement AtomicStore")
+	}
+	if err := store.Set(ctx, "k", "v", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, captcha.ErrCaptchaNotFound) {
+		t.Fatalf("Get(missing) error = %v", err)
+	}
+	if _, err := store.GetDel(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Incr(ctx, "n", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Del(ctx, "n"); err != nil {
+		t.Fatal(err)
+	}
+	bytesStore, ok := m.Store(captcha.NewMemStore()).(captcha.BytesStore)
+	if !ok {
+		t.Fatal("instrumented MemStore does not implement BytesStore")
+	}
+	if err := bytesStore.SetBytes(ctx, "b", []byte("raw"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := bytesStore.GetBytes(ctx, "b"); err != nil || string(value) != "raw" {
+		t.Fatalf("GetBytes() = %q, %v", value, err)
+	}
+
+	broken := m.Store(failingStore{}).(captcha.AtomicStore)
+	if _, err := broken.Get(ctx, "k"); !errors.Is(err, errStoreDown) {
+		t.Fatalf("Get() error = %v, want errStoreDown", err)
+	}
+	if _, err := broken.Incr(ctx, "n", time.Minute); !errors.Is(err, errStoreDown) {
+		t.Fatalf("Incr() error = %v, want errStoreDown", err)
+	}
+
+	families := gather(t, registry)
+	wantCounts := map[string]uint64{"set": 1, "get": 3, "getdel": 1, "incr": 2, "del": 1, "setbytes": 1, "getbytes": 1}
+	for op, want := range wantCounts {
+		metric := findMetric(t, families, "captcha_store_duration_seconds", map[string]string{"op": op})
+		if got := metric.GetHistogram().GetSampleCount(); got != want {
+			t.Errorf("store_duration_seconds{op=%q} count = %d, want %d", op, got, want)
+		}
+	}
+	wantErrors := map[string]float64{"get": 1, "incr": 1}
+	for op, want := range wantErrors {
+		metric := findMetric(t, families, "captcha_store_errors_total", map[string]string{"op": op})
+		if got := metric.GetCounter().GetValue(); got != want {
+			t.Errorf("store_errors_total{op=%q} = %v, want %v", op, got, want)
+		}
+	}
+	if len(families["captcha_store_errors_total"].GetMetric()) != len(wantErrors) {
+		t.Errorf("store_errors_total = %v, want only get and incr", families["captcha_store_errors_total"].GetMetric())
+	}
+}
+
+// TestMetricsGather 接入服务后导出的指标名和标签
+func TestMetricsGather(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := New(registry, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, ok := m.Store(captcha.NewMemStore()).(captcha.AtomicStore)
+	if !ok {
+		t.Fatal("instrumented MemStore does not implement AtomicStore")
+	}
+	service, err := captcha.NewService(store,
+		captcha.WithObserver(m),
+		captcha.WithPool(captcha.CaptchaTypeCharacter, captcha.PoolConfig{Size: 2}),
+		captcha.WithRateLimit(captcha.RateLimitConfig{Verify: captcha.RateLimit{Limit: 3}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RegisterService(service); err != nil {
+		t.Fatal(err)
+	}
+
+	// 等待验证码池填满
+	deadline := time.Now().Add(5 * time.Second)
+	for service.PoolStats()[0].Available < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("验证码池未在 5 秒内填满")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx := captcha.WithRequestMeta(context.Background(), captcha.RequestMeta{IP: "1.2.3.4"})
+	resp, err := service.Generate(ctx, captcha.CaptchaTypeCharacter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := func(id string, code string) {
+		t.Helper()
+		_, err := service.VerifyDetailed(ctx, &captcha.VerifyRequest{
+			CaptchaID:   id,
+			CaptchaType: captcha.CaptchaTypeCharacter,
+			Answer:      captcha.CharacterAnswer{Code: code},
+		})
+		if err != nil && !errors.Is(err, captcha.ErrRateLimited) {
+			t.Fatal(err)
+		}
+	}
+	code := characterCode(t, store, resp.CaptchaID)
+	verify(resp.CaptchaID, "!!!!")
+	verify(resp.CaptchaID, code)
+	verify("6f1c2a8e-0000-4000-8000-000000000000", code)
+	verify(resp.CaptchaID, code) // 被限流
+	service.Close()
+
+	families := gather(t, registry)
+	for _, name := range []string{
+		"captcha_generated_total",
+		"captcha_verified_total",
+		"captcha_failed_total",
+		"captcha_pass_rate",
+		"captcha_rate_limited_total",
+		"captcha_generate_duration_seconds",
+		"captcha_image_bytes",
+		"captcha_store_duration_seconds",
+		"captcha_pool_capacity",
+		"captcha_pool_available",
+		"captcha_pool_hits_total",
+		"captcha_pool_misses_total",
+		"captcha_pool_generated_total",
+		"captcha_pool_expired_total",
+		"captcha_pool_failures_total",
+		"captcha_events_dropped_total",
+		"captcha_rate_limiter_errors_total",
+	} {
+		if _, ok := families[name]; !ok {
+			t.Errorf("missing metric %s", name)
+		}
+	}
+
+	character := map[string]string{"type": "character", "scene": ""}
+	checks := []struct {
+		name   string
+		labels map[string]string
+		want   float64
+	}{
+		{name: "captcha_generated_total", labels: character, want: 1},
+		{name: "captcha_verified_total", labels: character, want: 1},
+		{name: "captcha_failed_total", labels: map[string]string{"type": "character", "scene": "", "reason": "wrong_answer"}, want: 1},
+		{name: "captcha_failed_total", labels: map[string]string{"type": "unknown", "scene": "", "reason": "not_found"}, want: 1},
+		{name: "captcha_pass_rate", labels: character, want: 0.5},
+		{name: "captcha_rate_limited_total", labels: map[string]string{"action": "verify"}, want: 1},
+		{name: "captcha_pool_capacity", labels: map[string]string{"type": "character"}, want: 2},
+		{name: "captcha_pool_hits_total", labels: map[string]string{"type": "character"}, want: 1},
+		{name: "captcha_pool_misses_total", labels: map[string]string{"type": "character"}, want: 0},
+	}
+	for _, c := range checks {
+		metric := findMetric(t, families, c.name, c.labels)
+		if got := metricValue(metric); got != c.want {
+			t.Errorf("%s%v = %v, want %v", c.name, c.labels, got, c.want)
+		}
+	}
+	if generated := metricValue(findMetric(t, families, "captcha_pool_generated_total", map[string]string{"type": "character"})); generated < 2 {
+		t.Errorf("captcha_pool_generated_total = %v, want >= 2", generated)
+	}
+	// 验证码不存在时不使用空的 type 标签，也不计入通过率
+	for _, name := range []string{"captcha_failed_total", "captcha_verified_total", "captcha_pass_rate"} {
+		for _, metric := range families[name].GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "type" && (label.GetValue() == "" || (name == "captcha_pass_rate" && label.GetValue() == "unknown")) {
+					t.Errorf("%s has type=%q", name, label.GetValue())
+				}
+			}
+		}
+	}
+}
+
+// gather 采集 registry 中的指标，key 为指标名
+func gather(t *testing.T, registry *prometheus.Registry) map[string]*dto.MetricFamily {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+	return byName
+}
+
+// findMetric 按标签查找指标，标签必须完全一致
+func findMetric(t *testing.T, families map[string]*dto.MetricFamily, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+	family, ok := families[name]
+	if !ok {
+		t.Fatalf("missing metric %s", name)
+	}
+	for _, metric := range family.GetMetric() {
+		if len(metric.GetLabel()) != len(labels) {
+			continue
+		}
+		match := true
+		for _, label := range metric.GetLabel() {
+			if value, ok := labels[label.GetName()]; !ok || value != label.GetValue() {
+				match = false
+				break
+			}
+		}
+		if match {
+			return metric
+		}
+	}
+	t.Fatalf("missing metric %s%v", name, labels)
+	return nil
+}
+
+// metricValue 返回计数器或仪表盘的值
+func metricValue(metric *dto.Metric) float64 {
+	if metric.GetCounter() != nil {
+		return metric.GetCounter().GetValue()
+	}
+	return metric.GetGauge().GetValue()
+}
+
+// characterCode 从存储的验证码记录中读出字符验证码的答案
+func characterCode(t *testing.T, store captcha.Store, captchaID string) string {
+	t.Helper()
+	value, err := store.Get(context.Background(), captchaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record struct {
+		Data struct {
+			Code string `json:"code"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(record.Data.Code) == "" {
+		t.Fatal("empty character code")
+	}
+	return record.Data.Code
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gpencil/captcha"
+)
+
+// Store 包装存储，统计每次操作的耗时和错误，key 不存在不计为错误
+//
+// store 实现了 captcha.AtomicStore 或 captcha.BytesStore 时，返回的存储同样实现对应的接口。
+func (m *Metrics) Store(store captcha.Store) captcha.Store {
+	s := &instrumentedStore{store: store, metrics: m}
+	atomicStore, isAtomic := store.(captcha.AtomicStore)
+	bytesStore, isBytes := store.(captcha.BytesStore)
+
+	switch {
+	case isAtomic && isBytes:
+		return &struct {
+			*instrumentedStore
+			atomicOps
+			bytesOps
+		}{s, atomicOps{s, atomicStore}, bytesOps{s, bytesStore}}
+	case isAtomic:
+		return &struct {
+			*instrumentedStore
+			atomicOps
+		}{s, atomicOps{s, atomicStore}}
+	case isBytes:
+		return &struct {
+			*instrumentedStore
+			bytesOps
+		}{s, bytesOps{s, bytesStore}}
+	default:
+		return s
+	}
+}
+
+// instrumentedStore 统计耗时和错误的存储
+type instrumentedStore struct {
+	store   captcha.Store
+	metrics *Metrics
+}
+
+// Set 存储数据
+func (s *instrumentedStore) Set(ctx context.Context, captchaID string, data interface{}, expireTime time.Duration) error {
+	start := time.Now()
+	err := s.store.Set(ctx, captchaID, data, expireTime)
+	s.observe("set", start, err)
+	return err
+}
+
+// Get 获取数据
+func (s *instrumentedStore) Get(ctx context.Context, captchaID string) (string, error) {
+	start := time.Now()
+	value, err := s.store.Get(ctx, captchaID)
+	s.observe("get", start, err)
+	return value, err
+}
+
+// Del 删除数据
+func (s *instrumentedStore) Del(ctx context.Context, captchaID string) error {
+	start := time.Now()
+	err := s.store.Del(ctx, captchaID)
+	s.observe("del", start, err)
+	return err
+}
+
+// observe 记录一次操作的耗时和错误
+func (s *instrumentedStore) observe(op string, start time.Time, err error) {
+	s.metrics.storeLatency.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, captcha.ErrCaptchaNotFound) {
+		s.metrics.storeErrors.WithLabelValues(op).Inc()
+	}
+}
+
+// atomicOps 统计 captcha.AtomicStore 的原子操作
+type atomicOps struct {
+	s     *instrumentedStore
+	store captcha.AtomicStore
+}
+
+// GetDel 读取并删除数据
+func (o atomicOps) GetDel(ctx context.Context, captchaID string) (string, error) {
+	start := time.Now()
+	value, err := o.store.GetDel(ctx, captchaID)
+	o.s.observe("getdel", start, err)
+	return value, err
+}
+
+// Incr 原子自增计数
+func (o atomicOps) Incr(ctx context.Context, key string, expireTime time.Duration) (int64, error) {
+	start := time.Now()
+	n, err := o.store.Incr(ctx, key, expireTime)
+	o.s.observe("incr", start, err)
+	return n, err
+}
+
+// bytesOps 统计 captcha.BytesStore 的字节读写
+type bytesOps struct {
+	s     *instrumentedStore
+	store captcha.BytesStore
+}
+
+// SetBytes 原样保存字节
+func (o bytesOps) SetBytes(ctx context.Context, key string, value []byte, expireTime time.Duration) error {
+	start := time.Now()
+	err := o.store.SetBytes(ctx, key, value, expireTime)
+	o.s.observe("setbytes", start, err)
+	return err
+}
+
+// GetBytes 读取字节
+func (o bytesOps) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	value, err := o.store.GetBytes(ctx, key)
+	o.s.observe("getbytes", start, err)
+	return value, err
+}
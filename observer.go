@@ -0,0 +1,190 @@
+package captcha
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// EventKind 事件类型
+type EventKind string
+
+const (
+	EventGenerate    EventKind = "generate"     // 生成验证码
+	EventVerify      EventKind = "verify"       // 验证验证码
+	EventExpire      EventKind = "expire"       // 验证时发现验证码已过期
+	EventRateLimited EventKind = "rate_limited" // 请求被限流
+)
+
+// Event 验证码事件
+type Event struct {
+	Kind        EventKind     `json:"kind"`                  // 事件类型
+	Time        time.Time     `json:"time"`                  // 事件发生时间
+	CaptchaID   string        `json:"captchaId,omitempty"`   // 验证码ID，限流事件和验证码不存在时为空
+	CaptchaType CaptchaType   `json:"captchaType,omitempty"` // 验证码类型
+	Scene       string        `json:"scene,omitempty"`       // 场景名
+	Meta        RequestMeta   `json:"meta"`                  // 请求方信息，来自 WithRequestMeta
+	Action      string        `json:"action,omitempty"`      // 限流事件被限流的操作：generate 或 verify
+	Result      *VerifyResult `json:"result,omitempty"`      // 验证和过期事件的验证结果
+	SolveTime   time.Duration `json:"solveTime,omitempty"`   // 验证和过期事件中从生成到验证的时间
+	Duration    time.Duration `json:"duration,omitempty"`    // 生成事件中生成验证码的耗时，包括渲染和写入 Store，从验证码池取出时不含渲染
+	ImageBytes  int           `json:"imageBytes,omitempty"`  // 生成事件中图片解码后的总字节数
+}
+
+// Observer 事件观察者，用于把验证码结果接入风控、审计等系统
+//
+// 事件在后台 goroutine 中按发生顺序依次回调，回调不能长时间阻塞，否则事件缓冲区写满后新事件会被丢弃；
+// 只关心部分事件时可以嵌入 NopObserver。
+type Observer interface {
+	OnGenerate(event Event)    // 生成验证码后
+	OnVerify(event Event)      // 验证验证码后，包括验证失败，不包括被限流和已过期
+	OnExpire(event Event)      // 验证时发现验证码已过期，未提交验证就过期的验证码不会触发
+	OnRateLimited(event Event) // 生成或验证请求被限流
+}
+
+// NopObserver 不处理任何事件的观察者，嵌入后只需实现关心的方法
+type NopObserver struct{}
+
+func (NopObserver) OnGenerate(Event)    {}
+func (NopObserver) OnVerify(Event)      {}
+func (NopObserver) OnExpire(Event)      {}
+func (NopObserver) OnRateLimited(Event) {}
+
+// ObserverConfig 事件分发配置
+type ObserverConfig struct {
+	BufferSize int `json:",default=1024"` // 事件缓冲区大小，写满后丢弃新事件，默认 1024
+}
+
+// withDefaults 填充零值字段的默认值
+func (c ObserverConfig) withDefaults() ObserverConfig {
+	if c.BufferSize == 0 {
+		c.BufferSize = 1024
+	}
+	return c
+}
+
+// Validate 校验事件分发配置
+func (c ObserverConfig) Validate() error {
+	if c.BufferSize < 0 {
+		return fmt.Errorf("observer: buffer size must not be negative")
+	}
+	return nil
+}
+
+// eventDispatcher 异步事件分发
+//
+// 生成和验证只把事件写入带缓冲的 channel，不等待观察者处理；缓冲区满时丢弃事件并计数。
+type eventDispatcher struct {
+	observers []Observer
+	events    chan Event
+	dropped   atomic.Uint64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// newEventDispatcher 创建事件分发并启动后台 goroutine
+func newEventDispatcher(observers []Observer, config ObserverConfig) *eventDispatcher {
+	config = config.withDefaults()
+
+	d := &eventDispatcher{
+		observers: observers,
+		events:    make(chan Event, config.BufferSize),
+		stop:      make(chan struct{}),
+	}
+	d.wg.Add(1)
+	go d.run()
+
+	return d
+}
+
+// emit 写入事件，缓冲区满时丢弃，关闭后写入的事件直接忽略，不计入丢弃数
+func (d *eventDispatcher) emit(event Event) {
+	select {
+	case <-d.stop:
+		return
+	default:
+	}
+
+	select {
+	case d.events <- event:
+	default:
+		if d.dropped.Add(1)%1000 == 1 {
+			logx.Errorf("事件缓冲区已满，丢弃事件: %s, 累计丢弃 %d", event.Kind, d.dropped.Load())
+		}
+	}
+}
+
+// run 后台 goroutine：依次回调观察者，关闭时处理完缓冲区中剩余的事件
+func (d *eventDispatcher) run() {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case event := <-d.events:
+			d.dispatch(event)
+		case <-d.stop:
+			for {
+				select {
+				case event := <-d.events:
+					d.dispatch(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// dispatch 回调所有观察者，观察者 panic 不影响其他观察者
+func (d *eventDispatcher) dispatch(event Event) {
+	for _, observer := range d.observers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logx.Errorf("事件观察者 panic: %s, %v", event.Kind, r)
+				}
+			}()
+
+			switch event.Kind {
+			case EventGenerate:
+				observer.OnGenerate(event)
+			case EventVerify:
+				observer.OnVerify(event)
+			case EventExpire:
+				observer.OnExpire(event)
+			case EventRateLimited:
+				observer.OnRateLimited(event)
+			}
+		}()
+	}
+}
+
+// close 停止后台 goroutine
+func (d *eventDispatcher) close() {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+	})
+	d.wg.Wait()
+}
+
+// DroppedEvents 返回因缓冲区已满被丢弃的事件数
+func (s *Service) DroppedEvents() uint64 {
+	if s.events == nil {
+		return 0
+	}
+	return s.events.dropped.Load()
+}
+
+// emit 分发事件，未设置观察者时不做任何事
+func (s *Service) emit(event Event) {
+	if s.events == nil {
+		return
+	}
+	event.Time = time.Now()
+	s.events.emit(event)
+}
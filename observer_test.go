@@ -0,0 +1,200 @@
+package captcha
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingObserver 记录收到的事件，block 不为空时回调阻塞到 block 关闭
+type recordingObserver struct {
+	NopObserver
+	mu     sync.Mutex
+	events []Event
+	block  chan struct{}
+	panics bool
+}
+
+func (o *recordingObserver) OnGenerate(event Event) {
+	if o.block != nil {
+		<-o.block
+	}
+	o.mu.Lock()
+	o.events = append(o.events, event)
+	o.mu.Unlock()
+	if o.panics {
+		panic("observer panic")
+	}
+}
+
+func (o *recordingObserver) count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.events)
+}
+
+// TestEventDispatcherDropsWhenFull 观察者阻塞、缓冲区写满后丢弃新事件，不阻塞调用方
+func TestEventDispatcherDropsWhenFull(t *testing.T) {
+	observer := &recordingObserver{block: make(chan struct{})}
+	d := newEventDispatcher([]Observer{observer}, ObserverConfig{BufferSize: 4})
+
+	// 第一个事件被后台 goroutine 取出后阻塞在观察者中，之后 4 个写满缓冲区
+	d.emit(Event{Kind: EventGenerate})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.events) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("第一个事件未被取出")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			d.emit(Event{Kind: EventGenerate})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("emit 被阻塞")
+	}
+	if dropped := d.dropped.Load(); dropped != 6 {
+		t.Errorf("dropped = %d, want 6", dropped)
+	}
+
+	close(observer.block)
+	d.close()
+	if got := observer.count(); got != 5 {
+		t.Errorf("observer received %d events, want 5", got)
+	}
+}
+
+// TestEventDispatcherRecoversPanic 观察者 panic 不影响其他观察者和后续事件
+func TestEventDispatcherRecoversPanic(t *testing.T) {
+	panicking := &recordingObserver{panics: true}
+	normal := &recordingObserver{}
+	d := newEventDispatcher([]Observer{panicking, normal}, ObserverConfig{})
+
+	for i := 0; i < 3; i++ {
+		d.emit(Event{Kind: EventGenerate})
+	}
+	d.close()
+
+	if got := panicking.count(); got != 3 {
+		t.Errorf("panicking observer received %d events, want 3", got)
+	}
+	if got := normal.count(); got != 3 {
+		t.Errorf("normal observer received %d events, want 3", got)
+	}
+}
+
+// TestEventDispatcherDrainsOnClose 关闭时处理完缓冲区中剩余的事件
+func TestEventDispatcherDrainsOnClose(t *testing.T) {
+	observer := &recordingObserver{block: make(chan struct{})}
+	d := newEventDispatcher([]Observer{observer}, ObserverConfig{BufferSize: 100})
+
+	for i := 0; i < 50; i++ {
+		d.emit(Event{Kind: EventGenerate})
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		d.close()
+		close(closed)
+	}()
+	// 观察者阻塞时 close 等待事件处理完
+	select {
+	case <-closed:
+		t.Fatal("close 在事件处理完之前返回")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(observer.block)
+	<-closed
+	if got := observer.count(); got != 50 {
+		t.Errorf("observer received %d events, want 50", got)
+	}
+	if dropped := d.dropped.Load(); dropped != 0 {
+		t.Errorf("dropped = %d, want 0", dropped)
+	}
+}
+
+// TestEventDispatcherEmitAfterClose 关闭后写入事件不 panic、不阻塞，事件不再分发
+func TestEventDispatcherEmitAfterClose(t *testing.T) {
+	observer := &recordingObserver{}
+	d := newEventDispatcher([]Observer{observer}, ObserverConfig{BufferSize: 2})
+	d.close()
+	d.close()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			d.emit(Event{Kind: EventGenerate})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("关闭后 emit 被阻塞")
+	}
+
+	if got := observer.count(); got != 0 {
+		t.Errorf("observer received %d events after close, want 0", got)
+	}
+	if dropped := d.dropped.Load(); dropped != 0 {
+		t.Errorf("dropped = %d, want 0", dropped)
+	}
+}
+
+// kindObserver 按顺序记录事件类型
+type kindObserver struct {
+	mu    sync.Mutex
+	kinds []EventKind
+}
+
+func (o *kindObserver) record(event Event) {
+	o.mu.Lock()
+	o.kinds = append(o.kinds, event.Kind)
+	o.mu.Unlock()
+}
+
+func (o *kindObserver) OnGenerate(event Event)    { o.record(event) }
+func (o *kindObserver) OnVerify(event Event)      { o.record(event) }
+func (o *kindObserver) OnExpire(event Event)      { o.record(event) }
+func (o *kindObserver) OnRateLimited(event Event) { o.record(event) }
+
+// TestServiceEmitsEvents 生成、验证和限流事件按顺序分发，Close 后全部处理完
+func TestServiceEmitsEvents(t *testing.T) {
+	observer := &kindObserver{}
+	store := NewMemStore()
+	service, err := NewService(store,
+		WithObserver(observer),
+		WithRateLimit(RateLimitConfig{Generate: RateLimit{Limit: 1}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := generate(t, service, "", RequestMeta{IP: "1.2.3.4"})
+	if _, err := service.VerifyDetailed(withMeta(RequestMeta{IP: "1.2.3.4"}), characterRequest(t, store, id, "")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Generate(withMeta(RequestMeta{IP: "1.2.3.4"}), CaptchaTypeCharacter); err == nil {
+		t.Fatal("expected rate limit")
+	}
+	service.Close()
+
+	want := []EventKind{EventGenerate, EventVerify, EventRateLimited}
+	if len(observer.kinds) != len(want) {
+		t.Fatalf("events = %v, want %v", observer.kinds, want)
+	}
+	for i := range want {
+		if observer.kinds[i] != want[i] {
+			t.Errorf("events = %v, want %v", observer.kinds, want)
+			break
+		}
+	}
+}
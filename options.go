@@ -0,0 +1,190 @@
+package captcha
+
+import (
+	"math/rand"
+)
+
+// Option 验证码服务选项
+type Option func(*serviceOptions)
+
+// serviceOptions NewService 的可选配置，未设置的配置使用各生成器的默认值
+type serviceOptions struct {
+	defaultType CaptchaType
+
+	character   CharacterConfig
+	imageSelect ImageSelectConfig
+	slide       SlideConfig
+	imageGrid   ImageGridConfig
+	pow         PowConfig
+
+	assets       *AssetLibrary
+	assetsConfig AssetLibraryConfig
+	assetURLs    *AssetURLConfig
+	pools        map[CaptchaType]PoolConfig
+	scenes       map[string]SceneConfig
+	risk         *RiskConfig
+	evaluator    RiskEvaluator
+	rateLimit    *RateLimitConfig
+	limiter      RateLimiter
+	binding      ClientBindingConfig
+	humanCheck   HumanCheckConfig
+	invisible    *InvisibleConfig
+	scorer       TelemetryScorer
+	observers    []Observer
+	observer     ObserverConfig
+	rand         rand.Source
+}
+
+// WithDefaultType 设置默认验证码类型，Generate 传入空类型时使用，默认 character
+func WithDefaultType(captchaType CaptchaType) Option {
+	return func(o *serviceOptions) {
+		o.defaultType = captchaType
+	}
+}
+
+// WithCharacterConfig 设置字符验证码配置
+func WithCharacterConfig(config CharacterConfig) Option {
+	return func(o *serviceOptions) {
+		o.character = config
+	}
+}
+
+// WithImageSelectConfig 设置图片选择验证码配置
+func WithImageSelectConfig(config ImageSelectConfig) Option {
+	return func(o *serviceOptions) {
+		o.imageSelect = config
+	}
+}
+
+// WithSlideConfig 设置滑动验证码配置
+func WithSlideConfig(config SlideConfig) Option {
+	return func(o *serviceOptions) {
+		o.slide = config
+	}
+}
+
+// WithImageGridConfig 设置宫格图片选择验证码配置
+func WithImageGridConfig(config ImageGridConfig) Option {
+	return func(o *serviceOptions) {
+		o.imageGrid = config
+	}
+}
+
+// WithPowConfig 设置工作量证明验证码配置
+func WithPowConfig(config PowConfig) Option {
+	return func(o *serviceOptions) {
+		o.pow = config
+	}
+}
+
+// WithAssetLibrary 使用外部创建的图片资源库，需要扫描各类型和场景配置中的图片目录，服务 Close 时不会关闭它
+func WithAssetLibrary(assets *AssetLibrary) Option {
+	return func(o *serviceOptions) {
+		o.assets = assets
+	}
+}
+
+// WithAssetLibraryConfig 设置服务自建图片资源库的配置，默认每 30 秒轮询一次目录变化
+func WithAssetLibraryConfig(config AssetLibraryConfig) Option {
+	return func(o *serviceOptions) {
+		o.assetsConfig = config
+	}
+}
+
+// WithAssetURLs 开启图片地址下发，见 AssetURLConfig
+func WithAssetURLs(config AssetURLConfig) Option {
+	return func(o *serviceOptions) {
+		o.assetURLs = &config
+	}
+}
+
+// WithPool 为指定类型开启预生成验证码池，可以多次调用为不同类型开启
+func WithPool(captchaType CaptchaType, config PoolConfig) Option {
+	return func(o *serviceOptions) {
+		o.pools[captchaType] = config
+	}
+}
+
+// WithScene 添加场景，使用 Service.GenerateForScene 生成，可以多次调用添加多个场景
+func WithScene(name string, config SceneConfig) Option {
+	return func(o *serviceOptions) {
+		o.scenes[name] = config
+	}
+}
+
+// WithRiskConfig 开启自适应难度，使用 Service.GenerateAdaptive 按风险分选择场景生成
+func WithRiskConfig(config RiskConfig) Option {
+	return func(o *serviceOptions) {
+		o.risk = &config
+	}
+}
+
+// WithRiskEvaluator 设置自适应难度的风险评估，默认使用 FailureRiskEvaluator
+func WithRiskEvaluator(evaluator RiskEvaluator) Option {
+	return func(o *serviceOptions) {
+		o.evaluator = evaluator
+	}
+}
+
+// WithRateLimit 开启生成和验证的限流，按 RequestMeta 中的 IP、设备和账号分别计数
+func WithRateLimit(config RateLimitConfig) Option {
+	return func(o *serviceOptions) {
+		o.rateLimit = &config
+	}
+}
+
+// WithRateLimiter 设置限流器，默认使用 MemRateLimiter，多实例部署时使用 RedisRateLimiter
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(o *serviceOptions) {
+		o.limiter = limiter
+	}
+}
+
+// WithClientBinding 开启客户端绑定，验证时请求方与生成时不一致则验证失败
+func WithClientBinding(config ClientBindingConfig) Option {
+	return func(o *serviceOptions) {
+		o.binding = config
+	}
+}
+
+// WithHumanCheck 设置验证时的最短作答时间和疑似机器提交的处理方式
+func WithHumanCheck(config HumanCheckConfig) Option {
+	return func(o *serviceOptions) {
+		o.humanCheck = config
+	}
+}
+
+// WithInvisible 开启无感验证，使用 Service.Challenge 按前端交互数据决定是否需要可见验证码
+func WithInvisible(config InvisibleConfig) Option {
+	return func(o *serviceOptions) {
+		o.invisible = &config
+	}
+}
+
+// WithTelemetryScorer 设置无感验证的交互数据评分，默认使用 HeuristicScorer
+func WithTelemetryScorer(scorer TelemetryScorer) Option {
+	return func(o *serviceOptions) {
+		o.scorer = scorer
+	}
+}
+
+// WithObserver 添加事件观察者，可以多次调用添加多个
+func WithObserver(observer Observer) Option {
+	return func(o *serviceOptions) {
+		o.observers = append(o.observers, observer)
+	}
+}
+
+// WithObserverConfig 设置事件分发配置
+func WithObserverConfig(config ObserverConfig) Option {
+	return func(o *serviceOptions) {
+		o.observer = config
+	}
+}
+
+// WithRandSource 设置所有生成器的随机数源，单个配置中设置了 Rand 时以配置为准
+func WithRandSource(source rand.Source) Option {
+	return func(o *serviceOptions) {
+		o.rand = source
+	}
+}
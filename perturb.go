@@ -0,0 +1,276 @@
+package captcha
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"math/rand"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// PerturbConfig 图片扰动配置
+//
+// 每次下发图片时随机变换，保证同一张原图的两次下发在字节和感知哈希上都不相同，
+// 防止机器人用爬取的数据集按哈希匹配答案。所有字段为零值时不做对应变换。
+type PerturbConfig struct {
+	CropRatio   float64 `json:",optional"` // 随机裁剪比例上限（0-0.5），如 0.1 表示每条边最多裁掉 10%
+	ScaleMin    float64 `json:",optional"` // 随机缩放下限，如 0.9
+	ScaleMax    float64 `json:",optional"` // 随机缩放上限，如 1.1
+	HueShift    float64 `json:",optional"` // 色相偏移上限（度），如 15
+	Noise       int     `json:",optional"` // 噪声强度（每个通道的最大偏移，0-255）
+	JPEGQuality int     `json:",optional"` // JPEG 重压缩质量（1-100），0 表示不重压缩
+	MaxRotate   float64 `json:",optional"` // 旋转角度上限（度），如 5
+	Flip        bool    `json:",optional"` // 是否随机水平翻转
+}
+
+// ImageTransform 单个图片变换步骤
+type ImageTransform func(img image.Image) (image.Image, error)
+
+// Pipeline 按配置构建变换流水线，顺序为：翻转、旋转、裁剪、缩放、色相、噪声、JPEG 重压缩，
+// 所有随机参数取自 rng
+func (c PerturbConfig) Pipeline(rng *rand.Rand) []ImageTransform {
+	var pipeline []ImageTransform
+	if c.Flip {
+		pipeline = append(pipeline, randomFlip(rng))
+	}
+	if c.MaxRotate > 0 {
+		pipeline = append(pipeline, randomRotate(rng, c.MaxRotate))
+	}
+	if c.CropRatio > 0 {
+		pipeline = append(pipeline, randomCrop(rng, math.Min(c.CropRatio, 0.5)))
+	}
+	if c.ScaleMax > 0 {
+		pipeline = append(pipeline, randomScale(rng, c.ScaleMin, c.ScaleMax))
+	}
+	if c.HueShift > 0 {
+		pipeline = append(pipeline, randomHueShift(rng, c.HueShift))
+	}
+	if c.Noise > 0 {
+		pipeline = append(pipeline, randomNoise(rng, c.Noise))
+	}
+	if c.JPEGQuality > 0 {
+		pipeline = append(pipeline, jpegRecompress(c.JPEGQuality))
+	}
+	return pipeline
+}
+
+// applyPipeline 依次执行变换
+func applyPipeline(img image.Image, pipeline []ImageTransform) (image.Image, error) {
+	for _, transform := range pipeline {
+		var err error
+		img, err = transform(img)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// toRGBA 转换为从 (0,0) 开始的 RGBA 图片
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	xdraw.Draw(dst, dst.Bounds(), img, bounds.Min, xdraw.Src)
+	return dst
+}
+
+// randomFlip 以 50% 概率水平翻转
+func randomFlip(rng *rand.Rand) ImageTransform {
+	return func(img image.Image) (image.Image, error) {
+		if rng.Intn(2) == 0 {
+			return img, nil
+		}
+
+		src := toRGBA(img)
+		width, height := src.Bounds().Dx(), src.Bounds().Dy()
+		dst := image.NewRGBA(src.Bounds())
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				dst.SetRGBA(width-1-x, y, src.RGBAAt(x, y))
+			}
+		}
+		return dst, nil
+	}
+}
+
+// randomRotate 在 [-maxDegrees, maxDegrees] 内随机旋转，超出原图的区域取最近的边缘像素
+func randomRotate(rng *rand.Rand, maxDegrees float64) ImageTransform {
+	return func(img image.Image) (image.Image, error) {
+		angle := (rng.Float64()*2 - 1) * maxDegrees * math.Pi / 180
+		sin, cos := math.Sincos(angle)
+
+		src := toRGBA(img)
+		width, height := src.Bounds().Dx(), src.Bounds().Dy()
+		cx, cy := float64(width)/2, float64(height)/2
+
+		dst := image.NewRGBA(src.Bounds())
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				// 反向映射到原图坐标
+				dx, dy := float64(x)-cx, float64(y)-cy
+				sx := int(math.Round(cos*dx + sin*dy + cx))
+				sy := int(math.Round(-sin*dx + cos*dy + cy))
+				dst.SetRGBA(x, y, src.RGBAAt(clampInt(sx, 0, width-1), clampInt(sy, 0, height-1)))
+			}
+		}
+		return dst, nil
+	}
+}
+
+// randomCrop 每条边随机裁掉不超过 ratio 的部分
+func randomCrop(rng *rand.Rand, ratio float64) ImageTransform {
+	return func(img image.Image) (image.Image, error) {
+		bounds := img.Bounds()
+		maxX := int(float64(bounds.Dx()) * ratio)
+		maxY := int(float64(bounds.Dy()) * ratio)
+
+		rect := image.Rect(
+			bounds.Min.X+randomUpTo(rng, maxX),
+			bounds.Min.Y+randomUpTo(rng, maxY),
+			bounds.Max.X-randomUpTo(rng, maxX),
+			bounds.Max.Y-randomUpTo(rng, maxY),
+		)
+
+		dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+		xdraw.Draw(dst, dst.Bounds(), img, rect.Min, xdraw.Src)
+		return dst, nil
+	}
+}
+
+// randomScale 在 [minScale, maxScale] 内随机缩放
+func randomScale(rng *rand.Rand, minScale, maxScale float64) ImageTransform {
+	return func(img image.Image) (image.Image, error) {
+		if minScale <= 0 || minScale > maxScale {
+			minScale = maxScale
+		}
+		scale := minScale + rng.Float64()*(maxScale-minScale)
+
+		bounds := img.Bounds()
+		width := int(math.Max(1, math.Round(float64(bounds.Dx())*scale)))
+		height := int(math.Max(1, math.Round(float64(bounds.Dy())*scale)))
+
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, bounds, xdraw.Src, nil)
+		return dst, nil
+	}
+}
+
+// randomHueShift 在 [-maxDegrees, maxDegrees] 内随机偏移色相
+func randomHueShift(rng *rand.Rand, maxDegrees float64) ImageTransform {
+	return func(img image.Image) (image.Image, error) {
+		shift := (rng.Float64()*2 - 1) * maxDegrees
+
+		dst := toRGBA(img)
+		bounds := dst.Bounds()
+		for y := 0; y < bounds.Dy(); y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				pixel := dst.RGBAAt(x, y)
+				h, s, v := rgbToHSV(pixel)
+				r, g, b := hsvToRGB(math.Mod(h+shift+360, 360), s, v)
+				dst.SetRGBA(x, y, color.RGBA{R: r, G: g, B: b, A: pixel.A})
+			}
+		}
+		return dst, nil
+	}
+}
+
+// randomNoise 给每个像素的每个通道加上 [-strength, strength] 的随机偏移
+func randomNoise(rng *rand.Rand, strength int) ImageTransform {
+	return func(img image.Image) (image.Image, error) {
+		dst := toRGBA(img)
+		for i := 0; i < len(dst.Pix); i += 4 {
+			for ch := 0; ch < 3; ch++ {
+				value := int(dst.Pix[i+ch]) + rng.Intn(2*strength+1) - strength
+				dst.Pix[i+ch] = uint8(clampInt(value, 0, 255))
+			}
+		}
+		return dst, nil
+	}
+}
+
+// jpegRecompress 以指定质量做一次 JPEG 有损压缩
+func jpegRecompress(quality int) ImageTransform {
+	return func(img image.Image) (image.Image, error) {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: clampInt(quality, 1, 100)}); err != nil {
+			return nil, err
+		}
+		return jpeg.Decode(&buf)
+	}
+}
+
+// rgbToHSV RGB 转 HSV，h 取值 [0,360)，s、v 取值 [0,1]
+func rgbToHSV(c color.RGBA) (float64, float64, float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	delta := maxC - minC
+
+	var h float64
+	switch {
+	case delta == 0:
+		h = 0
+	case maxC == r:
+		h = 60 * math.Mod((g-b)/delta, 6)
+	case maxC == g:
+		h = 60 * ((b-r)/delta + 2)
+	default:
+		h = 60 * ((r-g)/delta + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+
+	s := 0.0
+	if maxC > 0 {
+		s = delta / maxC
+	}
+	return h, s, maxC
+}
+
+// hsvToRGB HSV 转 RGB
+func hsvToRGB(h, s, v float64) (uint8, uint8, uint8) {
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	return uint8(math.Round((r + m) * 255)), uint8(math.Round((g + m) * 255)), uint8(math.Round((b + m) * 255))
+}
+
+// randomUpTo 返回 [0, n] 内的随机整数
+func randomUpTo(rng *rand.Rand, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return rng.Intn(n + 1)
+}
+
+// clampInt 将 v 限制在 [lo, hi] 内
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
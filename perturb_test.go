@@ -0,0 +1,117 @@
+package captcha
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// gradientImage 生成每个像素颜色都不同的渐变图片
+func gradientImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x * 255 / width), G: uint8(y * 255 / height), B: uint8((x + y) % 256), A: 255})
+		}
+	}
+	return img
+}
+
+// sameImage 两张图片尺寸和像素是否完全一致
+func sameImage(a, b image.Image) bool {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return false
+	}
+	ra, rb := toRGBA(a), toRGBA(b)
+	return bytes.Equal(ra.Pix, rb.Pix)
+}
+
+// TestPerturbPipeline 每个字段单独开启时都会改变图片，且不修改原图
+func TestPerturbPipeline(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PerturbConfig
+	}{
+		{"裁剪", PerturbConfig{CropRatio: 0.2}},
+		{"缩放", PerturbConfig{ScaleMin: 0.5, ScaleMax: 0.8}},
+		{"色相", PerturbConfig{HueShift: 90}},
+		{"噪声", PerturbConfig{Noise: 20}},
+		{"JPEG 重压缩", PerturbConfig{JPEGQuality: 10}},
+		{"旋转", PerturbConfig{MaxRotate: 30}},
+		{"翻转", PerturbConfig{Flip: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := gradientImage(64, 48)
+			original := append([]uint8(nil), src.Pix...)
+			rng := rand.New(rand.NewSource(1))
+
+			pipeline := tt.config.Pipeline(rng)
+			if len(pipeline) != 1 {
+				t.Fatalf("len(pipeline) = %d, want 1", len(pipeline))
+			}
+
+			// 翻转以 50% 概率生效，多执行几次
+			changed := false
+			for i := 0; i < 10 && !changed; i++ {
+				dst, err := applyPipeline(src, pipeline)
+				if err != nil {
+					t.Fatal(err)
+				}
+				changed = !sameImage(src, dst)
+			}
+			if !changed {
+				t.Error("图片未被改变")
+			}
+			if !bytes.Equal(src.Pix, original) {
+				t.Error("原图被修改")
+			}
+		})
+	}
+
+	if pipeline := (PerturbConfig{}).Pipeline(rand.New(rand.NewSource(1))); len(pipeline) != 0 {
+		t.Errorf("零值配置 len(pipeline) = %d, want 0", len(pipeline))
+	}
+}
+
+// TestImageSelectPerturbServesDifferentBytes 同一张原图两次下发的字节不同，不开启扰动时相同
+func TestImageSelectPerturbServesDifferentBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, gradientImage(64, 48)); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	serveTwice := func(config PerturbConfig) ([]byte, []byte) {
+		t.Helper()
+		c := NewImageSelectCaptcha(ImageSelectConfig{Perturb: config, Rand: rand.NewSource(1)})
+		first, err := c.loadAndEncodeImage(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := c.loadAndEncodeImage(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return first.Data, second.Data
+	}
+
+	if first, second := serveTwice(PerturbConfig{}); !bytes.Equal(first, second) {
+		t.Error("不开启扰动时两次下发的字节不同")
+	}
+
+	config := PerturbConfig{CropRatio: 0.1, ScaleMin: 0.9, ScaleMax: 1.1, HueShift: 15, Noise: 8, JPEGQuality: 85, MaxRotate: 5, Flip: true}
+	if first, second := serveTwice(config); bytes.Equal(first, second) {
+		t.Error("开启扰动后两次下发的字节相同")
+	}
+}
@@ -0,0 +1,155 @@
+package captcha
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// PoolConfig 预生成验证码池配置
+type PoolConfig struct {
+	Size    int           `json:",default=100"` // 池容量，即保持多少个已渲染好的验证码，默认 100
+	Workers int           `json:",default=2"`   // 后台补充池的并发数，默认 2
+	MaxAge  time.Duration `json:",default=10m"` // 预生成验证码的最长保留时间，超过后丢弃重新生成，默认 10 分钟
+}
+
+// PoolStats 验证码池状态
+type PoolStats struct {
+	Type      CaptchaType `json:"type"`      // 验证码类型
+	Capacity  int         `json:"capacity"`  // 池容量
+	Available int         `json:"available"` // 当前可直接取用的数量
+	Hits      uint64      `json:"hits"`      // 从池中取到验证码的次数
+	Misses    uint64      `json:"misses"`    // 池为空时同步生成的次数
+	Generated uint64      `json:"generated"` // 后台生成的总数
+	Expired   uint64      `json:"expired"`   // 因超过 MaxAge 被丢弃的数量
+	Failures  uint64      `json:"failures"`  // 后台生成失败的次数
+}
+
+// captchaPool 预生成验证码池
+//
+// 后台 worker 持续渲染验证码放入池中，池满时阻塞等待空位。取出的验证码
+// 从池中移除，每个预生成的验证码最多下发一次。
+type captchaPool struct {
+	captchaType CaptchaType
+	config      PoolConfig
+	render      func() (*renderedCaptcha, error)
+
+	items chan *renderedCaptcha // 已渲染的验证码
+	slots chan struct{}         // 空位令牌，保证池中加上正在渲染的数量不超过容量
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	generated atomic.Uint64
+	expired   atomic.Uint64
+	failures  atomic.Uint64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// newCaptchaPool 创建验证码池并启动后台 worker
+func newCaptchaPool(captchaType CaptchaType, config PoolConfig, render func() (*renderedCaptcha, error)) *captchaPool {
+	if config.Size <= 0 {
+		config.Size = 100
+	}
+	if config.Workers <= 0 {
+		config.Workers = 2
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 10 * time.Minute
+	}
+
+	p := &captchaPool{
+		captchaType: captchaType,
+		config:      config,
+		render:      render,
+		items:       make(chan *renderedCaptcha, config.Size),
+		slots:       make(chan struct{}, config.Size),
+		stop:        make(chan struct{}),
+	}
+	for i := 0; i < config.Size; i++ {
+		p.slots <- struct{}{}
+	}
+
+	p.wg.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go p.fill()
+	}
+
+	return p
+}
+
+// take 从池中取出一个验证码，池为空时同步渲染
+func (p *captchaPool) take() (*renderedCaptcha, error) {
+	for {
+		select {
+		case item := <-p.items:
+			p.slots <- struct{}{}
+			if time.Since(item.renderedAt) > p.config.MaxAge {
+				p.expired.Add(1)
+				continue
+			}
+			p.hits.Add(1)
+			return item, nil
+		default:
+			p.misses.Add(1)
+			return p.render()
+		}
+	}
+}
+
+// fill 后台 worker：拿到空位后渲染一个验证码放入池中
+func (p *captchaPool) fill() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.slots:
+		}
+
+		item, err := p.render()
+		if err != nil {
+			p.failures.Add(1)
+			logx.Errorf("预生成验证码失败: %s, error: %v", p.captchaType, err)
+			p.slots <- struct{}{}
+
+			// 避免持续失败时空转
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		p.generated.Add(1)
+		p.items <- item
+	}
+}
+
+// stats 返回池状态
+func (p *captchaPool) stats() PoolStats {
+	return PoolStats{
+		Type:      p.captchaType,
+		Capacity:  p.config.Size,
+		Available: len(p.items),
+		Hits:      p.hits.Load(),
+		Misses:    p.misses.Load(),
+		Generated: p.generated.Load(),
+		Expired:   p.expired.Load(),
+		Failures:  p.failures.Load(),
+	}
+}
+
+// close 停止后台 worker
+func (p *captchaPool) close() {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+}
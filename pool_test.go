@@ -0,0 +1,98 @@
+package captcha
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitPoolFull 等待池被后台 worker 填满
+func waitPoolFull(t *testing.T, p *captchaPool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.items) < p.config.Size {
+		if time.Now().After(deadline) {
+			t.Fatalf("池未在 5 秒内填满: %d/%d", len(p.items), p.config.Size)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestCaptchaPoolNeverServesTwice 并发取用时每个预生成的验证码只下发一次
+func TestCaptchaPoolNeverServesTwice(t *testing.T) {
+	var seq atomic.Int64
+	p := newCaptchaPool(CaptchaTypeCharacter, PoolConfig{Size: 20, Workers: 4, MaxAge: time.Minute}, func() (*renderedCaptcha, error) {
+		return &renderedCaptcha{secret: seq.Add(1), renderedAt: time.Now()}, nil
+	})
+	defer p.close()
+	waitPoolFull(t, p)
+
+	const goroutines, perGoroutine = 16, 50
+	var (
+		mu     sync.Mutex
+		served = make(map[int64]int)
+		wg     sync.WaitGroup
+	)
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				item, err := p.take()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				served[item.secret.(int64)]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(served) != goroutines*perGoroutine {
+		t.Errorf("下发了 %d 个不同的验证码, want %d", len(served), goroutines*perGoroutine)
+	}
+	for id, n := range served {
+		if n > 1 {
+			t.Errorf("验证码 %d 被下发了 %d 次", id, n)
+		}
+	}
+	stats := p.stats()
+	if stats.Hits+stats.Misses != goroutines*perGoroutine {
+		t.Errorf("hits %d + misses %d != %d", stats.Hits, stats.Misses, goroutines*perGoroutine)
+	}
+}
+
+// TestCaptchaPoolDiscardsExpired 超过 MaxAge 的验证码被丢弃，不会下发
+func TestCaptchaPoolDiscardsExpired(t *testing.T) {
+	const maxAge = time.Minute
+	var stale atomic.Bool
+	stale.Store(true)
+	p := newCaptchaPool(CaptchaTypeCharacter, PoolConfig{Size: 5, Workers: 1, MaxAge: maxAge}, func() (*renderedCaptcha, error) {
+		renderedAt := time.Now()
+		if stale.Load() {
+			renderedAt = renderedAt.Add(-2 * maxAge)
+		}
+		return &renderedCaptcha{renderedAt: renderedAt}, nil
+	})
+	defer p.close()
+	waitPoolFull(t, p)
+
+	// 池中只有过期的验证码，之后生成的都是新的
+	stale.Store(false)
+	for i := 0; i < 10; i++ {
+		item, err := p.take()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if age := time.Since(item.renderedAt); age > maxAge {
+			t.Fatalf("下发了过期的验证码: 已生成 %s", age)
+		}
+	}
+	if expired := p.stats().Expired; expired < 5 {
+		t.Errorf("Expired = %d, want >= 5", expired)
+	}
+}
@@ -0,0 +1,81 @@
+package captcha
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/bits"
+	"math/rand"
+	"time"
+)
+
+// PowCaptcha 工作量证明验证码（hashcash）
+//
+// 前端需要找到一个 nonce，使 SHA-256(prefix + nonce) 的前 Difficulty 位为 0，
+// 平均需要计算 2^Difficulty 次哈希，服务端验证只需计算一次。
+type PowCaptcha struct {
+	config PowConfig
+	rng    *rand.Rand
+}
+
+// NewPowCaptcha 创建工作量证明验证码
+func NewPowCaptcha(config PowConfig) *PowCaptcha {
+	config = config.withDefaults()
+
+	return &PowCaptcha{
+		config: config,
+		rng:    newRand(config.Rand),
+	}
+}
+
+// withDefaults 填充零值字段的默认值
+func (c PowConfig) withDefaults() PowConfig {
+	if c.Difficulty == 0 {
+		c.Difficulty = 16
+	}
+	if c.ExpireTime == 0 {
+		c.ExpireTime = 5 * time.Minute
+	}
+	return c
+}
+
+// Generate 生成随机前缀，返回前缀和难度
+func (c *PowCaptcha) Generate() (string, int) {
+	prefix := make([]byte, 16)
+	for i := range prefix {
+		prefix[i] = byte(c.rng.Intn(256))
+	}
+	return hex.EncodeToString(prefix), c.config.Difficulty
+}
+
+// Verify 验证 SHA-256(prefix + nonce) 的前 difficulty 位是否为 0，nonce 必须是 1 到 64 位十六进制字符
+func (c *PowCaptcha) Verify(prefix string, difficulty int, nonce string) bool {
+	if nonce == "" || len(nonce) > 64 || !isHex(nonce) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(prefix + nonce))
+	return leadingZeroBits(sum[:]) >= difficulty
+}
+
+// isHex 是否只包含十六进制字符
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// leadingZeroBits 计算前导 0 的位数
+func leadingZeroBits(hash []byte) int {
+	n := 0
+	for _, b := range hash {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,121 @@
+package captcha
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// findNonce 从 0 开始递增查找前导 0 位数恰好为 bits 的 nonce，format 决定 nonce 的写法
+func findNonce(t *testing.T, prefix, format string, exact bool, bits int) string {
+	t.Helper()
+	for i := 0; i < 1<<24; i++ {
+		nonce := fmt.Sprintf(format, i)
+		sum := sha256.Sum256([]byte(prefix + nonce))
+		n := leadingZeroBits(sum[:])
+		if n == bits || (!exact && n > bits) {
+			return nonce
+		}
+	}
+	t.Fatalf("未找到前导 0 位数为 %d 的 nonce", bits)
+	return ""
+}
+
+// TestPowVerifyDifficultyBoundary 前导 0 位数恰好等于难度时通过，难度加一时失败
+func TestPowVerifyDifficultyBoundary(t *testing.T) {
+	pow := NewPowCaptcha(PowConfig{})
+	const prefix = "00112233445566778899aabbccddeeff"
+
+	for _, bits := range []int{8, 9, 16, 17} {
+		t.Run(strconv.Itoa(bits), func(t *testing.T) {
+			nonce := findNonce(t, prefix, "%d", true, bits)
+			if !pow.Verify(prefix, bits, nonce) {
+				t.Errorf("Verify(difficulty=%d) = false, want true", bits)
+			}
+			if !pow.Verify(prefix, bits-1, nonce) {
+				t.Errorf("Verify(difficulty=%d) = false, want true", bits-1)
+			}
+			if pow.Verify(prefix, bits+1, nonce) {
+				t.Errorf("Verify(difficulty=%d) = true, want false", bits+1)
+			}
+		})
+	}
+}
+
+// TestPowVerifyWrongPrefix 为一个前缀找到的 nonce 不能用于另一个前缀
+func TestPowVerifyWrongPrefix(t *testing.T) {
+	pow := NewPowCaptcha(PowConfig{})
+	const prefix, other = "00112233445566778899aabbccddeeff", "ffeeddccbbaa99887766554433221100"
+
+	nonce := findNonce(t, prefix, "%d", false, 16)
+	if !pow.Verify(prefix, 16, nonce) {
+		t.Fatal("Verify() with own prefix = false")
+	}
+	if pow.Verify(other, 16, nonce) {
+		t.Error("Verify() with another prefix = true, want false")
+	}
+}
+
+// TestPowVerifyNonceFormat nonce 必须是 1 到 64 位十六进制字符
+func TestPowVerifyNonceFormat(t *testing.T) {
+	pow := NewPowCaptcha(PowConfig{})
+	const prefix = "00112233445566778899aabbccddeeff"
+
+	// 满足难度的 64 位和 65 位 nonce，只有长度不同
+	nonce64 := findNonce(t, prefix, "%064d", false, 8)
+	nonce65 := findNonce(t, prefix, "%065d", false, 8)
+	if !pow.Verify(prefix, 8, nonce64) {
+		t.Errorf("Verify(64 位 nonce) = false, want true")
+	}
+	if pow.Verify(prefix, 8, nonce65) {
+		t.Errorf("Verify(65 位 nonce) = true, want false")
+	}
+
+	tests := []struct {
+		name  string
+		nonce string
+	}{
+		{name: "空", nonce: ""},
+		{name: "非十六进制字母", nonce: "12g4"},
+		{name: "空格", nonce: " 1234"},
+		{name: "负号", nonce: "-1"},
+		{name: "非 ASCII", nonce: "１２３"},
+		{name: "65 位", nonce: strings.Repeat("0", 65)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 难度为 0 时任何哈希都满足，失败只能是因为格式
+			if pow.Verify(prefix, 0, tt.nonce) {
+				t.Errorf("Verify(%q) = true, want false", tt.nonce)
+			}
+		})
+	}
+	for _, nonce := range []string{"0", "deadBEEF", strings.Repeat("f", 64)} {
+		if !pow.Verify(prefix, 0, nonce) {
+			t.Errorf("Verify(%q, difficulty=0) = false, want true", nonce)
+		}
+	}
+}
+
+// TestLeadingZeroBits 前导 0 位数跨字节计算
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		hash []byte
+		want int
+	}{
+		{hash: []byte{0x80}, want: 0},
+		{hash: []byte{0x01}, want: 7},
+		{hash: []byte{0x00, 0xff}, want: 8},
+		{hash: []byte{0x00, 0x7f}, want: 9},
+		{hash: []byte{0x00, 0x00, 0x80}, want: 16},
+		{hash: []byte{0x00, 0x00, 0x40}, want: 17},
+		{hash: []byte{0x00, 0x00}, want: 16},
+	}
+	for _, tt := range tests {
+		if got := leadingZeroBits(tt.hash); got != tt.want {
+			t.Errorf("leadingZeroBits(%x) = %d, want %d", tt.hash, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package captcha
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+)
+
+// NewCryptoSource 创建基于 crypto/rand 的随机数源，输出不可预测，是所有生成器的默认随机数源
+func NewCryptoSource() rand.Source {
+	return cryptoSource{}
+}
+
+// NewSeededSource 创建固定种子的随机数源，相同种子生成相同的验证码，用于测试，不要在生产环境使用
+func NewSeededSource(seed int64) rand.Source {
+	return &lockedSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+// cryptoSource 基于 crypto/rand 的随机数源，并发安全
+type cryptoSource struct{}
+
+func (cryptoSource) Int63() int64 {
+	return int64(cryptoSource{}.Uint64() >> 1)
+}
+
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic("captcha: crypto/rand unavailable: " + err.Error())
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// Seed crypto/rand 不支持设置种子，调用无效果
+func (cryptoSource) Seed(int64) {}
+
+// lockedSource 加锁保护的随机数源，使共享的 rand.Rand 可以被多个 goroutine 同时使用
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// syncSource 把任意随机数源包装为并发安全的 Source64
+type syncSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *syncSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *syncSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if src64, ok := s.src.(rand.Source64); ok {
+		return src64.Uint64()
+	}
+	return uint64(s.src.Int63())>>31 | uint64(s.src.Int63())<<32
+}
+
+func (s *syncSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// newRand 根据配置的随机数源创建生成器使用的 rand.Rand，为空时使用 crypto/rand
+//
+// 返回的 rand.Rand 只能调用不依赖内部状态的方法（Intn、Float64、Shuffle 等），不要调用 Read。
+func newRand(source rand.Source) *rand.Rand {
+	switch source.(type) {
+	case nil:
+		return rand.New(cryptoSource{})
+	case cryptoSource, *lockedSource, *syncSource:
+		return rand.New(source)
+	default:
+		return rand.New(&syncSource{src: source})
+	}
+}
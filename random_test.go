@@ -0,0 +1,147 @@
+package captcha
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestNewSeededSource 相同种子输出相同的序列，不同种子输出不同
+func TestNewSeededSource(t *testing.T) {
+	sequence := func(seed int64) []int64 {
+		rng := rand.New(NewSeededSource(seed))
+		values := make([]int64, 16)
+		for i := range values {
+			values[i] = rng.Int63()
+		}
+		return values
+	}
+
+	if !reflect.DeepEqual(sequence(42), sequence(42)) {
+		t.Error("相同种子输出不同")
+	}
+	if reflect.DeepEqual(sequence(42), sequence(43)) {
+		t.Error("不同种子输出相同")
+	}
+}
+
+// TestSeededSourceConcurrent 固定种子的随机数源可以被多个 goroutine 同时使用
+func TestSeededSourceConcurrent(t *testing.T) {
+	rng := newRand(NewSeededSource(1))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				rng.Intn(100)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// writeSelectDataset 写入两个类别、每个类别三张图片的数据集目录
+func writeSelectDataset(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, category := range []string{"bus", "car"} {
+		if err := os.Mkdir(filepath.Join(dir, category), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			writePNG(t, filepath.Join(dir, category, string(rune('a'+i))+".png"), 20+i, 20)
+		}
+	}
+	return dir
+}
+
+// writeGridScene 写入一张带多边形标注的宫格场景图片
+func writeGridScene(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "street.png"), 300, 300)
+	annotation := `{"target": "bus", "polygons": [[[0, 0], [150, 0], [150, 150], [0, 150]]]}`
+	if err := os.WriteFile(filepath.Join(dir, "street.json"), []byte(annotation), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// TestSeededGeneratorsDeterministic 相同种子下各类型使用真实图片时的输出也完全一致
+func TestSeededGeneratorsDeterministic(t *testing.T) {
+	selectDir := writeSelectDataset(t)
+	gridDir := writeGridScene(t)
+
+	generators := map[string]func(seed int64) []interface{}{
+		"character": func(seed int64) []interface{} {
+			code, image, err := NewCharacterCaptcha(CharacterConfig{Rand: NewSeededSource(seed)}).generate()
+			return []interface{}{code, image, err}
+		},
+		"image_select": func(seed int64) []interface{} {
+			c := NewImageSelectCaptcha(ImageSelectConfig{
+				ImageDir:       selectDir,
+				ImageCount:     4,
+				MaxSelectCount: 2,
+				Perturb:        PerturbConfig{Noise: 10, Flip: true},
+				Rand:           NewSeededSource(seed),
+			})
+			question, targetType, images, targets, err := c.generate()
+			return []interface{}{question, targetType, images, targets, err}
+		},
+		"slide": func(seed int64) []interface{} {
+			background, template, targetX, err := NewSlideCaptcha(SlideConfig{Rand: NewSeededSource(seed)}).generate()
+			return []interface{}{background, template, targetX, err}
+		},
+		"image_grid": func(seed int64) []interface{} {
+			c := NewImageGridCaptcha(ImageGridConfig{ImageDir: gridDir, Rand: NewSeededSource(seed)})
+			question, targetType, image, required, optional, err := c.generate()
+			return []interface{}{question, targetType, image, required, optional, err}
+		},
+		"pow": func(seed int64) []interface{} {
+			prefix, difficulty := NewPowCaptcha(PowConfig{Rand: NewSeededSource(seed)}).Generate()
+			return []interface{}{prefix, difficulty}
+		},
+	}
+
+	for name, generate := range generators {
+		t.Run(name, func(t *testing.T) {
+			first := generate(7)
+			if err, _ := first[len(first)-1].(error); err != nil {
+				t.Fatal(err)
+			}
+			if second := generate(7); !reflect.DeepEqual(first, second) {
+				t.Error("相同种子的两次生成结果不同")
+			}
+			// 只有一张场景图片时宫格验证码的输出与种子无关
+			if other := generate(8); name != "image_grid" && reflect.DeepEqual(first, other) {
+				t.Error("不同种子的生成结果相同")
+			}
+		})
+	}
+}
+
+// TestSeededImageSelectUsesDataset 固定种子时使用数据集图片而不是占位图片
+func TestSeededImageSelectUsesDataset(t *testing.T) {
+	c := NewImageSelectCaptcha(ImageSelectConfig{ImageDir: writeSelectDataset(t), Rand: NewSeededSource(1)})
+	_, targetType, images, _, err := c.generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if targetType != "bus" && targetType != "car" {
+		t.Errorf("targetType = %q, want a dataset category", targetType)
+	}
+	placeholder, err := encodeImage(c.encoder, c.drawPlaceholder(targetType))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, image := range images {
+		if bytes.Equal(image.Data, placeholder.Data) {
+			t.Error("使用了占位图片")
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// 限流的操作
+const (
+	rateActionGenerate = "generate"
+	rateActionVerify   = "verify"
+)
+
+// RateLimit 限流规则，时间窗口内最多允许 Limit 次
+type RateLimit struct {
+	Limit  int           `json:",optional"`   // 时间窗口内允许的次数，0 表示不限制
+	Window time.Duration `json:",default=1m"` // 时间窗口，默认 1 分钟
+}
+
+// RateLimitConfig 限流配置，按 RequestMeta 中的 IP、设备和账号分别计数，任一超限即拒绝
+type RateLimitConfig struct {
+	Generate   RateLimit `json:",optional"` // 生成验证码的限流
+	Verify     RateLimit `json:",optional"` // 验证验证码的限流
+	FailClosed bool      `json:",optional"` // 限流器出错时拒绝请求并返回 ErrRateLimited，默认放行
+}
+
+// withDefaults 填充零值字段的默认值
+func (c RateLimit) withDefaults() RateLimit {
+	if c.Window == 0 {
+		c.Window = time.Minute
+	}
+	return c
+}
+
+// enabled 是否开启限流
+func (c RateLimitConfig) enabled() bool {
+	return c.Generate.Limit > 0 || c.Verify.Limit > 0
+}
+
+// Validate 校验限流配置
+func (c RateLimitConfig) Validate() error {
+	if c.Generate.Limit < 0 || c.Verify.Limit < 0 || c.Generate.Window < 0 || c.Verify.Window < 0 {
+		return fmt.Errorf("rate limit: limit and window must not be negative")
+	}
+	return nil
+}
+
+// RateLimiter 限流器
+type RateLimiter interface {
+	// Allow 按规则判断 key 是否还能再执行一次，允许时计入一次
+	Allow(ctx context.Context, key string, limit RateLimit) (bool, error)
+}
+
+// AtomicRateLimiter 可选接口，同时按 IP、设备和账号限流时原子地判断所有 key
+//
+// 未实现时依次调用 Allow，后面的 key 被拒绝时前面已计入的次数不会退回。
+type AtomicRateLimiter interface {
+	RateLimiter
+	// AllowAll 所有 key 都还能再执行一次时允许，并为每个 key 计入一次；任一 key 超限时都不计入
+	AllowAll(ctx context.Context, keys []string, limit RateLimit) (bool, error)
+}
+
+// MemRateLimiter 内存令牌桶限流器，仅适用于单实例
+//
+// 桶容量为 Limit，每个 Window 匀速补充 Limit 个令牌，允许短时突发。
+type MemRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket 令牌桶
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	window time.Duration
+}
+
+// NewMemRateLimiter 创建内存令牌桶限流器
+func NewMemRateLimiter() *MemRateLimiter {
+	return &MemRateLimiter{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow 取一个令牌，桶为空时拒绝
+func (l *MemRateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (bool, error) {
+	return l.AllowAll(ctx, []string{key}, limit)
+}
+
+// AllowAll 所有桶都有令牌时各取一个，任一桶为空时拒绝且不取令牌
+func (l *MemRateLimiter) AllowAll(ctx context.Context, keys []string, limit RateLimit) (bool, error) {
+	limit = limit.withDefaults()
+	if limit.Limit <= 0 {
+		return true, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	buckets := make([]*tokenBucket, len(keys))
+	for i, key := range keys {
+		buckets[i] = l.refill(key, limit, now)
+		if buckets[i].tokens < 1 {
+			return false, nil
+		}
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return true, nil
+}
+
+// refill 获取 key 对应的桶，并按经过的时间补充令牌
+func (l *MemRateLimiter) refill(key string, limit RateLimit, now time.Time) *tokenBucket {
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Limit), last: now, window: limit.Window}
+		l.buckets[key] = bucket
+	}
+
+	rate := float64(limit.Limit) / float64(limit.Window)
+	bucket.tokens = min(float64(limit.Limit), bucket.tokens+float64(now.Sub(bucket.last))*rate)
+	bucket.last = now
+	return bucket
+}
+
+// sweep 定期删除已经补满的桶
+func (l *MemRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= bucket.window {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// slidingWindowScript 滑动窗口限流脚本，有序集合中保存窗口内每次请求的时间，所有 key 都未超限时才计入
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+for _, key in ipairs(KEYS) do
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+	if redis.call('ZCARD', key) >= tonumber(ARGV[3]) then
+		return 0
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+end
+return 1
+`)
+
+// RedisRateLimiter Redis 滑动窗口限流器，多实例共享计数
+type RedisRateLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRateLimiter 创建 Redis 滑动窗口限流器
+func NewRedisRateLimiter(client *redis.Client, prefix string) *RedisRateLimiter {
+	if prefix == "" {
+		prefix = "captcha:ratelimit:"
+	}
+	return &RedisRateLimiter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Allow 统计最近一个时间窗口内的次数，未超限时计入一次
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (bool, error) {
+	return l.AllowAll(ctx, []string{key}, limit)
+}
+
+// AllowAll 在一个脚本中统计所有 key 最近一个时间窗口内的次数，都未超限时各计入一次
+func (l *RedisRateLimiter) AllowAll(ctx context.Context, keys []string, limit RateLimit) (bool, error) {
+	limit = limit.withDefaults()
+	if limit.Limit <= 0 {
+		return true, nil
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = l.prefix + key
+	}
+	allowed, err := slidingWindowScript.Run(ctx, l.client, prefixed,
+		time.Now().UnixMilli(), limit.Window.Milliseconds(), limit.Limit, uuid.New().String()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	return allowed == 1, nil
+}
+
+// checkRate 按 context 中的请求方信息限流，限流器出错时按 FailClosed 拒绝或放行，并计入 RateLimiterErrors
+func (s *Service) checkRate(ctx context.Context, action string) error {
+	if s.limiter == nil {
+		return nil
+	}
+
+	limit := s.rateLimit.Generate
+	if action == rateActionVerify {
+		limit = s.rateLimit.Verify
+	}
+	if limit.Limit <= 0 {
+		return nil
+	}
+
+	meta, _ := RequestMetaFromContext(ctx)
+	keys := rateLimitKeys(action, meta)
+	allowed, err := allowAll(ctx, s.limiter, keys, limit)
+	if err != nil {
+		s.limiterErrors.Add(1)
+		if s.rateLimit.FailClosed {
+			logx.Errorf("限流检查失败，拒绝: %v", err)
+			return fmt.Errorf("%w: %w", ErrRateLimited, err)
+		}
+		logx.Errorf("限流检查失败，放行: %v", err)
+		return nil
+	}
+	if !allowed {
+		logx.Infof("请求被限流: %s", strings.Join(keys, ", "))
+		s.emit(Event{Kind: EventRateLimited, Meta: meta, Action: action})
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// allowAll 限流器实现 AtomicRateLimiter 时原子地判断所有 key，否则依次调用 Allow，遇到拒绝或出错时停止
+func allowAll(ctx context.Context, limiter RateLimiter, keys []string, limit RateLimit) (bool, error) {
+	if atomic, ok := limiter.(AtomicRateLimiter); ok {
+		return atomic.AllowAll(ctx, keys, limit)
+	}
+	for _, key := range keys {
+		if allowed, err := limiter.Allow(ctx, key, limit); err != nil || !allowed {
+			return allowed, err
+		}
+	}
+	return true, nil
+}
+
+// RateLimiterErrors 返回限流器出错的次数
+func (s *Service) RateLimiterErrors() uint64 {
+	return s.limiterErrors.Load()
+}
+
+// rateLimitKeys 限流计数的 key，没有 IP、设备和账号的请求共用一个计数
+func rateLimitKeys(action string, meta RequestMeta) []string {
+	var keys []string
+	if meta.IP != "" {
+		keys = append(keys, action+":ip:"+meta.IP)
+	}
+	if meta.DeviceID != "" {
+		keys = append(keys, action+":device:"+meta.DeviceID)
+	}
+	if meta.Account != "" {
+		keys = append(keys, action+":account:"+meta.Account)
+	}
+	if len(keys) == 0 {
+		keys = append(keys, action+":unknown")
+	}
+	return keys
+}
@@ -0,0 +1,206 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// testRateLimiters 内存和 Redis 限流器，Redis 使用 miniredis
+func testRateLimiters(t *testing.T) map[string]RateLimiter {
+	store, _ := newTestRedisStore(t)
+	return map[string]RateLimiter{
+		"memory": NewMemRateLimiter(),
+		"redis":  NewRedisRateLimiter(store.client, ""),
+	}
+}
+
+// TestRateLimiterBoundary 窗口内恰好允许 Limit 次，第 Limit+1 次被拒绝
+func TestRateLimiterBoundary(t *testing.T) {
+	for name, limiter := range testRateLimiters(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := RateLimit{Limit: 3, Window: time.Minute}
+			for i := 0; i < limit.Limit; i++ {
+				if allowed, err := limiter.Allow(ctx, "key", limit); err != nil || !allowed {
+					t.Fatalf("第 %d 次 Allow() = %v, %v, want true", i+1, allowed, err)
+				}
+			}
+			if allowed, err := limiter.Allow(ctx, "key", limit); err != nil || allowed {
+				t.Fatalf("第 %d 次 Allow() = %v, %v, want false", limit.Limit+1, allowed, err)
+			}
+		})
+	}
+}
+
+// TestRateLimiterWindowRollover 时间窗口过去后重新允许
+func TestRateLimiterWindowRollover(t *testing.T) {
+	for name, limiter := range testRateLimiters(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := RateLimit{Limit: 2, Window: 100 * time.Millisecond}
+			for i := 0; i < limit.Limit; i++ {
+				if allowed, _ := limiter.Allow(ctx, "key", limit); !allowed {
+					t.Fatalf("第 %d 次被拒绝", i+1)
+				}
+			}
+			if allowed, _ := limiter.Allow(ctx, "key", limit); allowed {
+				t.Fatal("超限后仍然允许")
+			}
+
+			time.Sleep(limit.Window + 20*time.Millisecond)
+			for i := 0; i < limit.Limit; i++ {
+				if allowed, err := limiter.Allow(ctx, "key", limit); err != nil || !allowed {
+					t.Fatalf("窗口过去后第 %d 次 Allow() = %v, %v, want true", i+1, allowed, err)
+				}
+			}
+		})
+	}
+}
+
+// TestRateLimiterKeyIsolation 不同 key 分别计数
+func TestRateLimiterKeyIsolation(t *testing.T) {
+	for name, limiter := range testRateLimiters(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := RateLimit{Limit: 1, Window: time.Minute}
+			if allowed, _ := limiter.Allow(ctx, "a", limit); !allowed {
+				t.Fatal("a 第 1 次被拒绝")
+			}
+			if allowed, _ := limiter.Allow(ctx, "a", limit); allowed {
+				t.Fatal("a 第 2 次仍然允许")
+			}
+			if allowed, err := limiter.Allow(ctx, "b", limit); err != nil || !allowed {
+				t.Fatalf("b Allow() = %v, %v, want true", allowed, err)
+			}
+		})
+	}
+}
+
+// TestRateLimiterAllowAllNoPartialConsume 任一 key 超限时其他 key 不计入
+func TestRateLimiterAllowAllNoPartialConsume(t *testing.T) {
+	for name, limiter := range testRateLimiters(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := RateLimit{Limit: 2, Window: time.Minute}
+			atomic := limiter.(AtomicRateLimiter)
+			for i := 0; i < limit.Limit; i++ {
+				if allowed, err := atomic.AllowAll(ctx, []string{"a"}, limit); err != nil || !allowed {
+					t.Fatalf("第 %d 次 AllowAll(a) = %v, %v, want true", i+1, allowed, err)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if allowed, err := atomic.AllowAll(ctx, []string{"b", "a"}, limit); err != nil || allowed {
+					t.Fatalf("AllowAll(b, a) = %v, %v, want false", allowed, err)
+				}
+			}
+			for i := 0; i < limit.Limit; i++ {
+				if allowed, err := limiter.Allow(ctx, "b", limit); err != nil || !allowed {
+					t.Fatalf("第 %d 次 Allow(b) = %v, %v, want true", i+1, allowed, err)
+				}
+			}
+		})
+	}
+}
+
+// TestRateLimitDeniedRequestKeepsOtherQuota 账号超限被拒绝的请求不占用所在 IP 的次数
+func TestRateLimitDeniedRequestKeepsOtherQuota(t *testing.T) {
+	for name, limiter := range testRateLimiters(t) {
+		t.Run(name, func(t *testing.T) {
+			service, err := NewService(NewMemStore(),
+				WithRateLimit(RateLimitConfig{Generate: RateLimit{Limit: 2}}),
+				WithRateLimiter(limiter),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer service.Close()
+
+			generate := func(ip, account string) error {
+				_, err := service.Generate(WithRequestMeta(context.Background(), RequestMeta{IP: ip, Account: account}), CaptchaTypeCharacter)
+				return err
+			}
+			for i := 0; i < 2; i++ {
+				if err := generate("203.0.113.1", "alice"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if err := generate("203.0.113.2", "alice"); !errors.Is(err, ErrRateLimited) {
+					t.Fatalf("Generate() error = %v, want ErrRateLimited", err)
+				}
+			}
+			for i := 0; i < 2; i++ {
+				if err := generate("203.0.113.2", "bob"); err != nil {
+					t.Fatalf("第 %d 次 bob Generate() error = %v, want nil", i+1, err)
+				}
+			}
+		})
+	}
+}
+
+// TestRateLimitUnknownRequester 没有请求方信息的请求共用一个计数
+func TestRateLimitUnknownRequester(t *testing.T) {
+	service, err := NewService(NewMemStore(),
+		WithRateLimit(RateLimitConfig{Generate: RateLimit{Limit: 1}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	ctx := context.Background()
+	if _, err := service.Generate(ctx, CaptchaTypeCharacter); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Generate(WithRequestMeta(ctx, RequestMeta{UserAgent: "test"}), CaptchaTypeCharacter); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Generate() error = %v, want ErrRateLimited", err)
+	}
+	if _, err := service.Generate(WithRequestMeta(ctx, RequestMeta{IP: "1.2.3.4"}), CaptchaTypeCharacter); err != nil {
+		t.Errorf("Generate() with IP error = %v", err)
+	}
+}
+
+// TestRateLimitLimiterError 限流器出错时按 FailClosed 放行或拒绝，并计数
+func TestRateLimitLimiterError(t *testing.T) {
+	// 连接不上的 Redis
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer client.Close()
+
+	tests := []struct {
+		name       string
+		failClosed bool
+		wantErr    error
+	}{
+		{name: "默认放行", failClosed: false, wantErr: nil},
+		{name: "出错时拒绝", failClosed: true, wantErr: ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewService(NewMemStore(),
+				WithRateLimit(RateLimitConfig{Generate: RateLimit{Limit: 10}, FailClosed: tt.failClosed}),
+				WithRateLimiter(NewRedisRateLimiter(client, "")),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer service.Close()
+
+			ctx := WithRequestMeta(context.Background(), RequestMeta{IP: "1.2.3.4"})
+			_, err = service.Generate(ctx, CaptchaTypeCharacter)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Generate() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Generate() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := service.RateLimiterErrors(); got != 1 {
+				t.Errorf("RateLimiterErrors() = %d, want 1", got)
+			}
+		})
+	}
+}
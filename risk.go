@@ -0,0 +1,225 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// RiskSignals 风险评估的输入
+type RiskSignals struct {
+	RequestMeta
+	Failures int // 时间窗口内同一 IP、设备或账号验证失败次数的最大值
+}
+
+// RiskEvaluator 风险评估接口，返回 0-1 的风险分，分数越高风险越大
+type RiskEvaluator interface {
+	Evaluate(ctx context.Context, signals RiskSignals) (float64, error)
+}
+
+// RiskEvaluatorFunc 函数形式的风险评估
+type RiskEvaluatorFunc func(ctx context.Context, signals RiskSignals) (float64, error)
+
+// Evaluate 调用 f
+func (f RiskEvaluatorFunc) Evaluate(ctx context.Context, signals RiskSignals) (float64, error) {
+	return f(ctx, signals)
+}
+
+// FailureRiskEvaluator 默认风险评估，按最近的验证失败次数计分
+type FailureRiskEvaluator struct {
+	MaxFailures int // 失败次数达到该值时风险分为 1，默认 5
+}
+
+// Evaluate 风险分为 失败次数/MaxFailures，缺少 User-Agent 时额外加 0.3
+func (e FailureRiskEvaluator) Evaluate(ctx context.Context, signals RiskSignals) (float64, error) {
+	maxFailures := e.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 5
+	}
+
+	score := float64(signals.Failures) / float64(maxFailures)
+	if signals.UserAgent == "" {
+		score += 0.3
+	}
+	return min(score, 1), nil
+}
+
+// RiskBand 风险区间，风险分不低于 MinScore 时使用对应场景生成验证码
+type RiskBand struct {
+	MinScore float64 `json:",optional"` // 区间下限，0-1
+	Scene    string  // 场景名，需要通过 WithScene 或 Conf.Scenes 添加
+}
+
+// RiskConfig 自适应难度配置
+//
+// 每个风险区间对应一个场景，场景决定验证码类型和难度，如低风险使用滑动验证码，
+// 高风险使用复杂度 3 的字符验证码或更多图片的图片选择验证码。
+type RiskConfig struct {
+	FailureWindow time.Duration `json:",default=15m"` // 统计验证失败次数的时间窗口，默认 15 分钟
+	MaxFailures   int           `json:",default=5"`   // 默认风险评估中风险分为 1 的失败次数，默认 5
+	Bands         []RiskBand    `json:",optional"`    // 风险区间
+}
+
+// withDefaults 填充零值字段的默认值
+func (c RiskConfig) withDefaults() RiskConfig {
+	if c.FailureWindow == 0 {
+		c.FailureWindow = 15 * time.Minute
+	}
+	if c.MaxFailures == 0 {
+		c.MaxFailures = 5
+	}
+	return c
+}
+
+// Validate 校验自适应难度配置，场景是否存在在创建服务时校验
+func (c RiskConfig) Validate() error {
+	c = c.withDefaults()
+
+	var errs []error
+	if c.FailureWindow < 0 || c.MaxFailures < 0 {
+		errs = append(errs, fmt.Errorf("risk: failure window and max failures must not be negative"))
+	}
+	if len(c.Bands) == 0 {
+		errs = append(errs, fmt.Errorf("risk: at least one band is required"))
+	}
+	for i, band := range c.Bands {
+		if band.MinScore < 0 || band.MinScore > 1 {
+			errs = append(errs, fmt.Errorf("risk: band %d min score must be in [0, 1], got %g", i, band.MinScore))
+		}
+		if band.Scene == "" {
+			errs = append(errs, fmt.Errorf("risk: band %d scene is required", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// riskPolicy 已创建的自适应难度策略
+type riskPolicy struct {
+	evaluator RiskEvaluator
+	window    time.Duration
+	bands     []RiskBand // 按 MinScore 从高到低排序
+}
+
+// newRiskPolicy 创建自适应难度策略，evaluator 为空时使用 FailureRiskEvaluator
+func newRiskPolicy(config RiskConfig, evaluator RiskEvaluator) *riskPolicy {
+	config = config.withDefaults()
+	if evaluator == nil {
+		evaluator = FailureRiskEvaluator{MaxFailures: config.MaxFailures}
+	}
+
+	bands := append([]RiskBand(nil), config.Bands...)
+	sort.SliceStable(bands, func(i, j int) bool {
+		return bands[i].MinScore > bands[j].MinScore
+	})
+
+	return &riskPolicy{
+		evaluator: evaluator,
+		window:    config.FailureWindow,
+		bands:     bands,
+	}
+}
+
+// band 返回风险分所在的区间，低于所有下限时使用最低的区间
+func (p *riskPolicy) band(score float64) RiskBand {
+	for _, band := range p.bands {
+		if score >= band.MinScore {
+			return band
+		}
+	}
+	return p.bands[len(p.bands)-1]
+}
+
+// sceneAllowed 场景所在的区间是否不低于风险分要求的区间，不属于任何区间的场景不受限制
+func (p *riskPolicy) sceneAllowed(scene string, score float64) bool {
+	required := p.band(score)
+	for _, band := range p.bands {
+		if band.Scene == scene {
+			return band.MinScore >= required.MinScore
+		}
+	}
+	return true
+}
+
+// EvaluateRisk 按 context 中的请求方信息评估风险分，未开启自适应难度时返回 0
+func (s *Service) EvaluateRisk(ctx context.Context) (float64, error) {
+	if s.risk == nil {
+		return 0, nil
+	}
+
+	meta, _ := RequestMetaFromContext(ctx)
+	score, err := s.risk.evaluator.Evaluate(ctx, RiskSignals{
+		RequestMeta: meta,
+		Failures:    s.recentFailures(ctx, meta),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate risk: %w", err)
+	}
+	return score, nil
+}
+
+// GenerateAdaptive 按风险分选择场景生成验证码，验证时需要带上返回的场景名
+//
+// 请求方信息通过 WithRequestMeta 放入 ctx；风险评估失败时按最高风险处理。
+// 验证风险区间场景的验证码时会重新评估风险分，场景低于当前风险要求的区间时返回 VerifyReasonRiskMismatch，
+// 高风险请求方不能通过 GenerateForScene 直接生成低风险场景的验证码绕过自适应难度。
+func (s *Service) GenerateAdaptive(ctx context.Context) (*CaptchaResponse, error) {
+	if s.risk == nil {
+		return nil, ErrRiskNotEnabled
+	}
+
+	return s.GenerateForScene(ctx, s.risk.band(s.adaptiveScore(ctx)).Scene)
+}
+
+// adaptiveScore 评估选择场景用的风险分，评估失败时按最高风险处理
+func (s *Service) adaptiveScore(ctx context.Context) float64 {
+	score, err := s.EvaluateRisk(ctx)
+	if err != nil {
+		logx.Errorf("风险评估失败，按最高风险处理: %v", err)
+		return 1
+	}
+	return score
+}
+
+// recentFailures 返回时间窗口内 IP、设备和账号中最多的验证失败次数
+func (s *Service) recentFailures(ctx context.Context, meta RequestMeta) int {
+	failures := 0
+	for _, key := range failureKeys(meta) {
+		value, err := s.store.Get(ctx, key)
+		if err != nil {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			failures = max(failures, n)
+		}
+	}
+	return failures
+}
+
+// recordFailure 记录一次验证失败，计数在最后一次失败后 FailureWindow 过期
+func (s *Service) recordFailure(ctx context.Context, meta RequestMeta) {
+	for _, key := range failureKeys(meta) {
+		if _, err := incr(ctx, s.store, key, s.risk.window); err != nil {
+			logx.Errorf("failed to record captcha failure: %v", err)
+		}
+	}
+}
+
+// failureKeys 失败次数在 Store 中的 key
+func failureKeys(meta RequestMeta) []string {
+	var keys []string
+	if meta.IP != "" {
+		keys = append(keys, failureKey("ip", meta.IP))
+	}
+	if meta.DeviceID != "" {
+		keys = append(keys, failureKey("device", meta.DeviceID))
+	}
+	if meta.Account != "" {
+		keys = append(keys, failureKey("account", meta.Account))
+	}
+	return keys
+}
@@ -0,0 +1,146 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// newRiskTestService 创建开启自适应难度的服务
+func newRiskTestService(t *testing.T, store Store) *Service {
+	t.Helper()
+	service, err := NewService(store,
+		WithScene("low", SceneConfig{Type: CaptchaTypeCharacter}),
+		WithRiskConfig(RiskConfig{Bands: []RiskBand{{Scene: "low"}}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(service.Close)
+	return service
+}
+
+// TestVerifyRejectsNonCaptchaKeys 验证码ID不能指向失败计数、作废标记等其他类型的数据
+func TestVerifyRejectsNonCaptchaKeys(t *testing.T) {
+	store := NewMemStore()
+	service := newRiskTestService(t, store)
+	meta := RequestMeta{IP: "1.2.3.4", UserAgent: "test"}
+	ctx := WithRequestMeta(context.Background(), meta)
+
+	resp, err := service.Generate(ctx, CaptchaTypeCharacter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.recordFailure(ctx, meta)
+	service.discard(ctx, resp.CaptchaID, &captchaRecord{Type: CaptchaTypeCharacter}, VerifyReasonReplayed)
+
+	ids := []string{
+		failureKey("ip", meta.IP),
+		markerKey(resp.CaptchaID),
+		resp.CaptchaID + ":marker",
+		"urn:uuid:" + resp.CaptchaID,
+		"",
+	}
+	for _, id := range ids {
+		result, err := service.VerifyDetailed(ctx, &VerifyRequest{CaptchaID: id, CaptchaType: CaptchaTypeCharacter, Answer: "abcd"})
+		if err != nil {
+			t.Errorf("VerifyDetailed(%q) error = %v", id, err)
+			continue
+		}
+		if result.OK || result.Reason != VerifyReasonNotFound {
+			t.Errorf("VerifyDetailed(%q) = %+v, want not_found", id, result)
+		}
+	}
+}
+
+// TestRecordFailureConcurrent 并发失败时计数不丢失
+func TestRecordFailureConcurrent(t *testing.T) {
+	service := newRiskTestService(t, NewMemStore())
+	meta := RequestMeta{IP: "1.2.3.4", DeviceID: "device"}
+	ctx := context.Background()
+
+	const n = 20
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			service.recordFailure(ctx, meta)
+		}()
+	}
+	wg.Wait()
+
+	if got := service.recentFailures(ctx, meta); got != n {
+		t.Errorf("recentFailures() = %d, want %d", got, n)
+	}
+	if got := service.recentFailures(ctx, RequestMeta{IP: "5.6.7.8"}); got != 0 {
+		t.Errorf("recentFailures() for another IP = %d, want 0", got)
+	}
+}
+
+// TestVerifyRejectsLowerRiskScene 高风险请求方直接生成低风险场景的验证码时，即使答案正确也不能通过
+func TestVerifyRejectsLowerRiskScene(t *testing.T) {
+	store := NewMemStore()
+	service, err := NewService(store,
+		WithScene("low", SceneConfig{Type: CaptchaTypeCharacter}),
+		WithScene("high", SceneConfig{Type: CaptchaTypeCharacter, Character: CharacterConfig{Complexity: 3}}),
+		WithScene("login", SceneConfig{Type: CaptchaTypeCharacter}),
+		WithRiskConfig(RiskConfig{Bands: []RiskBand{{Scene: "low"}, {MinScore: 0.6, Scene: "high"}}}),
+		WithRiskEvaluator(RiskEvaluatorFunc(func(ctx context.Context, signals RiskSignals) (float64, error) {
+			switch signals.IP {
+			case "203.0.113.66":
+				return 0.9, nil
+			case "203.0.113.99":
+				return 0, errors.New("risk model unavailable")
+			}
+			return 0, nil
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+
+	lowRisk := RequestMeta{IP: "203.0.113.10"}
+	highRisk := RequestMeta{IP: "203.0.113.66"}
+	unknown := RequestMeta{IP: "203.0.113.99"}
+
+	tests := []struct {
+		name  string
+		meta  RequestMeta
+		scene string // 为空时使用 GenerateAdaptive
+		want  VerifyReason
+	}{
+		{"低风险使用低风险场景", lowRisk, "low", VerifyReasonOK},
+		{"低风险使用高风险场景", lowRisk, "high", VerifyReasonOK},
+		{"高风险自适应生成", highRisk, "", VerifyReasonOK},
+		{"高风险直接生成低风险场景", highRisk, "low", VerifyReasonRiskMismatch},
+		{"高风险使用区间外的场景", highRisk, "login", VerifyReasonOK},
+		{"评估失败按最高风险处理", unknown, "low", VerifyReasonRiskMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scene := tt.scene
+			var id string
+			if scene == "" {
+				resp, err := service.GenerateAdaptive(withMeta(tt.meta))
+				if err != nil {
+					t.Fatal(err)
+				}
+				id, scene = resp.CaptchaID, resp.Scene
+			} else {
+				id = generate(t, service, scene, tt.meta)
+			}
+
+			result, err := service.VerifyDetailed(withMeta(tt.meta), characterRequest(t, store, id, scene))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Reason != tt.want || result.OK != (tt.want == VerifyReasonOK) {
+				t.Errorf("VerifyDetailed() = %+v, want %s", result, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package captcha
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// SceneConfig 场景配置
+//
+// 不同业务流程（登录、注册、发送短信、重置密码等）可以使用不同的验证码类型、难度和过期时间。
+// 与 Type 对应的类型配置不为空时，场景使用自己的生成器；否则使用服务的配置。
+type SceneConfig struct {
+	Type         CaptchaType       `json:",options=character|image_select|slide|image_grid|pow"` // 验证码类型
+	ExpireTime   time.Duration     `json:",optional"`                                            // 过期时间，为 0 时使用类型配置中的过期时间
+	MaxAttempts  int               `json:",optional"`                                            // 允许验证失败的次数，达到后验证码作废，0 表示不限制
+	MinSolveTime time.Duration     `json:",optional"`                                            // 最短作答时间，为 0 时使用 HumanCheckConfig.MinSolveTime
+	Character    CharacterConfig   `json:",optional"`                                            // 场景专用的字符验证码配置
+	ImageSelect  ImageSelectConfig `json:",optional"`                                            // 场景专用的图片选择验证码配置
+	Slide        SlideConfig       `json:",optional"`                                            // 场景专用的滑动验证码配置
+	ImageGrid    ImageGridConfig   `json:",optional"`                                            // 场景专用的宫格图片选择验证码配置
+	Pow          PowConfig         `json:",optional"`                                            // 场景专用的工作量证明验证码配置，可以按场景调整难度
+}
+
+// assetDirs 返回场景专用配置中需要资源库扫描的图片目录和清单
+func (c SceneConfig) assetDirs() []string {
+	switch c.Type {
+	case CaptchaTypeImageSelect:
+		return []string{c.ImageSelect.ImageDir, c.ImageSelect.Manifest}
+	case SlideTypeSelect:
+		return []string{c.Slide.ImageDir, c.Slide.TemplateDir}
+	case CaptchaTypeImageGrid:
+		return []string{c.ImageGrid.ImageDir}
+	}
+	return nil
+}
+
+// Validate 校验场景配置
+func (c SceneConfig) Validate() error {
+	if c.ExpireTime < 0 {
+		return fmt.Errorf("expire time must not be negative")
+	}
+	if c.MaxAttempts < 0 {
+		return fmt.Errorf("max attempts must not be negative")
+	}
+	if c.MinSolveTime < 0 {
+		return fmt.Errorf("min solve time must not be negative")
+	}
+
+	switch c.Type {
+	case CaptchaTypeCharacter:
+		if c.Character != (CharacterConfig{}) {
+			return c.Character.Validate()
+		}
+	case CaptchaTypeImageSelect:
+		if c.ImageSelect != (ImageSelectConfig{}) {
+			return c.ImageSelect.Validate()
+		}
+	case SlideTypeSelect:
+		if c.Slide != (SlideConfig{}) {
+			return c.Slide.Validate()
+		}
+	case CaptchaTypeImageGrid:
+		if c.ImageGrid != (ImageGridConfig{}) {
+			return c.ImageGrid.Validate()
+		}
+	case CaptchaTypePow:
+		if c.Pow != (PowConfig{}) {
+			return c.Pow.Validate()
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrCaptchaTypeNotSupported, c.Type)
+	}
+	return nil
+}
+
+// withRand 为场景专用配置设置随机数源，未设置专用配置时保持为空
+func (c SceneConfig) withRand(source rand.Source) SceneConfig {
+	if c.Character != (CharacterConfig{}) && c.Character.Rand == nil {
+		c.Character.Rand = source
+	}
+	if c.ImageSelect != (ImageSelectConfig{}) && c.ImageSelect.Rand == nil {
+		c.ImageSelect.Rand = source
+	}
+	if c.Slide != (SlideConfig{}) && c.Slide.Rand == nil {
+		c.Slide.Rand = source
+	}
+	if c.ImageGrid != (ImageGridConfig{}) && c.ImageGrid.Rand == nil {
+		c.ImageGrid.Rand = source
+	}
+	if c.Pow != (PowConfig{}) && c.Pow.Rand == nil {
+		c.Pow.Rand = source
+	}
+	return c
+}
+
+// scene 已创建的场景
+type scene struct {
+	name       string
+	config     SceneConfig
+	generators *generatorSet // 没有场景专用配置时与服务共用
+}
+
+// newScene 创建场景，只有场景设置了专用配置时才创建新的生成器
+func newScene(name string, config SceneConfig, defaults *generatorSet, assets *AssetLibrary) *scene {
+	sc := &scene{name: name, config: config, generators: defaults}
+
+	custom := *defaults
+	switch config.Type {
+	case CaptchaTypeCharacter:
+		if config.Character != (CharacterConfig{}) {
+			custom.character = NewCharacterCaptcha(config.Character)
+		}
+	case CaptchaTypeImageSelect:
+		if config.ImageSelect != (ImageSelectConfig{}) {
+			custom.imageSelect = NewImageSelectCaptcha(config.ImageSelect).WithAssetLibrary(assets)
+		}
+	case SlideTypeSelect:
+		if config.Slide != (SlideConfig{}) {
+			custom.slide = NewSlideCaptcha(config.Slide).WithAssetLibrary(assets)
+		}
+	case CaptchaTypeImageGrid:
+		if config.ImageGrid != (ImageGridConfig{}) {
+			custom.imageGrid = NewImageGridCaptcha(config.ImageGrid).WithAssetLibrary(assets)
+		}
+	case CaptchaTypePow:
+		if config.Pow != (PowConfig{}) {
+			custom.pow = NewPowCaptcha(config.Pow)
+		}
+	}
+	if custom != *defaults {
+		sc.generators = &custom
+	}
+
+	return sc
+}
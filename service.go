@@ -3,43 +3,455 @@ package captcha
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
+// defaultAssetPollInterval 服务共享的图片资源库默认轮询间隔
+const defaultAssetPollInterval = 30 * time.Second
+
 // Service 验证码服务
 type Service struct {
-	store             Store
-	characterConfig   CharacterConfig
-	imageSelectConfig ImageSelectConfig
-	slideConfig       SlideConfig
+	store      Store
+	assets     *AssetLibrary
+	ownsAssets bool // 资源库由服务创建，Close 时一并关闭
+
+	// 生成器在创建服务时构建一次，所有请求共享
+	generators *generatorSet
+
+	// 默认验证码类型，Generate 传入空类型时使用
+	defaultType CaptchaType
+
+	// 场景，key 为场景名
+	scenes map[string]*scene
+
+	// 预生成验证码池，按类型开启
+	pools map[CaptchaType]*captchaPool
+
+	// 图片地址下发配置，为空时图片以 data URI 内联返回
+	assetURLs *AssetURLConfig
+
+	// 自适应难度策略，为空时不统计失败次数
+	risk *riskPolicy
+
+	// 限流器，为空时不限流
+	limiter       RateLimiter
+	rateLimit     RateLimitConfig
+	limiterErrors atomic.Uint64
+
+	// 客户端绑定配置
+	binding ClientBindingConfig
+
+	// 无感验证，为空时不开启
+	invisible *invisible
+
+	// 提交检查配置
+	humanCheck HumanCheckConfig
+
+	// 事件分发，未设置观察者时为空
+	events *eventDispatcher
 }
 
 // NewService 创建验证码服务
-func NewService(store Store, characterConfig CharacterConfig, imageSelectConfig ImageSelectConfig, slideConfig SlideConfig) *Service {
-	return &Service{
-		store:             store,
-		characterConfig:   characterConfig,
-		imageSelectConfig: imageSelectConfig,
-		slideConfig:       slideConfig,
+//
+// 服务启动时一次性扫描所有配置的图片目录，解码后的图片由所有生成器共享，
+// 并定期轮询目录变化。所有配置在创建时校验，配置无效时返回错误。
+// 不再使用服务时调用 Close 停止轮询。
+//
+//	service, err := captcha.NewService(store,
+//		captcha.WithCharacterConfig(captcha.CharacterConfig{Length: 5}),
+//		captcha.WithSlideConfig(captcha.SlideConfig{ImageDir: "./images/backgrounds"}),
+//	)
+func NewService(store Store, opts ...Option) (*Service, error) {
+	if store == nil {
+		return nil, fmt.Errorf("captcha: store is required")
+	}
+
+	o := &serviceOptions{
+		assetsConfig: AssetLibraryConfig{PollInterval: defaultAssetPollInterval},
+		pools:        make(map[CaptchaType]PoolConfig),
+		scenes:       make(map[string]SceneConfig),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.rand != nil {
+		if o.character.Rand == nil {
+			o.character.Rand = o.rand
+		}
+		if o.imageSelect.Rand == nil {
+			o.imageSelect.Rand = o.rand
+		}
+		if o.slide.Rand == nil {
+			o.slide.Rand = o.rand
+		}
+		if o.imageGrid.Rand == nil {
+			o.imageGrid.Rand = o.rand
+		}
+		if o.pow.Rand == nil {
+			o.pow.Rand = o.rand
+		}
+		for name, config := range o.scenes {
+			o.scenes[name] = config.withRand(o.rand)
+		}
+	}
+
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	assets, ownsAssets := o.assets, false
+	if assets == nil {
+		dirs := []string{
+			o.imageSelect.ImageDir,
+			o.imageSelect.Manifest,
+			o.slide.ImageDir,
+			o.slide.TemplateDir,
+			o.imageGrid.ImageDir,
+		}
+		for _, config := range o.scenes {
+			dirs = append(dirs, config.assetDirs()...)
+		}
+		assets, ownsAssets = NewAssetLibrary(o.assetsConfig, dirs...), true
+	}
+
+	s := &Service{
+		store:       store,
+		assets:      assets,
+		ownsAssets:  ownsAssets,
+		generators:  newGeneratorSet(o.character, o.imageSelect, o.slide, o.imageGrid, o.pow, assets),
+		defaultType: o.defaultType,
+		scenes:      make(map[string]*scene, len(o.scenes)),
+		pools:       make(map[CaptchaType]*captchaPool),
+		binding:     o.binding,
+		humanCheck:  o.humanCheck.withDefaults(),
+	}
+	if s.defaultType == "" {
+		s.defaultType = CaptchaTypeCharacter
+	}
+	for name, config := range o.scenes {
+		s.scenes[name] = newScene(name, config, s.generators, assets)
+	}
+	if o.risk != nil {
+		s.risk = newRiskPolicy(*o.risk, o.evaluator)
+	}
+	if o.invisible != nil {
+		s.invisible = &invisible{config: o.invisible.withDefaults(), scorer: o.scorer}
+		if s.invisible.scorer == nil {
+			s.invisible.scorer = HeuristicScorer{}
+		}
+	}
+	if o.rateLimit != nil && o.rateLimit.enabled() {
+		s.limiter, s.rateLimit = o.limiter, *o.rateLimit
+		if s.limiter == nil {
+			s.limiter = NewMemRateLimiter()
+		}
+	}
+
+	if len(o.observers) > 0 {
+		s.events = newEventDispatcher(o.observers, o.observer)
+	}
+
+	if o.assetURLs != nil {
+		if err := s.EnableAssetURLs(*o.assetURLs); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	for captchaType, config := range o.pools {
+		if err := s.EnablePool(captchaType, config); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// validate 校验所有配置，返回包含全部问题的错误
+func (o *serviceOptions) validate() error {
+	var errs []error
+	if o.defaultType != "" && !o.defaultType.valid() {
+		errs = append(errs, fmt.Errorf("invalid default type: %w: %q", ErrCaptchaTypeNotSupported, o.defaultType))
+	}
+	if err := o.character.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid character config: %w", err))
+	}
+	if err := o.imageSelect.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid image select config: %w", err))
+	}
+	if err := o.slide.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid slide config: %w", err))
+	}
+	if err := o.imageGrid.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid image grid config: %w", err))
+	}
+	if err := o.pow.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid pow config: %w", err))
+	}
+	if o.assetURLs != nil {
+		if err := o.assetURLs.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for captchaType, config := range o.pools {
+		if err := config.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s pool config: %w", captchaType, err))
+		}
+	}
+	for name, config := range o.scenes {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("scene name is required"))
+		}
+		if err := config.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid scene %q: %w", name, err))
+		}
 	}
+	if err := o.binding.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.humanCheck.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.observer.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, observer := range o.observers {
+		if observer == nil {
+			errs = append(errs, fmt.Errorf("observer: observer must not be nil"))
+		}
+	}
+	if o.rateLimit != nil {
+		if err := o.rateLimit.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.invisible != nil {
+		if err := o.invisible.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if _, ok := o.scenes[o.invisible.FallbackScene]; o.invisible.FallbackScene != "" && !ok {
+			errs = append(errs, fmt.Errorf("invisible: scene %q not found", o.invisible.FallbackScene))
+		}
+	}
+	if o.risk != nil {
+		if err := o.risk.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		for _, band := range o.risk.Bands {
+			if _, ok := o.scenes[band.Scene]; band.Scene != "" && !ok {
+				errs = append(errs, fmt.Errorf("risk: scene %q not found", band.Scene))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// EnablePool 为指定类型开启预生成验证码池，需要在开始处理请求前调用
+func (s *Service) EnablePool(captchaType CaptchaType, config PoolConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.generators.render(captchaType); err != nil {
+		return fmt.Errorf("failed to enable pool for %s: %w", captchaType, err)
+	}
+	if old, ok := s.pools[captchaType]; ok {
+		old.close()
+	}
+
+	s.pools[captchaType] = newCaptchaPool(captchaType, config, func() (*renderedCaptcha, error) {
+		return s.generators.render(captchaType)
+	})
+	return nil
+}
+
+// PoolStats 返回所有验证码池的状态
+func (s *Service) PoolStats() []PoolStats {
+	stats := make([]PoolStats, 0, len(s.pools))
+	for _, pool := range s.pools {
+		stats = append(stats, pool.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Type < stats[j].Type
+	})
+	return stats
 }
 
-// Generate 生成验证码
+// Close 停止图片资源库的目录轮询和验证码池的后台生成，并等待已产生的事件分发完毕
+func (s *Service) Close() {
+	if s.ownsAssets {
+		s.assets.Close()
+	}
+	for _, pool := range s.pools {
+		pool.close()
+	}
+	if s.events != nil {
+		s.events.close()
+	}
+}
+
+// renderedCaptcha 已渲染但尚未分配ID的验证码
+type renderedCaptcha struct {
+	secret     interface{}       // 存储的答案数据
+	data       interface{}       // 返回给前端的验证码数据，图片字段在下发时由 images 填充
+	images     map[string]*Asset // 编码后的图片，key 为图片名，如 image、image-0、background
+	expireTime time.Duration     // 过期时间
+	renderedAt time.Time         // 渲染时间
+}
+
+// Generate 生成验证码，captchaType 为空时使用默认类型，开启限流且超限时返回 ErrRateLimited
 func (s *Service) Generate(ctx context.Context, captchaType CaptchaType) (*CaptchaResponse, error) {
+	start := time.Now()
+	if captchaType == "" {
+		captchaType = s.defaultType
+	}
+	if err := s.checkRate(ctx, rateActionGenerate); err != nil {
+		return nil, err
+	}
+
+	// 开启验证码池时优先从池中取，池为空时同步生成
+	var rendered *renderedCaptcha
+	var err error
+	if pool, ok := s.pools[captchaType]; ok {
+		rendered, err = pool.take()
+	} else {
+		rendered, err = s.generators.render(captchaType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, captchaType, "", rendered, start)
+}
+
+// DefaultType 返回默认验证码类型
+func (s *Service) DefaultType() CaptchaType {
+	return s.defaultType
+}
+
+// GenerateForScene 按场景配置生成验证码，验证时需要带上相同的场景名
+func (s *Service) GenerateForScene(ctx context.Context, sceneName string) (*CaptchaResponse, error) {
+	start := time.Now()
+	sc, ok := s.scenes[sceneName]
+	if !ok {
+		return nil, ErrSceneNotFound
+	}
+	if err := s.checkRate(ctx, rateActionGenerate); err != nil {
+		return nil, err
+	}
+
+	// 使用服务配置的场景可以共享验证码池
+	var rendered *renderedCaptcha
+	var err error
+	if pool, ok := s.pools[sc.config.Type]; ok && sc.generators == s.generators {
+		rendered, err = pool.take()
+	} else {
+		rendered, err = sc.generators.render(sc.config.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sc.config.ExpireTime > 0 {
+		rendered.expireTime = sc.config.ExpireTime
+	}
+
+	return s.issue(ctx, sc.config.Type, sceneName, rendered, start)
+}
+
+// issue 分配验证码ID，把答案存入 Store 并返回给前端的数据，start 为开始生成的时间
+func (s *Service) issue(ctx context.Context, captchaType CaptchaType, sceneName string, rendered *renderedCaptcha, start time.Time) (*CaptchaResponse, error) {
 	captchaID := uuid.New().String()
 
+	secret, err := json.Marshal(rendered.secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal captcha data: %w", err)
+	}
+
+	now := time.Now()
+	record := &captchaRecord{
+		Type:      captchaType,
+		Scene:     sceneName,
+		CreatedAt: now.UnixMilli(),
+		ExpireAt:  now.Add(rendered.expireTime).UnixMilli(),
+		Data:      secret,
+	}
+	meta, _ := RequestMetaFromContext(ctx)
+	if s.binding.enabled() {
+		record.Client = s.binding.bind(meta)
+	}
+
+	// 存储验证码数据，过期后再保留一段时间，用于区分已过期和不存在
+	err = s.store.Set(ctx, captchaID, record, record.storeTTL())
+	if err != nil {
+		logx.Errorf("failed to store captcha: %v", err)
+		return nil, fmt.Errorf("failed to store captcha: %w", err)
+	}
+
+	// 填充图片：开启图片地址下发时把图片存入 Store 并使用签名地址，否则使用 data URI
+	urls, err := s.imageURLs(ctx, captchaID, rendered)
+	if err != nil {
+		logx.Errorf("failed to externalize captcha assets: %v", err)
+		return nil, err
+	}
+	captchaData := withImageURLs(rendered.data, urls)
+
+	s.emit(Event{
+		Kind:        EventGenerate,
+		CaptchaID:   captchaID,
+		CaptchaType: captchaType,
+		Scene:       sceneName,
+		Meta:        meta,
+		Duration:    time.Since(start),
+		ImageBytes:  rendered.imageBytes(),
+	})
+
+	return &CaptchaResponse{
+		CaptchaID:   captchaID,
+		CaptchaType: captchaType,
+		Scene:       sceneName,
+		Data:        captchaData,
+		ExpireTime:  now.Add(rendered.expireTime).Unix(),
+	}, nil
+}
+
+// generatorSet 一组生成器，服务默认配置和每个自定义配置的场景各有一组
+type generatorSet struct {
+	character   *CharacterCaptcha
+	imageSelect *ImageSelectCaptcha
+	slide       *SlideCaptcha
+	imageGrid   *ImageGridCaptcha
+	pow         *PowCaptcha
+}
+
+// newGeneratorSet 创建生成器，图片资源库由所有生成器共享
+func newGeneratorSet(character CharacterConfig, imageSelect ImageSelectConfig, slide SlideConfig, imageGrid ImageGridConfig, pow PowConfig, assets *AssetLibrary) *generatorSet {
+	return &generatorSet{
+		character:   NewCharacterCaptcha(character),
+		imageSelect: NewImageSelectCaptcha(imageSelect).WithAssetLibrary(assets),
+		slide:       NewSlideCaptcha(slide).WithAssetLibrary(assets),
+		imageGrid:   NewImageGridCaptcha(imageGrid).WithAssetLibrary(assets),
+		pow:         NewPowCaptcha(pow),
+	}
+}
+
+// render 渲染验证码图片并生成答案
+func (g *generatorSet) render(captchaType CaptchaType) (*renderedCaptcha, error) {
 	var data interface{}
 	var expireTime time.Duration
 	var captchaData interface{}
+	images := make(map[string]*Asset)
 
 	switch captchaType {
 	case CaptchaTypeCharacter:
-		charCaptcha := NewCharacterCaptcha(s.characterConfig)
-		code, image, err := charCaptcha.Generate()
+		charCaptcha := g.character
+		code, image, err := charCaptcha.generate()
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate character captcha: %w", err)
 		}
@@ -47,33 +459,40 @@ func (s *Service) Generate(ctx context.Context, captchaType CaptchaType) (*Captc
 		data = CharacterData{
 			Code: code,
 		}
-		expireTime = s.characterConfig.ExpireTime
-		captchaData = CharacterCaptchaData{
-			Image: image,
-		}
+		expireTime = charCaptcha.config.ExpireTime
+		captchaData = CharacterCaptchaData{}
+		images["image"] = image
 
 	case CaptchaTypeImageSelect:
-		imageCaptcha := NewImageSelectCaptcha(s.imageSelectConfig)
-		question, images, targetIndexes, err := imageCaptcha.Generate()
+		imageCaptcha := g.imageSelect
+		question, targetType, selectImages, targetIndexes, err := imageCaptcha.generate()
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate image select captcha: %w", err)
 		}
 
 		data = ImageSelectData{
 			TargetIndexes: targetIndexes,
+			TargetType:    targetType,
 			Question:      question,
 		}
-		expireTime = s.imageSelectConfig.ExpireTime
+		expireTime = imageCaptcha.config.ExpireTime
+		selectCount := len(targetIndexes)
+		if imageCaptcha.HidesSelectCount() {
+			selectCount = 0
+		}
 		captchaData = ImageSelectCaptchaData{
 			Question:    question,
-			TargetType:  "bus", // 固定为公交车，实际可根据配置变化
-			Images:      images,
-			SelectCount: s.imageSelectConfig.SelectCount,
+			TargetType:  targetType,
+			Images:      make([]string, len(selectImages)),
+			SelectCount: selectCount,
+		}
+		for i, image := range selectImages {
+			images[imageSelectName(i)] = image
 		}
 
 	case SlideTypeSelect:
-		slideCaptcha := NewSlideCaptcha(s.slideConfig)
-		background, template, _, targetX, err := slideCaptcha.Generate()
+		slideCaptcha := g.slide
+		background, template, targetX, err := slideCaptcha.generate()
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate slide captcha: %w", err)
 		}
@@ -81,81 +500,136 @@ func (s *Service) Generate(ctx context.Context, captchaType CaptchaType) (*Captc
 		data = SlideData{
 			TargetX: targetX,
 		}
-		expireTime = s.slideConfig.ExpireTime
+		expireTime = slideCaptcha.config.ExpireTime
 		captchaData = SlideCaptchaData{
-			BackgroundImage: background,
-			TemplateImage:   template,
-			TemplateY:       0, // 简化实现
-			Width:           s.slideConfig.Width,
-			Height:          s.slideConfig.Height,
+			TemplateY: 0, // 简化实现
+			Width:     slideCaptcha.config.Width,
+			Height:    slideCaptcha.config.Height,
+		}
+		images["background"] = background
+		images["template"] = template
+
+	case CaptchaTypeImageGrid:
+		gridCaptcha := g.imageGrid
+		question, targetType, image, requiredTiles, optionalTiles, err := gridCaptcha.generate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate image grid captcha: %w", err)
+		}
+
+		data = ImageGridData{
+			RequiredTiles: requiredTiles,
+			OptionalTiles: optionalTiles,
+			Question:      question,
+		}
+		expireTime = gridCaptcha.config.ExpireTime
+		captchaData = ImageGridCaptchaData{
+			Question:   question,
+			TargetType: targetType,
+			GridSize:   gridCaptcha.config.GridSize,
+		}
+		images["image"] = image
+
+	case CaptchaTypePow:
+		powCaptcha := g.pow
+		prefix, difficulty := powCaptcha.Generate()
+
+		data = PowData{
+			Prefix:     prefix,
+			Difficulty: difficulty,
+		}
+		expireTime = powCaptcha.config.ExpireTime
+		captchaData = PowCaptchaData{
+			Prefix:     prefix,
+			Difficulty: difficulty,
+			Algorithm:  "SHA-256",
 		}
 
 	default:
 		return nil, ErrCaptchaTypeNotSupported
 	}
-	marshal, _ := json.Marshal(data)
-	fmt.Println("存储的验证码", string(marshal))
-
-	// 存储验证码数据
-	err := s.store.Set(ctx, captchaID, data, expireTime)
-	if err != nil {
-		logx.Errorf("failed to store captcha: %v", err)
-		return nil, fmt.Errorf("failed to store captcha: %w", err)
-	}
 
-	return &CaptchaResponse{
-		CaptchaID:   captchaID,
-		CaptchaType: captchaType,
-		Data:        captchaData,
-		ExpireTime:  time.Now().Add(expireTime).Unix(),
+	return &renderedCaptcha{
+		secret:     data,
+		data:       captchaData,
+		images:     images,
+		expireTime: expireTime,
+		renderedAt: time.Now(),
 	}, nil
 }
 
-// Verify 验证验证码
+// Verify 验证验证码，验证通过后验证码仍然有效，需要作废时使用 VerifyAndDelete
+//
+// 验证码类型和场景必须与生成时一致，开启客户端绑定时请求方也必须一致，
+// 蜜罐字段被填写或作答时间过短时按 HumanCheckConfig.Action 处理；
+// 场景设置了 MaxAttempts 时，验证失败次数达到上限后验证码作废，验证次数在验证答案前计数，Verify 验证通过也计入一次；
+// 验证码已过期时返回 ErrCaptchaExpired。需要失败原因时使用 VerifyDetailed。
 func (s *Service) Verify(ctx context.Context, req *VerifyRequest) (bool, error) {
-	// 获取存储的验证码数据
-	value, err := s.store.Get(ctx, req.CaptchaID)
+	result, err := s.verify(ctx, req, false)
+	return result.OK, err
+}
+
+// loadRecord 从 Store 读取验证码记录，旧格式的记录按 legacyType 处理
+func (s *Service) loadRecord(ctx context.Context, captchaID string, legacyType CaptchaType) (*captchaRecord, error) {
+	value, err := s.store.Get(ctx, captchaID)
 	if err != nil {
-		fmt.Println("拿到错误了", err)
-		if err == ErrCaptchaNotFound {
-			return false, nil
+		if errors.Is(err, ErrCaptchaNotFound) {
+			return nil, ErrCaptchaNotFound
 		}
-		return false, fmt.Errorf("failed to get captcha: %w", err)
+		return nil, fmt.Errorf("failed to get captcha: %w", err)
 	}
 
-	// 根据类型验证
-	switch req.CaptchaType {
+	var record captchaRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal captcha record: %w", err)
+	}
+	// 升级前只存储答案数据，没有类型和过期时间；仍在 Store 中说明没有过期，类型以验证请求为准
+	if record.Type == "" {
+		return &captchaRecord{
+			Type:     legacyType,
+			ExpireAt: time.Now().Add(legacyRecordTTL).UnixMilli(),
+			Data:     json.RawMessage(value),
+		}, nil
+	}
+	return &record, nil
+}
+
+// verify 根据类型验证答案，返回验证结果原因
+func (g *generatorSet) verify(captchaType CaptchaType, data []byte, answer interface{}) (VerifyReason, error) {
+	var (
+		valid bool
+		err   error
+	)
+	switch captchaType {
 	case CaptchaTypeCharacter:
-		return s.verifyCharacter(value, req.Answer)
+		valid, err = g.verifyCharacter(data, answer)
 	case CaptchaTypeImageSelect:
-		return s.verifyImageSelect(value, req.Answer)
+		valid, err = g.verifyImageSelect(data, answer)
 	case SlideTypeSelect:
-		return s.verifySlide(value, req.Answer)
+		return g.verifySlide(data, answer)
+	case CaptchaTypeImageGrid:
+		valid, err = g.verifyImageGrid(data, answer)
+	case CaptchaTypePow:
+		valid, err = g.verifyPow(data, answer)
 	default:
-		return false, ErrCaptchaTypeNotSupported
+		return "", ErrCaptchaTypeNotSupported
 	}
-}
-
-// VerifyAndDelete 验证并删除验证码
-func (s *Service) VerifyAndDelete(ctx context.Context, req *VerifyRequest) (bool, error) {
-	valid, err := s.Verify(ctx, req)
 	if err != nil {
-		return false, err
-	}
-
-	if valid {
-		// 验证成功，删除验证码
-		_ = s.store.Del(ctx, req.CaptchaID)
+		return "", err
 	}
+	return verifyReason(valid), nil
+}
 
-	return valid, nil
+// VerifyAndDelete 验证并删除验证码，等同于 VerifyDetailed 只返回是否通过
+func (s *Service) VerifyAndDelete(ctx context.Context, req *VerifyRequest) (bool, error) {
+	result, err := s.VerifyDetailed(ctx, req)
+	return result.OK, err
 }
 
 // verifyCharacter 验证字符验证码
-func (s *Service) verifyCharacter(value string, answer interface{}) (bool, error) {
+func (g *generatorSet) verifyCharacter(value []byte, answer interface{}) (bool, error) {
 	// 解析存储的数据
 	var data CharacterData
-	err := json.Unmarshal([]byte(value), &data)
+	err := json.Unmarshal(value, &data)
 	if err != nil {
 		return false, fmt.Errorf("failed to unmarshal captcha data: %w", err)
 	}
@@ -173,15 +647,14 @@ func (s *Service) verifyCharacter(value string, answer interface{}) (bool, error
 	}
 
 	// 验证
-	charCaptcha := NewCharacterCaptcha(s.characterConfig)
-	return charCaptcha.Verify(data.Code, answerData.Code), nil
+	return g.character.Verify(data.Code, answerData.Code), nil
 }
 
 // verifyImageSelect 验证图片选择验证码
-func (s *Service) verifyImageSelect(value string, answer interface{}) (bool, error) {
+func (g *generatorSet) verifyImageSelect(value []byte, answer interface{}) (bool, error) {
 	// 解析存储的数据
 	var data ImageSelectData
-	err := json.Unmarshal([]byte(value), &data)
+	err := json.Unmarshal(value, &data)
 	if err != nil {
 		return false, fmt.Errorf("failed to unmarshal captcha data: %w", err)
 	}
@@ -199,15 +672,39 @@ func (s *Service) verifyImageSelect(value string, answer interface{}) (bool, err
 	}
 
 	// 验证
-	imageCaptcha := NewImageSelectCaptcha(s.imageSelectConfig)
-	return imageCaptcha.Verify(data.TargetIndexes, answerData.SelectedIndexes), nil
+	return g.imageSelect.Verify(data.TargetIndexes, answerData.SelectedIndexes), nil
 }
 
-// verifySlide 验证滑动验证码
-func (s *Service) verifySlide(value string, answer interface{}) (bool, error) {
+// verifySlide 验证滑动验证码，位置正确但轨迹异常时返回 VerifyReasonTrajectorySuspicious
+func (g *generatorSet) verifySlide(value []byte, answer interface{}) (VerifyReason, error) {
 	// 解析存储的数据
 	var data SlideData
-	err := json.Unmarshal([]byte(value), &data)
+	err := json.Unmarshal(value, &data)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal captcha data: %w", err)
+	}
+
+	// 解析答案
+	answerBytes, err := json.Marshal(answer)
+	if err != nil {
+		return "", ErrCaptchaAnswerFormatWrong
+	}
+
+	var answerData SlideAnswer
+	err = json.Unmarshal(answerBytes, &answerData)
+	if err != nil {
+		return "", ErrCaptchaAnswerFormatWrong
+	}
+
+	// 验证
+	return g.slide.Check(data.TargetX, answerData), nil
+}
+
+// verifyImageGrid 验证宫格图片选择验证码
+func (g *generatorSet) verifyImageGrid(value []byte, answer interface{}) (bool, error) {
+	// 解析存储的数据
+	var data ImageGridData
+	err := json.Unmarshal(value, &data)
 	if err != nil {
 		return false, fmt.Errorf("failed to unmarshal captcha data: %w", err)
 	}
@@ -218,15 +715,65 @@ func (s *Service) verifySlide(value string, answer interface{}) (bool, error) {
 		return false, ErrCaptchaAnswerFormatWrong
 	}
 
-	var answerData SlideAnswer
+	var answerData ImageGridAnswer
+	err = json.Unmarshal(answerBytes, &answerData)
+	if err != nil {
+		return false, ErrCaptchaAnswerFormatWrong
+	}
+
+	// 验证
+	return g.imageGrid.Verify(data.RequiredTiles, data.OptionalTiles, answerData.SelectedTiles), nil
+}
+
+// verifyPow 验证工作量证明验证码，难度以生成时记录的为准
+func (g *generatorSet) verifyPow(value []byte, answer interface{}) (bool, error) {
+	// 解析存储的数据
+	var data PowData
+	err := json.Unmarshal(value, &data)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmarshal captcha data: %w", err)
+	}
+
+	// 解析答案
+	answerBytes, err := json.Marshal(answer)
+	if err != nil {
+		return false, ErrCaptchaAnswerFormatWrong
+	}
+
+	var answerData PowAnswer
 	err = json.Unmarshal(answerBytes, &answerData)
 	if err != nil {
 		return false, ErrCaptchaAnswerFormatWrong
 	}
 
 	// 验证
-	slideCaptcha := NewSlideCaptcha(s.slideConfig)
-	return slideCaptcha.Verify(data.TargetX, answerData), nil
+	return g.pow.Verify(data.Prefix, data.Difficulty, answerData.Nonce), nil
+}
+
+// expiredRetention 验证码过期后记录在 Store 中的保留时间，期间验证返回 ErrCaptchaExpired
+const expiredRetention = 10 * time.Minute
+
+// legacyRecordTTL 旧格式记录的最长剩余有效期（升级前的默认过期时间），用于计算作废标记和验证次数的保留时间
+const legacyRecordTTL = 5 * time.Minute
+
+// captchaRecord 存储中的验证码记录，Data 为各类型的答案数据
+type captchaRecord struct {
+	Type      CaptchaType     `json:"type"`             // 验证码类型
+	Scene     string          `json:"scene,omitempty"`  // 场景名，不属于任何场景时为空
+	CreatedAt int64           `json:"createdAt"`        // 生成时间（毫秒时间戳）
+	ExpireAt  int64           `json:"expireAt"`         // 过期时间（毫秒时间戳）
+	Client    *clientBinding  `json:"client,omitempty"` // 开启客户端绑定时生成验证码的请求方
+	Data      json.RawMessage `json:"data"`             // 答案数据
+}
+
+// expired 验证码是否已过期
+func (r *captchaRecord) expired(now time.Time) bool {
+	return now.UnixMilli() >= r.ExpireAt
+}
+
+// storeTTL 记录在 Store 中的剩余保留时间，包含过期后的保留时间
+func (r *captchaRecord) storeTTL() time.Duration {
+	return time.Until(time.UnixMilli(r.ExpireAt)) + expiredRetention
 }
 
 // CharacterData 字符验证码存储数据
@@ -237,6 +784,7 @@ type CharacterData struct {
 // ImageSelectData 图片选择验证码存储数据
 type ImageSelectData struct {
 	TargetIndexes []int  `json:"targetIndexes"` // 正确答案的索引
+	TargetType    string `json:"targetType"`    // 目标类别
 	Question      string `json:"question"`      // 问题
 }
 
@@ -244,3 +792,16 @@ type ImageSelectData struct {
 type SlideData struct {
 	TargetX int `json:"targetX"` // 正确的X坐标
 }
+
+// ImageGridData 宫格图片选择验证码存储数据
+type ImageGridData struct {
+	RequiredTiles []int  `json:"requiredTiles"` // 必须选中的方格索引
+	OptionalTiles []int  `json:"optionalTiles"` // 可选的方格索引（目标边缘）
+	Question      string `json:"question"`      // 问题
+}
+
+// PowData 工作量证明验证码存储数据
+type PowData struct {
+	Prefix     string `json:"prefix"`     // 随机前缀
+	Difficulty int    `json:"difficulty"` // 哈希前导 0 的位数
+}
@@ -1,107 +1,163 @@
 package captcha
 
 import (
-	"bytes"
-	"encoding/base64"
-	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
 	"math"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/zeromicro/go-zero/core/logx"
+	xdraw "golang.org/x/image/draw"
 )
 
 // SlideCaptcha 滑动验证码
 type SlideCaptcha struct {
-	config SlideConfig
+	config          SlideConfig
+	encoder         Encoder // 背景图编码器
+	templateEncoder Encoder // 滑块模板编码器，需要保留透明通道
+	rng             *rand.Rand
+
+	assets     *AssetLibrary
+	assetsOnce sync.Once
 }
 
+// slideMask 滑块形状，返回模板坐标 (x, y) 是否属于滑块
+type slideMask func(x, y int) bool
+
 // NewSlideCaptcha 创建滑动验证码
 func NewSlideCaptcha(config SlideConfig) *SlideCaptcha {
-	// 设置默认值
-	if config.Width == 0 {
-		config.Width = 350
+	config = config.withDefaults()
+
+	return &SlideCaptcha{
+		config:          config,
+		encoder:         NewEncoder(config.Encode),
+		templateEncoder: newTransparentEncoder(config.Encode),
+		rng:             newRand(config.Rand),
+	}
+}
+
+// withDefaults 填充零值字段的默认值
+func (c SlideConfig) withDefaults() SlideConfig {
+	if c.Width == 0 {
+		c.Width = 350
 	}
-	if config.Height == 0 {
-		config.Height = 200
+	if c.Height == 0 {
+		c.Height = 200
 	}
-	if config.TemplateWidth == 0 {
-		config.TemplateWidth = 60
+	if c.TemplateWidth == 0 {
+		c.TemplateWidth = 60
 	}
-	if config.TemplateHeight == 0 {
-		config.TemplateHeight = 60
+	if c.TemplateHeight == 0 {
+		c.TemplateHeight = 60
 	}
-	if config.ExpireTime == 0 {
-		config.ExpireTime = 5 * time.Minute
+	if c.ExpireTime == 0 {
+		c.ExpireTime = 5 * time.Minute
 	}
+	return c
+}
 
-	return &SlideCaptcha{
-		config: config,
-	}
+// WithAssetLibrary 使用共享的图片资源库，不设置时首次生成验证码时扫描 ImageDir/TemplateDir 创建私有资源库
+func (c *SlideCaptcha) WithAssetLibrary(assets *AssetLibrary) *SlideCaptcha {
+	c.assets = assets
+	return c
 }
 
-// Generate 生成验证码
+// assetLibrary 返回图片资源库
+func (c *SlideCaptcha) assetLibrary() *AssetLibrary {
+	c.assetsOnce.Do(func() {
+		if c.assets == nil {
+			c.assets = NewAssetLibrary(AssetLibraryConfig{}, c.config.ImageDir, c.config.TemplateDir)
+		}
+	})
+	return c.assets
+}
+
+// Generate 生成验证码，返回 data URI 格式的背景图和滑块模板、滑块 Y 坐标（未使用）和缺口 X 坐标
 func (c *SlideCaptcha) Generate() (string, string, string, int, error) {
-	rand.Seed(time.Now().UnixNano())
+	background, template, targetX, err := c.generate()
+	if err != nil {
+		return "", "", "", 0, err
+	}
+	return background.dataURI(), template.dataURI(), "", targetX, nil
+}
 
+// generate 生成验证码，返回编码后的背景图、滑块模板和缺口 X 坐标
+func (c *SlideCaptcha) generate() (*Asset, *Asset, int, error) {
 	// 1. 创建背景图
 	backgroundImg := c.createBackgroundImage()
 
 	// 2. 创建滑块模板
-	templateImg, templateY := c.createTemplateImage()
+	mask := c.templateMask()
+	templateImg, templateY := c.createTemplateImage(mask)
 
 	// 3. 在背景图上生成缺口（挖空滑块位置）
 	captchaX := c.randomPosition(c.config.Width - c.config.TemplateWidth)
-	backgroundWithHole := c.cutHole(backgroundImg, captchaX, templateY, c.config.TemplateWidth, c.config.TemplateHeight)
+	backgroundWithHole := c.cutHole(backgroundImg, mask, captchaX, templateY, c.config.TemplateWidth, c.config.TemplateHeight)
 
-	// 4. 编码为Base64
-	backgroundBase64, err := c.encodeImageToBase64(backgroundWithHole)
+	// 4. 编码
+	background, err := encodeImage(c.encoder, backgroundWithHole)
 	if err != nil {
-		return "", "", "", 0, err
+		return nil, nil, 0, err
 	}
 
-	templateBase64, err := c.encodeImageToBase64(templateImg)
+	template, err := encodeImage(c.templateEncoder, templateImg)
 	if err != nil {
-		return "", "", "", 0, err
+		return nil, nil, 0, err
 	}
 
-	return backgroundBase64, templateBase64, "", captchaX, nil
+	return background, template, captchaX, nil
 }
 
 // Verify 验证验证码
 func (c *SlideCaptcha) Verify(targetX int, answer SlideAnswer) bool {
+	return c.Check(targetX, answer) == VerifyReasonOK
+}
+
+// Check 验证验证码并返回原因，位置正确但轨迹或耗时异常时返回 VerifyReasonTrajectorySuspicious
+func (c *SlideCaptcha) Check(targetX int, answer SlideAnswer) VerifyReason {
 	// 允许误差范围
 	tolerance := 5 // 像素
 
 	// 检查X坐标是否在允许范围内
 	diff := math.Abs(float64(targetX - answer.X))
 	if diff > float64(tolerance) {
-		return false
+		return VerifyReasonWrongAnswer
 	}
 
 	// 检查滑动轨迹（简单验证：轨迹点数量）
 	if len(answer.Track) < 10 {
-		return false
+		return VerifyReasonTrajectorySuspicious
 	}
 
 	// 检查滑动时间（简单验证：不能太快也不能太慢）
 	minDuration := int64(500)   // 0.5秒
 	maxDuration := int64(10000) // 10秒
 	if answer.Duration < minDuration || answer.Duration > maxDuration {
-		return false
+		return VerifyReasonTrajectorySuspicious
 	}
 
-	return true
+	return VerifyReasonOK
 }
 
-// createBackgroundImage 创建背景图
+// createBackgroundImage 创建背景图：优先从 ImageDir 随机选一张图片，没有时生成渐变背景
 func (c *SlideCaptcha) createBackgroundImage() *image.RGBA {
 	img := image.NewRGBA(image.Rect(0, 0, c.config.Width, c.config.Height))
 
+	if c.config.ImageDir != "" {
+		assets := c.assetLibrary()
+		if backgrounds := assets.Images(c.config.ImageDir); len(backgrounds) > 0 {
+			background, err := assets.Image(backgrounds[c.rng.Intn(len(backgrounds))])
+			if err == nil {
+				drawCover(img, background)
+				return img
+			}
+			logx.Errorf("加载背景图片失败: %v", err)
+		}
+	}
+
 	// 填充渐变背景
 	for y := 0; y < c.config.Height; y++ {
 		for x := 0; x < c.config.Width; x++ {
@@ -114,12 +170,12 @@ func (c *SlideCaptcha) createBackgroundImage() *image.RGBA {
 
 	// 添加一些随机噪点
 	for i := 0; i < 100; i++ {
-		x := rand.Intn(c.config.Width)
-		y := rand.Intn(c.config.Height)
+		x := c.rng.Intn(c.config.Width)
+		y := c.rng.Intn(c.config.Height)
 		img.Set(x, y, color.RGBA{
-			R: uint8(rand.Intn(256)),
-			G: uint8(rand.Intn(256)),
-			B: uint8(rand.Intn(256)),
+			R: uint8(c.rng.Intn(256)),
+			G: uint8(c.rng.Intn(256)),
+			B: uint8(c.rng.Intn(256)),
 			A: 255,
 		})
 	}
@@ -127,24 +183,42 @@ func (c *SlideCaptcha) createBackgroundImage() *image.RGBA {
 	return img
 }
 
+// templateMask 滑块形状：优先从 TemplateDir 随机选一张模板图片（按透明度取形状），没有时使用圆形
+func (c *SlideCaptcha) templateMask() slideMask {
+	width, height := c.config.TemplateWidth, c.config.TemplateHeight
+
+	if c.config.TemplateDir != "" {
+		assets := c.assetLibrary()
+		if templates := assets.Images(c.config.TemplateDir); len(templates) > 0 {
+			template, err := assets.Image(templates[c.rng.Intn(len(templates))])
+			if err == nil {
+				scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+				xdraw.BiLinear.Scale(scaled, scaled.Bounds(), template, template.Bounds(), xdraw.Src, nil)
+				return func(x, y int) bool {
+					return scaled.RGBAAt(x, y).A >= 128
+				}
+			}
+			logx.Errorf("加载滑块模板失败: %v", err)
+		}
+	}
+
+	// 圆形滑块
+	return func(x, y int) bool {
+		dx := x - width/2
+		dy := y - height/2
+		return math.Sqrt(float64(dx*dx+dy*dy)) <= float64(width/2)
+	}
+}
+
 // createTemplateImage 创建滑块模板
-func (c *SlideCaptcha) createTemplateImage() (*image.RGBA, int) {
-	templateY := rand.Intn(c.config.Height-c.config.TemplateHeight-20) + 10
+func (c *SlideCaptcha) createTemplateImage(mask slideMask) (*image.RGBA, int) {
+	templateY := c.rng.Intn(c.config.Height-c.config.TemplateHeight-20) + 10
 	img := image.NewRGBA(image.Rect(0, 0, c.config.TemplateWidth, c.config.TemplateHeight))
 
 	// 创建半透明的滑块
 	for y := 0; y < c.config.TemplateHeight; y++ {
 		for x := 0; x < c.config.TemplateWidth; x++ {
-			// 创建圆形滑块
-			centerX := c.config.TemplateWidth / 2
-			centerY := c.config.TemplateHeight / 2
-			radius := c.config.TemplateWidth / 2
-
-			dx := x - centerX
-			dy := y - centerY
-			distance := math.Sqrt(float64(dx*dx + dy*dy))
-
-			if distance <= float64(radius) {
+			if mask(x, y) {
 				// 滑块内部：半透明白色
 				img.Set(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 200})
 			} else {
@@ -161,22 +235,14 @@ func (c *SlideCaptcha) createTemplateImage() (*image.RGBA, int) {
 }
 
 // cutHole 在背景图上挖空滑块位置
-func (c *SlideCaptcha) cutHole(background *image.RGBA, x, y, width, height int) *image.RGBA {
+func (c *SlideCaptcha) cutHole(background *image.RGBA, mask slideMask, x, y, width, height int) *image.RGBA {
 	result := image.NewRGBA(background.Bounds())
 	draw.Draw(result, result.Bounds(), background, image.Point{}, draw.Src)
 
 	// 在指定位置绘制透明区域（模拟缺口）
 	for py := 0; py < height; py++ {
 		for px := 0; px < width; px++ {
-			centerX := width / 2
-			centerY := height / 2
-			radius := width / 2
-
-			dx := px - centerX
-			dy := py - centerY
-			distance := math.Sqrt(float64(dx*dx + dy*dy))
-
-			if distance <= float64(radius) {
+			if mask(px, py) {
 				// 设置为半透明黑色
 				if x+px < c.config.Width && y+py < c.config.Height {
 					result.Set(x+px, y+py, color.RGBA{R: 50, G: 50, B: 50, A: 150})
@@ -188,6 +254,21 @@ func (c *SlideCaptcha) cutHole(background *image.RGBA, x, y, width, height int)
 	return result
 }
 
+// drawCover 将图片等比缩放并居中裁剪，铺满目标图片
+func drawCover(dst *image.RGBA, src image.Image) {
+	srcBounds := src.Bounds()
+	dstW, dstH := dst.Bounds().Dx(), dst.Bounds().Dy()
+
+	scale := math.Max(float64(dstW)/float64(srcBounds.Dx()), float64(dstH)/float64(srcBounds.Dy()))
+	cropW := int(float64(dstW) / scale)
+	cropH := int(float64(dstH) / scale)
+	offsetX := srcBounds.Min.X + (srcBounds.Dx()-cropW)/2
+	offsetY := srcBounds.Min.Y + (srcBounds.Dy()-cropH)/2
+
+	crop := image.Rect(offsetX, offsetY, offsetX+cropW, offsetY+cropH)
+	xdraw.BiLinear.Scale(dst, dst.Bounds(), src, crop, xdraw.Src, nil)
+}
+
 // addBorder 添加边框
 func (c *SlideCaptcha) addBorder(img *image.RGBA, borderColor color.RGBA) {
 	width := img.Bounds().Dx()
@@ -211,19 +292,7 @@ func (c *SlideCaptcha) randomPosition(max int) int {
 	// 在30%-70%范围内随机
 	min := int(float64(max) * 0.3)
 	maxPos := int(float64(max) * 0.7)
-	return min + rand.Intn(maxPos-min)
-}
-
-// encodeImageToBase64 将图片编码为Base64
-func (c *SlideCaptcha) encodeImageToBase64(img *image.RGBA) (string, error) {
-	var buf bytes.Buffer
-	err := png.Encode(&buf, img)
-	if err != nil {
-		return "", fmt.Errorf("failed to encode image: %w", err)
-	}
-
-	base64Str := base64.StdEncoding.EncodeToString(buf.Bytes())
-	return fmt.Sprintf("data:image/png;base64,%s", base64Str), nil
+	return min + c.rng.Intn(maxPos-min)
 }
 
 // init 初始化
@@ -3,19 +3,79 @@ package captcha
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-// Store 验证码存储接口
+// Store 验证码存储接口，Get 在 key 不存在或已过期时返回 ErrCaptchaNotFound
 type Store interface {
 	Set(ctx context.Context, captchaID string, data interface{}, expireTime time.Duration) error
 	Get(ctx context.Context, captchaID string) (string, error)
 	Del(ctx context.Context, captchaID string) error
 }
 
+// AtomicStore 支持原子操作的存储，RedisStore 和 MemStore 都实现了该接口
+//
+// 未实现时读取并删除退化为先 Get 再 Del，计数退化为先 Get 再 Set，并发时可能重复通过或计数偏少。
+type AtomicStore interface {
+	Store
+	// GetDel 原子地读取并删除，并发调用时只有一个能读到值，其他返回 ErrCaptchaNotFound
+	GetDel(ctx context.Context, captchaID string) (string, error)
+	// Incr 原子地把计数加一并把过期时间重置为 expireTime，返回加一后的值，key 不存在时从 0 开始
+	Incr(ctx context.Context, key string, expireTime time.Duration) (int64, error)
+}
+
+// BytesStore 支持直接存取字节的存储，RedisStore 和 MemStore 都实现了该接口
+//
+// 以图片地址下发时，图片原始字节通过该接口保存；未实现时图片以 JSON 保存。
+type BytesStore interface {
+	Store
+	// SetBytes 原样保存 value，不做 JSON 编码
+	SetBytes(ctx context.Context, key string, value []byte, expireTime time.Duration) error
+	// GetBytes 读取 SetBytes 保存的值，key 不存在或已过期时返回 ErrCaptchaNotFound
+	GetBytes(ctx context.Context, key string) ([]byte, error)
+}
+
+// getDel 读取并删除，Store 未实现 AtomicStore 时先读后删
+func getDel(ctx context.Context, store Store, key string) (string, error) {
+	if atomicStore, ok := store.(AtomicStore); ok {
+		return atomicStore.GetDel(ctx, key)
+	}
+
+	value, err := store.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if err := store.Del(ctx, key); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// incr 计数加一，Store 未实现 AtomicStore 时先读后写
+func incr(ctx context.Context, store Store, key string, expireTime time.Duration) (int64, error) {
+	if atomicStore, ok := store.(AtomicStore); ok {
+		return atomicStore.Incr(ctx, key, expireTime)
+	}
+
+	var n int64
+	if value, err := store.Get(ctx, key); err == nil {
+		n, _ = strconv.ParseInt(value, 10, 64)
+	} else if !errors.Is(err, ErrCaptchaNotFound) {
+		return 0, err
+	}
+	n++
+	if err := store.Set(ctx, key, n, expireTime); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
 // RedisStore Redis 存储
 type RedisStore struct {
 	client *redis.Client
@@ -55,6 +115,9 @@ func (s *RedisStore) Get(ctx context.Context, captchaID string) (string, error)
 	key := s.prefix + captchaID
 
 	value, err := s.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrCaptchaNotFound
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to get captcha: %w", err)
 	}
@@ -74,15 +137,79 @@ func (s *RedisStore) Del(ctx context.Context, captchaID string) error {
 	return nil
 }
 
-// MemStore 内存存储（用于测试）
+// GetDel 读取并删除验证码，需要 Redis 6.2 及以上
+func (s *RedisStore) GetDel(ctx context.Context, captchaID string) (string, error) {
+	key := s.prefix + captchaID
+
+	value, err := s.client.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrCaptchaNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get and delete captcha: %w", err)
+	}
+
+	return value, nil
+}
+
+// SetBytes 原样保存字节
+func (s *RedisStore) SetBytes(ctx context.Context, key string, value []byte, expireTime time.Duration) error {
+	if err := s.client.Set(ctx, s.prefix+key, value, expireTime).Err(); err != nil {
+		return fmt.Errorf("failed to set bytes: %w", err)
+	}
+	return nil
+}
+
+// GetBytes 读取字节
+func (s *RedisStore) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCaptchaNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bytes: %w", err)
+	}
+	return value, nil
+}
+
+// Incr 原子自增计数，在同一个事务中重置过期时间
+func (s *RedisStore) Incr(ctx context.Context, key string, expireTime time.Duration) (int64, error) {
+	key = s.prefix + key
+
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.PExpire(ctx, key, expireTime)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+
+	return incr.Val(), nil
+}
+
+// MemStore 内存存储（用于测试和单实例部署）
 type MemStore struct {
-	data map[string]string
+	mu        sync.Mutex
+	data      map[string]memEntry
+	lastSweep time.Time
+}
+
+// memEntry 内存存储的值和过期时间
+type memEntry struct {
+	value    string
+	expireAt time.Time
 }
 
+// memSweepInterval 清理过期 key 的最小间隔
+const memSweepInterval = time.Minute
+
 // NewMemStore 创建内存存储
 func NewMemStore() *MemStore {
 	return &MemStore{
-		data: make(map[string]string),
+		data:      make(map[string]memEntry),
+		lastSweep: time.Now(),
 	}
 }
 
@@ -93,28 +220,98 @@ func (s *MemStore) Set(ctx context.Context, captchaID string, data interface{},
 		return fmt.Errorf("failed to marshal captcha data: %w", err)
 	}
 
-	s.data[captchaID] = string(value)
-
-	// 自动过期
-	go func() {
-		time.Sleep(expireTime)
-		delete(s.data, captchaID)
-	}()
+	s.put(captchaID, string(value), expireTime)
+	return nil
+}
 
+// SetBytes 原样保存字节
+func (s *MemStore) SetBytes(ctx context.Context, key string, value []byte, expireTime time.Duration) error {
+	s.put(key, string(value), expireTime)
 	return nil
 }
 
+// put 保存值并定期清理过期的 key
+func (s *MemStore) put(key, value string, expireTime time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.data[key] = memEntry{
+		value:    value,
+		expireAt: now.Add(expireTime),
+	}
+
+	// 过期的 key 在读取时忽略，写入时定期清理，不为每个 key 启动定时器
+	if now.Sub(s.lastSweep) >= memSweepInterval {
+		for key, entry := range s.data {
+			if !now.Before(entry.expireAt) {
+				delete(s.data, key)
+			}
+		}
+		s.lastSweep = now
+	}
+}
+
 // Get 获取验证码
 func (s *MemStore) Get(ctx context.Context, captchaID string) (string, error) {
-	value, ok := s.data[captchaID]
-	if !ok {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.data[captchaID]
+	if !ok || !time.Now().Before(entry.expireAt) {
 		return "", ErrCaptchaNotFound
 	}
-	return value, nil
+	return entry.value, nil
+}
+
+// GetBytes 读取字节
+func (s *MemStore) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
 }
 
 // Del 删除验证码
 func (s *MemStore) Del(ctx context.Context, captchaID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	delete(s.data, captchaID)
 	return nil
 }
+
+// GetDel 读取并删除验证码
+func (s *MemStore) GetDel(ctx context.Context, captchaID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.data[captchaID]
+	if !ok || !time.Now().Before(entry.expireAt) {
+		return "", ErrCaptchaNotFound
+	}
+	delete(s.data, captchaID)
+	return entry.value, nil
+}
+
+// Incr 原子自增计数
+func (s *MemStore) Incr(ctx context.Context, key string, expireTime time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	now := time.Now()
+	if entry, ok := s.data[key]; ok && now.Before(entry.expireAt) {
+		var err error
+		if n, err = strconv.ParseInt(entry.value, 10, 64); err != nil {
+			return 0, fmt.Errorf("failed to increment counter: value is not an integer")
+		}
+	}
+	n++
+	s.data[key] = memEntry{
+		value:    strconv.FormatInt(n, 10),
+		expireAt: now.Add(expireTime),
+	}
+	return n, nil
+}
@@ -0,0 +1,148 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisStore 创建连接到 miniredis 的 Redis 存储
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client, "captcha:"), server
+}
+
+// plainStore 只实现 Store 的存储，模拟未实现 AtomicStore 的自定义存储
+type plainStore struct {
+	store Store
+}
+
+func (s plainStore) Set(ctx context.Context, key string, data interface{}, expireTime time.Duration) error {
+	return s.store.Set(ctx, key, data, expireTime)
+}
+
+func (s plainStore) Get(ctx context.Context, key string) (string, error) {
+	return s.store.Get(ctx, key)
+}
+
+func (s plainStore) Del(ctx context.Context, key string) error {
+	return s.store.Del(ctx, key)
+}
+
+// TestStoreFallback 未实现 AtomicStore 时先读后写
+func TestStoreFallback(t *testing.T) {
+	store := plainStore{store: NewMemStore()}
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		if got, err := incr(ctx, store, "counter", time.Minute); err != nil || got != want {
+			t.Fatalf("incr() = %d, %v, want %d", got, err, want)
+		}
+	}
+
+	if err := store.Set(ctx, "key", 42, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := getDel(ctx, store, "key"); err != nil || value != "42" {
+		t.Fatalf("getDel() = %q, %v, want 42", value, err)
+	}
+	if _, err := getDel(ctx, store, "key"); !errors.Is(err, ErrCaptchaNotFound) {
+		t.Errorf("getDel() after delete error = %v, want ErrCaptchaNotFound", err)
+	}
+}
+
+// TestStoreIncr 并发自增不丢失计数，计数和 Get 读到的值一致
+func TestStoreIncr(t *testing.T) {
+	redisStore, _ := newTestRedisStore(t)
+	stores := map[string]AtomicStore{
+		"memory": NewMemStore(),
+		"redis":  redisStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			const n = 50
+
+			var wg sync.WaitGroup
+			wg.Add(n)
+			for i := 0; i < n; i++ {
+				go func() {
+					defer wg.Done()
+					if _, err := store.Incr(ctx, "counter", time.Minute); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			value, err := store.Get(ctx, "counter")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != "50" {
+				t.Errorf("counter = %s, want 50", value)
+			}
+			if got, err := store.Incr(ctx, "counter", time.Minute); err != nil || got != n+1 {
+				t.Errorf("Incr() = %d, %v, want %d", got, err, n+1)
+			}
+		})
+	}
+}
+
+// TestRedisStoreIncrExpire 自增重置过期时间，过期后从 0 开始
+func TestRedisStoreIncrExpire(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Incr(ctx, "counter", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.TTL("captcha:counter"); ttl != time.Minute {
+		t.Errorf("TTL = %s, want 1m", ttl)
+	}
+
+	server.FastForward(time.Minute)
+	if got, err := store.Incr(ctx, "counter", time.Minute); err != nil || got != 1 {
+		t.Errorf("Incr() after expire = %d, %v, want 1", got, err)
+	}
+}
+
+// TestRedisStoreNotFound 不存在的 key（redis.Nil）返回 ErrCaptchaNotFound，连接出错时返回其他错误
+func TestRedisStoreNotFound(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrCaptchaNotFound) {
+		t.Errorf("Get() error = %v, want ErrCaptchaNotFound", err)
+	}
+	if _, err := store.GetDel(ctx, "missing"); !errors.Is(err, ErrCaptchaNotFound) {
+		t.Errorf("GetDel() error = %v, want ErrCaptchaNotFound", err)
+	}
+	if _, err := store.GetBytes(ctx, "missing"); !errors.Is(err, ErrCaptchaNotFound) {
+		t.Errorf("GetBytes() error = %v, want ErrCaptchaNotFound", err)
+	}
+
+	if err := store.Set(ctx, "key", "value", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	server.FastForward(time.Minute)
+	if _, err := store.Get(ctx, "key"); !errors.Is(err, ErrCaptchaNotFound) {
+		t.Errorf("Get() after expire error = %v, want ErrCaptchaNotFound", err)
+	}
+
+	// 连接不上的 Redis
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer client.Close()
+	if _, err := NewRedisStore(client, "").Get(ctx, "key"); err == nil || errors.Is(err, ErrCaptchaNotFound) {
+		t.Errorf("Get() with server down error = %v, want a connection error", err)
+	}
+}
@@ -1,6 +1,9 @@
 package captcha
 
-import "time"
+import (
+	"math/rand"
+	"time"
+)
 
 // CaptchaType 验证码类型
 type CaptchaType string
@@ -9,9 +12,22 @@ const (
 	CaptchaTypeCharacter   CaptchaType = "character"    // 字符验证码
 	CaptchaTypeImageSelect CaptchaType = "image_select" // 图片选择验证码
 	SlideTypeSelect        CaptchaType = "slide"        // 滑动验证码
+	CaptchaTypeImageGrid   CaptchaType = "image_grid"   // 宫格图片选择验证码
+	CaptchaTypePow         CaptchaType = "pow"          // 工作量证明验证码
 )
 
+// valid 是否为支持的验证码类型
+func (t CaptchaType) valid() bool {
+	switch t {
+	case CaptchaTypeCharacter, CaptchaTypeImageSelect, SlideTypeSelect, CaptchaTypeImageGrid, CaptchaTypePow:
+		return true
+	}
+	return false
+}
+
 // CaptchaConfig 验证码配置
+//
+// Deprecated: 使用 Conf 和 NewServiceFromConf。
 type CaptchaConfig struct {
 	// Redis 配置
 	RedisAddr     string
@@ -27,39 +43,70 @@ type CaptchaConfig struct {
 
 // CharacterConfig 字符验证码配置
 type CharacterConfig struct {
-	Width      int           // 图片宽度
-	Height     int           // 图片高度
-	Length     int           // 验证码长度
-	ExpireTime time.Duration // 过期时间
-	Complexity int           // 复杂度: 1-简单, 2-中等, 3-复杂
+	Width      int           `json:",default=160"`             // 图片宽度
+	Height     int           `json:",default=60"`              // 图片高度
+	Length     int           `json:",default=4"`               // 验证码长度
+	ExpireTime time.Duration `json:",default=5m"`              // 过期时间
+	Complexity int           `json:",default=2,options=1|2|3"` // 复杂度: 1-简单, 2-中等, 3-复杂
+	Encode     EncodeConfig  `json:",optional"`                // 图片编码格式
+	Rand       rand.Source   `json:"-"`                        // 随机数源，为空时使用 crypto/rand；测试时可用 NewSeededSource 固定输出
 }
 
 // ImageSelectConfig 图片选择验证码配置
 type ImageSelectConfig struct {
-	ImageCount  int           // 选项图片数量
-	SelectCount int           // 需要选择的数量
-	ExpireTime  time.Duration // 过期时间
-	Category    string        // 图片类别: traffic(交通), animal(动物), food(食物)等
-	ImageDir    string        // 图片文件目录路径
+	ImageCount     int           `json:",default=4"`  // 选项图片数量
+	SelectCount    int           `json:",default=1"`  // 需要选择的数量（固定数量模式）
+	MinSelectCount int           `json:",optional"`   // 目标数量下限（"选择所有符合的图片"模式），默认 1
+	MaxSelectCount int           `json:",optional"`   // 目标数量上限，大于0时每题目标数量在范围内随机且不告知前端
+	ExpireTime     time.Duration `json:",default=5m"` // 过期时间
+	Category       string        `json:",optional"`   // 图片类别: traffic(交通), animal(动物), food(食物)等，对应清单中的 group
+	ImageDir       string        `json:",optional"`   // 图片文件目录路径，也可以直接指向清单文件
+	Manifest       string        `json:",optional"`   // 数据集清单文件路径（json/yaml），为空时在 ImageDir 下查找 manifest.json/manifest.yaml
+	Language       string        `json:",default=zh"` // 问题文本语言，默认 zh
+	Perturb        PerturbConfig `json:",optional"`   // 下发图片前的随机扰动
+	Encode         EncodeConfig  `json:",optional"`   // 图片编码格式
+	Rand           rand.Source   `json:"-"`           // 随机数源，为空时使用 crypto/rand
 }
 
 // SlideConfig 滑动验证码配置
 type SlideConfig struct {
-	Width          int           // 背景图宽度
-	Height         int           // 背景图高度
-	TemplateWidth  int           // 滑块模板宽度
-	TemplateHeight int           // 滑块模板高度
-	ExpireTime     time.Duration // 过期时间
-	ImageDir       string        // 背景图片目录路径
-	TemplateDir    string        // 滑块模板目录路径
+	Width          int           `json:",default=350"` // 背景图宽度
+	Height         int           `json:",default=200"` // 背景图高度
+	TemplateWidth  int           `json:",default=60"`  // 滑块模板宽度
+	TemplateHeight int           `json:",default=60"`  // 滑块模板高度
+	ExpireTime     time.Duration `json:",default=5m"`  // 过期时间
+	ImageDir       string        `json:",optional"`    // 背景图片目录路径
+	TemplateDir    string        `json:",optional"`    // 滑块模板目录路径
+	Encode         EncodeConfig  `json:",optional"`    // 图片编码格式，JPEG 只用于背景图，滑块模板需要透明通道仍使用 PNG
+	Rand           rand.Source   `json:"-"`            // 随机数源，为空时使用 crypto/rand
+}
+
+// ImageGridConfig 宫格图片选择验证码配置
+type ImageGridConfig struct {
+	GridSize   int           `json:",default=3,options=3|4"` // 宫格边长: 3(3×3) 或 4(4×4)
+	Width      int           `json:",default=300"`           // 输出图片宽度
+	Height     int           `json:",default=300"`           // 输出图片高度
+	ExpireTime time.Duration `json:",default=5m"`            // 过期时间
+	ImageDir   string        `json:",optional"`              // 场景图片目录路径（图片与同名 .json 标注文件放在一起）
+	MinOverlap float64       `json:",default=0.2"`           // 目标覆盖方格面积的比例达到该值时必须选中（0-1）
+	Encode     EncodeConfig  `json:",optional"`              // 图片编码格式
+	Rand       rand.Source   `json:"-"`                      // 随机数源，为空时使用 crypto/rand
+}
+
+// PowConfig 工作量证明验证码配置
+type PowConfig struct {
+	Difficulty int           `json:",default=16"` // 难度，哈希前导 0 的位数，每加 1 前端计算量翻倍
+	ExpireTime time.Duration `json:",default=5m"` // 过期时间
+	Rand       rand.Source   `json:"-"`           // 随机数源，为空时使用 crypto/rand
 }
 
 // CaptchaResponse 验证码响应
 type CaptchaResponse struct {
-	CaptchaID   string      `json:"captchaId"`   // 验证码ID
-	CaptchaType CaptchaType `json:"captchaType"` // 验证码类型
-	Data        interface{} `json:"data"`        // 验证码数据
-	ExpireTime  int64       `json:"expireTime"`  // 过期时间戳
+	CaptchaID   string      `json:"captchaId"`       // 验证码ID
+	CaptchaType CaptchaType `json:"captchaType"`     // 验证码类型
+	Scene       string      `json:"scene,omitempty"` // 场景名，验证时需要原样带回
+	Data        interface{} `json:"data"`            // 验证码数据
+	ExpireTime  int64       `json:"expireTime"`      // 过期时间戳
 }
 
 // CharacterCaptchaData 字符验证码数据
@@ -69,17 +116,19 @@ type CharacterCaptchaData struct {
 
 // ImageSelectCaptchaData 图片选择验证码数据
 type ImageSelectCaptchaData struct {
-	Question    string   `json:"question"`    // 问题：如"请选择所有的公交车"
-	TargetType  string   `json:"targetType"`  // 目标类型
-	Images      []string `json:"images"`      // 图片列表（Base64）
-	SelectCount int      `json:"selectCount"` // 需要选择的数量
+	Question    string   `json:"question"`              // 问题：如"请选择所有的公交车"
+	TargetType  string   `json:"targetType"`            // 目标类型
+	Images      []string `json:"images"`                // 图片列表（Base64）
+	SelectCount int      `json:"selectCount,omitempty"` // 需要选择的数量，目标数量不公开时为空
 }
 
 // VerifyRequest 验证请求
 type VerifyRequest struct {
-	CaptchaID   string      `json:"captchaId"`   // 验证码ID
-	CaptchaType CaptchaType `json:"captchaType"` // 验证码类型
-	Answer      interface{} `json:"answer"`      // 答案
+	CaptchaID   string      `json:"captchaId"`       // 验证码ID
+	CaptchaType CaptchaType `json:"captchaType"`     // 验证码类型
+	Scene       string      `json:"scene,omitempty"` // 场景名，必须与生成时一致
+	Answer      interface{} `json:"answer"`          // 答案
+	Honeypot    string      `json:"-"`               // 蜜罐字段的值，前端对用户隐藏，不为空时视为机器提交
 }
 
 // CharacterAnswer 字符验证码答案
@@ -92,6 +141,19 @@ type ImageSelectAnswer struct {
 	SelectedIndexes []int `json:"selectedIndexes"` // 选中的图片索引
 }
 
+// ImageGridCaptchaData 宫格图片选择验证码数据
+type ImageGridCaptchaData struct {
+	Question   string `json:"question"`   // 问题：如"请选择所有包含公交车的方格"
+	TargetType string `json:"targetType"` // 目标类型
+	Image      string `json:"image"`      // 场景图片（Base64），前端按 GridSize 切分
+	GridSize   int    `json:"gridSize"`   // 宫格边长
+}
+
+// ImageGridAnswer 宫格图片选择验证码答案
+type ImageGridAnswer struct {
+	SelectedTiles []int `json:"selectedTiles"` // 选中的方格索引（行优先，从0开始）
+}
+
 // SlideCaptchaData 滑动验证码数据
 type SlideCaptchaData struct {
 	BackgroundImage string `json:"backgroundImage"` // 背景图片（Base64）
@@ -107,3 +169,15 @@ type SlideAnswer struct {
 	Track    []int `json:"track"`    // 滑动轨迹
 	Duration int64 `json:"duration"` // 滑动耗时（毫秒）
 }
+
+// PowCaptchaData 工作量证明验证码数据
+type PowCaptchaData struct {
+	Prefix     string `json:"prefix"`     // 随机前缀
+	Difficulty int    `json:"difficulty"` // 哈希前导 0 的位数
+	Algorithm  string `json:"algorithm"`  // 哈希算法，固定为 SHA-256
+}
+
+// PowAnswer 工作量证明验证码答案
+type PowAnswer struct {
+	Nonce string `json:"nonce"` // 使 SHA-256(prefix + nonce) 满足难度的字符串，1 到 64 位十六进制字符，十进制计数也满足
+}
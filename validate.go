@@ -0,0 +1,197 @@
+package captcha
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate 校验字符验证码配置，零值字段按默认值校验
+func (c CharacterConfig) Validate() error {
+	c = c.withDefaults()
+
+	var errs []error
+	if c.Width < 0 || c.Height < 0 || c.Length < 0 {
+		errs = append(errs, fmt.Errorf("width, height and length must not be negative"))
+	}
+	if c.Length > 0 && (c.Width-20)/c.Length < 20 {
+		errs = append(errs, fmt.Errorf("width %d is too small for %d characters, need at least %d", c.Width, c.Length, 20+20*c.Length))
+	}
+	if c.Height < 40 {
+		errs = append(errs, fmt.Errorf("height %d is too small, need at least 40", c.Height))
+	}
+	if c.Complexity < 1 || c.Complexity > 3 {
+		errs = append(errs, fmt.Errorf("complexity must be 1, 2 or 3, got %d", c.Complexity))
+	}
+	if c.ExpireTime < 0 {
+		errs = append(errs, fmt.Errorf("expire time must not be negative"))
+	}
+	if err := c.Encode.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验图片选择验证码配置，零值字段按默认值校验
+func (c ImageSelectConfig) Validate() error {
+	c = c.withDefaults()
+
+	var errs []error
+	if c.ImageCount < 2 {
+		errs = append(errs, fmt.Errorf("image count must be at least 2, got %d", c.ImageCount))
+	}
+	if c.MaxSelectCount > 0 {
+		if c.MinSelectCount < 1 || c.MinSelectCount > c.MaxSelectCount {
+			errs = append(errs, fmt.Errorf("min select count %d must be between 1 and max select count %d", c.MinSelectCount, c.MaxSelectCount))
+		}
+		if c.MaxSelectCount >= c.ImageCount {
+			errs = append(errs, fmt.Errorf("max select count %d must be less than image count %d", c.MaxSelectCount, c.ImageCount))
+		}
+	} else if c.SelectCount < 1 || c.SelectCount >= c.ImageCount {
+		errs = append(errs, fmt.Errorf("select count %d must be between 1 and image count - 1 (%d)", c.SelectCount, c.ImageCount-1))
+	}
+	if c.ExpireTime < 0 {
+		errs = append(errs, fmt.Errorf("expire time must not be negative"))
+	}
+	if err := c.validateManifest(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Perturb.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Encode.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验滑动验证码配置，零值字段按默认值校验
+func (c SlideConfig) Validate() error {
+	c = c.withDefaults()
+
+	var errs []error
+	if c.TemplateWidth <= 0 || c.TemplateHeight <= 0 {
+		errs = append(errs, fmt.Errorf("template size must be positive, got %dx%d", c.TemplateWidth, c.TemplateHeight))
+	}
+	// 滑块 X 在 (Width-TemplateWidth) 的 30%-70% 范围内随机，需要留出足够的滑动距离
+	if c.Width-c.TemplateWidth < 10 {
+		errs = append(errs, fmt.Errorf("width %d must be at least template width %d + 10", c.Width, c.TemplateWidth))
+	}
+	// 滑块 Y 上下各留 10 像素边距
+	if c.Height <= c.TemplateHeight+20 {
+		errs = append(errs, fmt.Errorf("height %d must be greater than template height %d + 20", c.Height, c.TemplateHeight))
+	}
+	if c.ExpireTime < 0 {
+		errs = append(errs, fmt.Errorf("expire time must not be negative"))
+	}
+	if err := c.Encode.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验宫格图片选择验证码配置，零值字段按默认值校验
+func (c ImageGridConfig) Validate() error {
+	c = c.withDefaults()
+
+	var errs []error
+	if c.GridSize != 3 && c.GridSize != 4 {
+		errs = append(errs, fmt.Errorf("grid size must be 3 or 4, got %d", c.GridSize))
+	}
+	if c.Width < c.GridSize*20 || c.Height < c.GridSize*20 {
+		errs = append(errs, fmt.Errorf("image size %dx%d is too small for a %dx%d grid", c.Width, c.Height, c.GridSize, c.GridSize))
+	}
+	if c.MinOverlap <= 0 || c.MinOverlap > 1 {
+		errs = append(errs, fmt.Errorf("min overlap must be in (0, 1], got %g", c.MinOverlap))
+	}
+	if c.ExpireTime < 0 {
+		errs = append(errs, fmt.Errorf("expire time must not be negative"))
+	}
+	if err := c.Encode.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验工作量证明验证码配置，零值字段按默认值校验
+func (c PowConfig) Validate() error {
+	c = c.withDefaults()
+
+	var errs []error
+	// 超过 32 位时普通设备需要数分钟以上
+	if c.Difficulty < 1 || c.Difficulty > 32 {
+		errs = append(errs, fmt.Errorf("pow: difficulty must be in [1, 32], got %d", c.Difficulty))
+	}
+	if c.ExpireTime < 0 {
+		errs = append(errs, fmt.Errorf("expire time must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验图片扰动配置
+func (c PerturbConfig) Validate() error {
+	var errs []error
+	if c.CropRatio < 0 || c.CropRatio > 0.5 {
+		errs = append(errs, fmt.Errorf("perturb: crop ratio must be in [0, 0.5], got %g", c.CropRatio))
+	}
+	if c.ScaleMax < 0 || c.ScaleMin < 0 || (c.ScaleMin > 0 && c.ScaleMin > c.ScaleMax) {
+		errs = append(errs, fmt.Errorf("perturb: invalid scale range [%g, %g]", c.ScaleMin, c.ScaleMax))
+	}
+	if c.HueShift < 0 || c.HueShift > 180 {
+		errs = append(errs, fmt.Errorf("perturb: hue shift must be in [0, 180], got %g", c.HueShift))
+	}
+	if c.Noise < 0 || c.Noise > 255 {
+		errs = append(errs, fmt.Errorf("perturb: noise must be in [0, 255], got %d", c.Noise))
+	}
+	if c.JPEGQuality < 0 || c.JPEGQuality > 100 {
+		errs = append(errs, fmt.Errorf("perturb: jpeg quality must be in [0, 100], got %d", c.JPEGQuality))
+	}
+	if c.MaxRotate < 0 || c.MaxRotate > 45 {
+		errs = append(errs, fmt.Errorf("perturb: max rotate must be in [0, 45], got %g", c.MaxRotate))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验图片编码配置
+func (c EncodeConfig) Validate() error {
+	var errs []error
+	switch c.Format {
+	case "", ImageFormatPNG, ImageFormatJPEG, ImageFormatGIF:
+	default:
+		errs = append(errs, fmt.Errorf("encode: unknown format %q", c.Format))
+	}
+	switch c.Compression {
+	case "", PNGCompressionDefault, PNGCompressionNone, PNGCompressionSpeed, PNGCompressionBest:
+	default:
+		errs = append(errs, fmt.Errorf("encode: unknown png compression %q", c.Compression))
+	}
+	if c.Quality < 0 || c.Quality > 100 {
+		errs = append(errs, fmt.Errorf("encode: jpeg quality must be in [0, 100], got %d", c.Quality))
+	}
+	if c.Colors != 0 && (c.Colors < 2 || c.Colors > 256) {
+		errs = append(errs, fmt.Errorf("encode: gif colors must be in [2, 256], got %d", c.Colors))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate 校验验证码池配置
+func (c PoolConfig) Validate() error {
+	if c.Size < 0 || c.Workers < 0 || c.MaxAge < 0 {
+		return fmt.Errorf("pool: size, workers and max age must not be negative")
+	}
+	return nil
+}
+
+// Validate 校验图片地址下发配置
+func (c AssetURLConfig) Validate() error {
+	var errs []error
+	if c.BaseURL == "" {
+		errs = append(errs, fmt.Errorf("asset url: base url is required"))
+	}
+	if c.SigningKey == "" {
+		errs = append(errs, fmt.Errorf("asset url: signing key is required"))
+	}
+	if c.TTL < 0 {
+		errs = append(errs, fmt.Errorf("asset url: ttl must not be negative"))
+	}
+	return errors.Join(errs...)
+}
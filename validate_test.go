@@ -0,0 +1,129 @@
+package captcha
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNewServiceValidate 每种配置的非法取值都会让 NewService 返回对应的错误
+func TestNewServiceValidate(t *testing.T) {
+	slideScene := WithScene("low", SceneConfig{Type: SlideTypeSelect})
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    string // 错误信息中应包含的内容，为空表示配置合法
+	}{
+		{"默认配置", nil, ""},
+		{"未知默认类型", []Option{WithDefaultType("audio")}, "invalid default type"},
+
+		{"字符 宽度为负", []Option{WithCharacterConfig(CharacterConfig{Width: -1})}, "must not be negative"},
+		{"字符 宽度不足", []Option{WithCharacterConfig(CharacterConfig{Width: 100, Length: 6})}, "too small for 6 characters"},
+		{"字符 高度不足", []Option{WithCharacterConfig(CharacterConfig{Height: 30})}, "height 30 is too small"},
+		{"字符 复杂度越界", []Option{WithCharacterConfig(CharacterConfig{Complexity: 4})}, "complexity must be 1, 2 or 3"},
+		{"字符 过期时间为负", []Option{WithCharacterConfig(CharacterConfig{ExpireTime: -time.Second})}, "invalid character config: expire time"},
+		{"字符 编码格式未知", []Option{WithCharacterConfig(CharacterConfig{Encode: EncodeConfig{Format: "webp"}})}, `unknown format "webp"`},
+
+		{"图片选择 图片数不足", []Option{WithImageSelectConfig(ImageSelectConfig{ImageCount: 1})}, "image count must be at least 2"},
+		{"图片选择 选择数过多", []Option{WithImageSelectConfig(ImageSelectConfig{ImageCount: 4, SelectCount: 4})}, "select count 4 must be between"},
+		{"图片选择 最少数大于最多数", []Option{WithImageSelectConfig(ImageSelectConfig{MinSelectCount: 3, MaxSelectCount: 2})}, "min select count 3"},
+		{"图片选择 最多数不少于图片数", []Option{WithImageSelectConfig(ImageSelectConfig{ImageCount: 4, MaxSelectCount: 4})}, "max select count 4 must be less than"},
+		{"图片选择 清单不存在", []Option{WithImageSelectConfig(ImageSelectConfig{Manifest: "/nonexistent/manifest.json"})}, "manifest"},
+		{"图片选择 扰动裁剪越界", []Option{WithImageSelectConfig(ImageSelectConfig{Perturb: PerturbConfig{CropRatio: 0.6}})}, "crop ratio"},
+		{"图片选择 扰动缩放区间反转", []Option{WithImageSelectConfig(ImageSelectConfig{Perturb: PerturbConfig{ScaleMin: 1.2, ScaleMax: 0.8}})}, "invalid scale range"},
+		{"图片选择 扰动色相越界", []Option{WithImageSelectConfig(ImageSelectConfig{Perturb: PerturbConfig{HueShift: 200}})}, "hue shift"},
+		{"图片选择 扰动噪声越界", []Option{WithImageSelectConfig(ImageSelectConfig{Perturb: PerturbConfig{Noise: 300}})}, "noise"},
+		{"图片选择 扰动质量越界", []Option{WithImageSelectConfig(ImageSelectConfig{Perturb: PerturbConfig{JPEGQuality: 101}})}, "perturb: jpeg quality"},
+		{"图片选择 扰动旋转越界", []Option{WithImageSelectConfig(ImageSelectConfig{Perturb: PerturbConfig{MaxRotate: 90}})}, "max rotate"},
+
+		{"滑动 模板尺寸为负", []Option{WithSlideConfig(SlideConfig{TemplateWidth: -1})}, "template size must be positive"},
+		{"滑动 宽度不足", []Option{WithSlideConfig(SlideConfig{Width: 60, TemplateWidth: 55})}, "width 60 must be at least"},
+		{"滑动 高度不足", []Option{WithSlideConfig(SlideConfig{Height: 70, TemplateHeight: 50})}, "height 70 must be greater"},
+		{"滑动 编码质量越界", []Option{WithSlideConfig(SlideConfig{Encode: EncodeConfig{Quality: 101}})}, "encode: jpeg quality"},
+
+		{"宫格 尺寸不支持", []Option{WithImageGridConfig(ImageGridConfig{GridSize: 5})}, "grid size must be 3 or 4"},
+		{"宫格 图片过小", []Option{WithImageGridConfig(ImageGridConfig{Width: 50, Height: 50})}, "too small for a 3x3 grid"},
+		{"宫格 重叠比例越界", []Option{WithImageGridConfig(ImageGridConfig{MinOverlap: 1.5})}, "min overlap"},
+		{"宫格 压缩级别未知", []Option{WithImageGridConfig(ImageGridConfig{Encode: EncodeConfig{Compression: "max"}})}, "unknown png compression"},
+		{"宫格 GIF 颜色数越界", []Option{WithImageGridConfig(ImageGridConfig{Encode: EncodeConfig{Colors: 1}})}, "gif colors"},
+
+		{"工作量证明 难度越界", []Option{WithPowConfig(PowConfig{Difficulty: 33})}, "difficulty must be in [1, 32]"},
+		{"工作量证明 过期时间为负", []Option{WithPowConfig(PowConfig{ExpireTime: -time.Second})}, "invalid pow config: expire time"},
+
+		{"图片地址 缺少地址和密钥", []Option{WithAssetURLs(AssetURLConfig{})}, "base url is required"},
+		{"图片地址 缺少密钥", []Option{WithAssetURLs(AssetURLConfig{BaseURL: "/asset/"})}, "signing key is required"},
+		{"图片地址 有效期为负", []Option{WithAssetURLs(AssetURLConfig{BaseURL: "/asset/", SigningKey: "k", TTL: -time.Second})}, "ttl must not be negative"},
+
+		{"验证码池 数量为负", []Option{WithPool(CaptchaTypeCharacter, PoolConfig{Size: -1})}, "invalid character pool config"},
+
+		{"场景 名称为空", []Option{WithScene("", SceneConfig{Type: CaptchaTypeCharacter})}, "scene name is required"},
+		{"场景 类型未知", []Option{WithScene("login", SceneConfig{Type: "audio"})}, `invalid scene "login"`},
+		{"场景 次数为负", []Option{WithScene("login", SceneConfig{Type: CaptchaTypePow, MaxAttempts: -1})}, "max attempts must not be negative"},
+		{"场景 作答时间为负", []Option{WithScene("login", SceneConfig{Type: CaptchaTypePow, MinSolveTime: -time.Second})}, "min solve time must not be negative"},
+		{"场景 类型配置非法", []Option{WithScene("login", SceneConfig{Type: CaptchaTypePow, Pow: PowConfig{Difficulty: 40}})}, "difficulty"},
+
+		{"绑定 IPv4 前缀越界", []Option{WithClientBinding(ClientBindingConfig{IPv4Prefix: 33})}, "ipv4 prefix"},
+		{"绑定 IPv6 前缀越界", []Option{WithClientBinding(ClientBindingConfig{IPv6Prefix: -2})}, "ipv6 prefix"},
+
+		{"人机校验 作答时间为负", []Option{WithHumanCheck(HumanCheckConfig{MinSolveTime: -time.Second})}, "human check: min solve time"},
+		{"人机校验 动作未知", []Option{WithHumanCheck(HumanCheckConfig{Action: "drop"})}, `unknown action "drop"`},
+
+		{"观察者 缓冲为负", []Option{WithObserverConfig(ObserverConfig{BufferSize: -1})}, "buffer size"},
+		{"观察者 为 nil", []Option{WithObserver(nil)}, "observer must not be nil"},
+
+		{"限流 次数为负", []Option{WithRateLimit(RateLimitConfig{Generate: RateLimit{Limit: -1}})}, "rate limit"},
+
+		{"无感验证 阈值越界", []Option{WithInvisible(InvisibleConfig{Threshold: 1.5})}, "threshold must be in [0, 1]"},
+		{"无感验证 凭证有效期为负", []Option{WithInvisible(InvisibleConfig{TicketTTL: -time.Second})}, "ticket ttl"},
+		{"无感验证 回退类型未知", []Option{WithInvisible(InvisibleConfig{FallbackType: "audio"})}, "invisible"},
+		{"无感验证 回退场景不存在", []Option{WithInvisible(InvisibleConfig{FallbackScene: "missing"})}, `invisible: scene "missing" not found`},
+
+		{"风险 缺少区间", []Option{WithRiskConfig(RiskConfig{})}, "at least one band is required"},
+		{"风险 失败次数为负", []Option{slideScene, WithRiskConfig(RiskConfig{MaxFailures: -1, Bands: []RiskBand{{Scene: "low"}}})}, "max failures must not be negative"},
+		{"风险 分数越界", []Option{slideScene, WithRiskConfig(RiskConfig{Bands: []RiskBand{{MinScore: 2, Scene: "low"}}})}, "band 0 min score"},
+		{"风险 区间缺少场景", []Option{WithRiskConfig(RiskConfig{Bands: []RiskBand{{}}})}, "band 0 scene is required"},
+		{"风险 场景不存在", []Option{WithRiskConfig(RiskConfig{Bands: []RiskBand{{Scene: "missing"}}})}, `risk: scene "missing" not found`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewService(NewMemStore(), tt.options...)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("NewService() error = %v", err)
+				}
+				service.Close()
+				return
+			}
+			if err == nil {
+				service.Close()
+				t.Fatalf("NewService() error = nil, want %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewService() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewServiceValidateJoinsErrors 多个配置错误同时返回，不支持的类型可以用 errors.Is 判断
+func TestNewServiceValidateJoinsErrors(t *testing.T) {
+	_, err := NewService(NewMemStore(),
+		WithDefaultType("audio"),
+		WithCharacterConfig(CharacterConfig{Complexity: 4}),
+		WithPowConfig(PowConfig{Difficulty: 33}),
+	)
+	if err == nil {
+		t.Fatal("NewService() error = nil")
+	}
+	for _, want := range []string{"invalid default type", "complexity", "difficulty"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if !errors.Is(err, ErrCaptchaTypeNotSupported) {
+		t.Errorf("errors.Is(err, ErrCaptchaTypeNotSupported) = false")
+	}
+}
@@ -0,0 +1,293 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// VerifyReason 验证结果原因
+type VerifyReason string
+
+const (
+	VerifyReasonOK                   VerifyReason = "ok"                    // 验证通过
+	VerifyReasonWrongAnswer          VerifyReason = "wrong_answer"          // 答案错误
+	VerifyReasonInvalidAnswer        VerifyReason = "invalid_answer"        // 答案格式错误
+	VerifyReasonNotFound             VerifyReason = "not_found"             // 验证码不存在
+	VerifyReasonExpired              VerifyReason = "expired"               // 验证码已过期
+	VerifyReasonReplayed             VerifyReason = "replayed"              // 验证码已经验证通过并使用过
+	VerifyReasonTooManyAttempts      VerifyReason = "too_many_attempts"     // 失败次数达到上限，验证码已作废
+	VerifyReasonTypeMismatch         VerifyReason = "type_mismatch"         // 验证码类型与生成时不一致
+	VerifyReasonSceneMismatch        VerifyReason = "scene_mismatch"        // 场景与生成时不一致
+	VerifyReasonClientMismatch       VerifyReason = "client_mismatch"       // 请求方与生成时不一致
+	VerifyReasonRiskMismatch         VerifyReason = "risk_mismatch"         // 场景低于请求方当前风险要求的区间
+	VerifyReasonTooFast              VerifyReason = "too_fast"              // 作答时间短于最短作答时间
+	VerifyReasonHoneypot             VerifyReason = "honeypot"              // 蜜罐字段被填写
+	VerifyReasonTrajectorySuspicious VerifyReason = "trajectory_suspicious" // 位置正确但滑动轨迹或耗时异常
+	VerifyReasonRateLimited          VerifyReason = "rate_limited"          // 验证请求过于频繁
+)
+
+// VerifyResult 验证结果
+type VerifyResult struct {
+	OK                bool           `json:"ok"`                // 是否验证通过
+	Reason            VerifyReason   `json:"reason"`            // 原因，通过时为 ok
+	Flags             []VerifyReason `json:"flags,omitempty"`   // HumanCheckConfig.Action 为 flag 时记录的疑似机器提交原因，不影响 OK
+	RiskScore         float64        `json:"riskScore"`         // 验证后请求方的风险分，未开启自适应难度时为 0
+	AttemptsRemaining int            `json:"attemptsRemaining"` // 剩余可验证次数，-1 表示不限制
+	CaptchaType       CaptchaType    `json:"captchaType"`       // 生成时的验证码类型，验证码不存在时为空
+	Scene             string         `json:"scene,omitempty"`   // 生成时的场景名
+}
+
+// verifyMarker 验证码作废后留下的标记，用于区分重放、失败次数用尽和从未存在
+type verifyMarker struct {
+	Reason      VerifyReason `json:"reason"`
+	CaptchaType CaptchaType  `json:"type"`
+	Scene       string       `json:"scene,omitempty"`
+}
+
+// VerifyDetailed 验证验证码并返回详细结果，验证通过后验证码作废，再次提交返回 VerifyReasonReplayed
+//
+// 未通过时 VerifyResult.Reason 总是说明原因。答错、重放、失败次数用尽、类型或请求方不一致等只通过 Reason 返回，error 为 nil；
+// 以下情况在 Reason 之外同时返回错误，便于调用方直接映射为 HTTP 状态码：被限流时为 VerifyReasonRateLimited 和 ErrRateLimited，
+// 验证码已过期时为 VerifyReasonExpired 和 ErrCaptchaExpired，答案格式错误时为 VerifyReasonInvalidAnswer 和 ErrCaptchaAnswerFormatWrong。
+// Store 出错时只返回错误，Reason 可能为空。
+func (s *Service) VerifyDetailed(ctx context.Context, req *VerifyRequest) (*VerifyResult, error) {
+	return s.verify(ctx, req, true)
+}
+
+// verify 验证验证码并分发验证事件，consume 为 true 时验证通过后作废
+func (s *Service) verify(ctx context.Context, req *VerifyRequest, consume bool) (*VerifyResult, error) {
+	result, record, err := s.verifyCaptcha(ctx, req, consume)
+
+	// 被限流时已分发限流事件，Store 出错时没有验证结果
+	if s.events == nil || result.Reason == "" || result.Reason == VerifyReasonRateLimited {
+		return result, err
+	}
+	// 观察者异步处理，传入副本避免调用方修改结果
+	meta, _ := RequestMetaFromContext(ctx)
+	copied := *result
+	event := Event{
+		Kind:        EventVerify,
+		CaptchaID:   req.CaptchaID,
+		CaptchaType: result.CaptchaType,
+		Scene:       result.Scene,
+		Meta:        meta,
+		Result:      &copied,
+	}
+	if result.Reason == VerifyReasonExpired {
+		event.Kind = EventExpire
+	}
+	// 旧格式的记录没有生成时间
+	if record != nil && record.CreatedAt > 0 {
+		event.SolveTime = time.Since(time.UnixMilli(record.CreatedAt))
+	}
+	s.emit(event)
+
+	return result, err
+}
+
+// verifyCaptcha 验证验证码，验证码不存在时返回的记录为空
+func (s *Service) verifyCaptcha(ctx context.Context, req *VerifyRequest, consume bool) (*VerifyResult, *captchaRecord, error) {
+	result := &VerifyResult{AttemptsRemaining: -1}
+	if err := s.checkRate(ctx, rateActionVerify); err != nil {
+		result.Reason = VerifyReasonRateLimited
+		return result, nil, err
+	}
+
+	// 不是服务生成的ID格式时不查询 Store，避免读到其他类型的数据
+	if !validCaptchaID(req.CaptchaID) {
+		result.Reason = VerifyReasonNotFound
+		return result, nil, nil
+	}
+
+	// 获取存储的验证码数据
+	record, err := s.loadRecord(ctx, req.CaptchaID, req.CaptchaType)
+	if errors.Is(err, ErrCaptchaNotFound) {
+		return s.verifyMissing(ctx, req.CaptchaID, result), nil, nil
+	}
+	if err != nil {
+		return result, nil, err
+	}
+	result.CaptchaType, result.Scene = record.Type, record.Scene
+
+	// 过期的记录会多保留一段时间，用于提示用户刷新
+	if record.expired(time.Now()) {
+		result.Reason = VerifyReasonExpired
+		return result, record, ErrCaptchaExpired
+	}
+	if record.Type != req.CaptchaType {
+		logx.Infof("验证码类型不匹配: %s, 生成时 %s, 验证时 %s", req.CaptchaID, record.Type, req.CaptchaType)
+		result.Reason = VerifyReasonTypeMismatch
+		return result, record, nil
+	}
+	if record.Scene != req.Scene {
+		logx.Infof("验证码场景不匹配: %s, 生成时 %q, 验证时 %q", req.CaptchaID, record.Scene, req.Scene)
+		result.Reason = VerifyReasonSceneMismatch
+		return result, record, nil
+	}
+
+	meta, _ := RequestMetaFromContext(ctx)
+	// 风险区间的场景由客户端选择，需要确认不低于当前风险要求的区间
+	if s.risk != nil && !s.risk.sceneAllowed(record.Scene, s.adaptiveScore(ctx)) {
+		logx.Infof("验证码场景低于风险要求: %s, 场景 %q", req.CaptchaID, record.Scene)
+		result.Reason = VerifyReasonRiskMismatch
+		return s.finish(ctx, meta, result), record, nil
+	}
+	if field := s.binding.mismatch(record.Client, meta); field != "" {
+		logx.Infof("验证码请求方不一致: %s, 字段 %s", req.CaptchaID, field)
+		result.Reason = VerifyReasonClientMismatch
+		return s.finish(ctx, meta, result), record, nil
+	}
+	if reason := s.suspect(record, req, time.Now()); reason != "" {
+		if s.humanCheck.Action == HumanCheckReject {
+			logx.Infof("疑似机器提交，验证失败: %s, 原因 %s", req.CaptchaID, reason)
+			result.Reason = reason
+			// 蜜罐字段被填写时验证码作废
+			if reason == VerifyReasonHoneypot {
+				s.discardQuietly(ctx, req.CaptchaID, record, reason)
+			}
+			return s.finish(ctx, meta, result), record, nil
+		}
+		logx.Infof("疑似机器提交: %s, 原因 %s", req.CaptchaID, reason)
+		result.Flags = append(result.Flags, reason)
+	}
+
+	// 场景使用自定义配置时用场景的生成器验证
+	generators, maxAttempts := s.generators, 0
+	if sc, ok := s.scenes[record.Scene]; ok {
+		generators, maxAttempts = sc.generators, sc.config.MaxAttempts
+	}
+
+	// 设置了 MaxAttempts 时先原子地占用一次验证次数，并发提交也不会超过上限
+	attempts := 0
+	if maxAttempts > 0 {
+		n, err := incr(ctx, s.store, attemptsKey(req.CaptchaID), record.storeTTL())
+		if err != nil {
+			return result, record, fmt.Errorf("failed to count captcha attempts: %w", err)
+		}
+		if attempts = int(n); attempts > maxAttempts {
+			result.Reason, result.AttemptsRemaining = VerifyReasonTooManyAttempts, 0
+			return s.finish(ctx, meta, result), record, nil
+		}
+	}
+
+	reason, err := generators.verify(record.Type, record.Data, req.Answer)
+	if err != nil && !errors.Is(err, ErrCaptchaAnswerFormatWrong) {
+		return result, record, err
+	}
+	result.Reason = reason
+	if err != nil {
+		result.Reason = VerifyReasonInvalidAnswer
+	}
+
+	if result.Reason == VerifyReasonOK {
+		if consume {
+			// 并发提交同一个验证码时只有一个请求能作废成功，其他请求视为重放
+			err := s.discard(ctx, req.CaptchaID, record, VerifyReasonReplayed)
+			if errors.Is(err, ErrCaptchaNotFound) {
+				logx.Infof("验证码重放: %s", req.CaptchaID)
+				result.Reason = VerifyReasonReplayed
+				return s.finish(ctx, meta, result), record, nil
+			}
+			if err != nil {
+				return result, record, err
+			}
+		}
+		result.OK = true
+		if len(result.Flags) > 0 {
+			return s.finish(ctx, meta, result), record, nil
+		}
+		result.RiskScore = s.riskScore(ctx)
+		return result, record, nil
+	}
+
+	// 失败次数达到上限后作废
+	if maxAttempts > 0 {
+		result.AttemptsRemaining = maxAttempts - attempts
+		if attempts == maxAttempts {
+			s.discardQuietly(ctx, req.CaptchaID, record, VerifyReasonTooManyAttempts)
+		}
+	}
+
+	return s.finish(ctx, meta, result), record, err
+}
+
+// verifyMissing 验证码不存在时根据作废标记判断原因
+func (s *Service) verifyMissing(ctx context.Context, captchaID string, result *VerifyResult) *VerifyResult {
+	result.Reason = VerifyReasonNotFound
+
+	value, err := s.store.Get(ctx, markerKey(captchaID))
+	if err != nil {
+		return result
+	}
+	var marker verifyMarker
+	if err := json.Unmarshal([]byte(value), &marker); err != nil {
+		logx.Errorf("failed to unmarshal captcha marker: %v", err)
+		return result
+	}
+
+	result.Reason, result.CaptchaType, result.Scene = marker.Reason, marker.CaptchaType, marker.Scene
+	switch marker.Reason {
+	case VerifyReasonReplayed:
+		logx.Infof("验证码重放: %s", captchaID)
+	case VerifyReasonTooManyAttempts:
+		result.AttemptsRemaining = 0
+	}
+	return result
+}
+
+// discard 原子地删除验证码并留下作废标记，标记与验证码记录同时失效
+//
+// 并发作废同一个验证码时只有一个请求成功，其他请求返回 ErrCaptchaNotFound。
+func (s *Service) discard(ctx context.Context, captchaID string, record *captchaRecord, reason VerifyReason) error {
+	if _, err := getDel(ctx, s.store, captchaID); err != nil {
+		if errors.Is(err, ErrCaptchaNotFound) {
+			return ErrCaptchaNotFound
+		}
+		return fmt.Errorf("failed to delete captcha: %w", err)
+	}
+
+	marker := verifyMarker{Reason: reason, CaptchaType: record.Type, Scene: record.Scene}
+	if err := s.store.Set(ctx, markerKey(captchaID), marker, record.storeTTL()); err != nil {
+		logx.Errorf("failed to store captcha marker: %v", err)
+	}
+	return nil
+}
+
+// discardQuietly 作废验证码，已被其他请求作废时忽略，出错时只记录日志
+func (s *Service) discardQuietly(ctx context.Context, captchaID string, record *captchaRecord, reason VerifyReason) {
+	if err := s.discard(ctx, captchaID, record, reason); err != nil && !errors.Is(err, ErrCaptchaNotFound) {
+		logx.Errorf("%v", err)
+	}
+}
+
+// finish 验证失败或被标记时计入失败次数，并填充风险分
+func (s *Service) finish(ctx context.Context, meta RequestMeta, result *VerifyResult) *VerifyResult {
+	if s.risk != nil {
+		s.recordFailure(ctx, meta)
+	}
+	result.RiskScore = s.riskScore(ctx)
+	return result
+}
+
+// riskScore 返回当前请求方的风险分，评估失败时返回 0
+func (s *Service) riskScore(ctx context.Context) float64 {
+	score, err := s.EvaluateRisk(ctx)
+	if err != nil {
+		logx.Errorf("风险评估失败: %v", err)
+		return 0
+	}
+	return score
+}
+
+// verifyReason 把验证结果转换为原因
+func verifyReason(valid bool) VerifyReason {
+	if valid {
+		return VerifyReasonOK
+	}
+	return VerifyReasonWrongAnswer
+}
@@ -0,0 +1,340 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newVerifyTestService 创建带场景的服务：limited 最多失败 2 次，short 有效期 30 毫秒
+func newVerifyTestService(t *testing.T, store Store, opts ...Option) *Service {
+	t.Helper()
+	opts = append([]Option{
+		WithScene("limited", SceneConfig{Type: CaptchaTypeCharacter, MaxAttempts: 2}),
+		WithScene("short", SceneConfig{Type: CaptchaTypeCharacter, ExpireTime: 30 * time.Millisecond}),
+	}, opts...)
+	service, err := NewService(store, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(service.Close)
+	return service
+}
+
+// testStores 内存和 Redis 存储，Redis 使用 miniredis
+func testStores(t *testing.T) map[string]Store {
+	redisStore, _ := newTestRedisStore(t)
+	return map[string]Store{
+		"memory": NewMemStore(),
+		"redis":  redisStore,
+	}
+}
+
+// TestVerifyDetailedReasons 各种验证失败的原因，未实现 AtomicStore 的存储结果相同
+func TestVerifyDetailedReasons(t *testing.T) {
+	clientA := RequestMeta{IP: "203.0.113.10"}
+	clientB := RequestMeta{IP: "198.51.100.10"}
+
+	tests := []struct {
+		name string
+		// verify 生成验证码后依次验证，返回最后一次的结果
+		verify  func(t *testing.T, s *Service, store Store) (*VerifyResult, error)
+		want    VerifyReason
+		wantErr error
+	}{
+		{
+			name: "验证通过",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				id := generate(t, s, "", clientA)
+				return s.VerifyDetailed(withMeta(clientA), characterRequest(t, store, id, ""))
+			},
+			want: VerifyReasonOK,
+		},
+		{
+			name: "答案错误",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				id := generate(t, s, "", clientA)
+				return s.VerifyDetailed(withMeta(clientA), wrongRequest(id, ""))
+			},
+			want: VerifyReasonWrongAnswer,
+		},
+		{
+			name: "重放",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				id := generate(t, s, "", clientA)
+				req := characterRequest(t, store, id, "")
+				if result, err := s.VerifyDetailed(withMeta(clientA), req); err != nil || !result.OK {
+					t.Fatalf("第一次验证 = %+v, %v", result, err)
+				}
+				return s.VerifyDetailed(withMeta(clientA), req)
+			},
+			want: VerifyReasonReplayed,
+		},
+		{
+			name: "失败次数用尽",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				id := generate(t, s, "limited", clientA)
+				req := characterRequest(t, store, id, "limited")
+				for i := 0; i < 2; i++ {
+					if result, err := s.VerifyDetailed(withMeta(clientA), wrongRequest(id, "limited")); err != nil || result.Reason != VerifyReasonWrongAnswer {
+						t.Fatalf("第 %d 次答错 = %+v, %v", i+1, result, err)
+					}
+				}
+				// 正确答案也不再接受
+				return s.VerifyDetailed(withMeta(clientA), req)
+			},
+			want: VerifyReasonTooManyAttempts,
+		},
+		{
+			name: "已过期",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				id := generate(t, s, "short", clientA)
+				req := characterRequest(t, store, id, "short")
+				time.Sleep(50 * time.Millisecond)
+				return s.VerifyDetailed(withMeta(clientA), req)
+			},
+			want:    VerifyReasonExpired,
+			wantErr: ErrCaptchaExpired,
+		},
+		{
+			name: "类型不一致",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				id := generate(t, s, "", clientA)
+				req := characterRequest(t, store, id, "")
+				req.CaptchaType = CaptchaTypePow
+				return s.VerifyDetailed(withMeta(clientA), req)
+			},
+			want: VerifyReasonTypeMismatch,
+		},
+		{
+			name: "场景不一致",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				id := generate(t, s, "limited", clientA)
+				return s.VerifyDetailed(withMeta(clientA), characterRequest(t, store, id, ""))
+			},
+			want: VerifyReasonSceneMismatch,
+		},
+		{
+			name: "请求方不一致",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				id := generate(t, s, "", clientA)
+				return s.VerifyDetailed(withMeta(clientB), characterRequest(t, store, id, ""))
+			},
+			want: VerifyReasonClientMismatch,
+		},
+		{
+			name: "不存在",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				return s.VerifyDetailed(withMeta(clientA), wrongRequest("6f1c2a8e-0000-4000-8000-000000000000", ""))
+			},
+			want: VerifyReasonNotFound,
+		},
+		{
+			name: "答案格式错误",
+			verify: func(t *testing.T, s *Service, store Store) (*VerifyResult, error) {
+				id := generate(t, s, "", clientA)
+				return s.VerifyDetailed(withMeta(clientA), &VerifyRequest{CaptchaID: id, CaptchaType: CaptchaTypeCharacter, Answer: 42})
+			},
+			want:    VerifyReasonInvalidAnswer,
+			wantErr: ErrCaptchaAnswerFormatWrong,
+		},
+	}
+
+	stores := testStores(t)
+	stores["plain"] = plainStore{store: NewMemStore()}
+	for name, store := range stores {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				s := newVerifyTestService(t, store, WithClientBinding(ClientBindingConfig{IP: true}))
+				result, err := tt.verify(t, s, store)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				if result.Reason != tt.want {
+					t.Errorf("Reason = %q, want %q", result.Reason, tt.want)
+				}
+				if result.OK != (tt.want == VerifyReasonOK) {
+					t.Errorf("OK = %v", result.OK)
+				}
+			})
+		}
+	}
+}
+
+// TestVerifyDetailedConcurrentSingleUse 并发提交正确答案时只有一个请求通过
+func TestVerifyDetailedConcurrentSingleUse(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := newVerifyTestService(t, store)
+			id := generate(t, s, "", RequestMeta{})
+			req := characterRequest(t, store, id, "")
+
+			results := verifyConcurrently(t, s, 20, func(int) *VerifyRequest { return req })
+			if results[VerifyReasonOK] != 1 {
+				t.Errorf("通过 %d 次, want 1: %v", results[VerifyReasonOK], results)
+			}
+			for reason := range results {
+				if reason != VerifyReasonOK && reason != VerifyReasonReplayed && reason != VerifyReasonNotFound {
+					t.Errorf("unexpected reason %q", reason)
+				}
+			}
+		})
+	}
+}
+
+// TestVerifyDetailedConcurrentMaxAttempts 并发提交时答案最多被检查 MaxAttempts 次
+func TestVerifyDetailedConcurrentMaxAttempts(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := newVerifyTestService(t, store)
+			id := generate(t, s, "limited", RequestMeta{})
+			correct := characterRequest(t, store, id, "limited")
+
+			// 除最后一个请求外都是错误答案
+			const n = 20
+			results := verifyConcurrently(t, s, n, func(i int) *VerifyRequest {
+				if i == n-1 {
+					return correct
+				}
+				return wrongRequest(id, "limited")
+			})
+			if checked := results[VerifyReasonWrongAnswer] + results[VerifyReasonOK]; checked > 2 {
+				t.Errorf("答案被检查了 %d 次, want <= 2: %v", checked, results)
+			}
+		})
+	}
+}
+
+// verifyConcurrently 并发验证 n 次，返回各原因的次数
+func verifyConcurrently(t *testing.T, s *Service, n int, request func(i int) *VerifyRequest) map[VerifyReason]int {
+	t.Helper()
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[VerifyReason]int)
+	)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(req *VerifyRequest) {
+			defer wg.Done()
+			result, err := s.VerifyDetailed(context.Background(), req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			results[result.Reason]++
+			mu.Unlock()
+		}(request(i))
+	}
+	wg.Wait()
+	return results
+}
+
+// generate 生成字符验证码，scene 不为空时按场景生成
+func generate(t *testing.T, s *Service, scene string, meta RequestMeta) string {
+	t.Helper()
+	var resp *CaptchaResponse
+	var err error
+	if scene == "" {
+		resp, err = s.Generate(withMeta(meta), CaptchaTypeCharacter)
+	} else {
+		resp, err = s.GenerateForScene(withMeta(meta), scene)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.CaptchaID
+}
+
+// characterRequest 带正确答案的验证请求
+func characterRequest(t *testing.T, store Store, captchaID, scene string) *VerifyRequest {
+	t.Helper()
+	return &VerifyRequest{
+		CaptchaID:   captchaID,
+		CaptchaType: CaptchaTypeCharacter,
+		Scene:       scene,
+		Answer:      CharacterAnswer{Code: characterCode(t, store, captchaID)},
+	}
+}
+
+// wrongRequest 带错误答案的验证请求，字符验证码不包含 "!"
+func wrongRequest(captchaID, scene string) *VerifyRequest {
+	return &VerifyRequest{
+		CaptchaID:   captchaID,
+		CaptchaType: CaptchaTypeCharacter,
+		Scene:       scene,
+		Answer:      CharacterAnswer{Code: "!!!!"},
+	}
+}
+
+// withMeta 带请求方信息的 context
+func withMeta(meta RequestMeta) context.Context {
+	return WithRequestMeta(context.Background(), meta)
+}
+
+// TestVerifyExpiredRecord Redis 中已过期的记录返回 ErrCaptchaExpired，超过保留时间后视为不存在
+func TestVerifyExpiredRecord(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	s := newVerifyTestService(t, store)
+	ctx := context.Background()
+
+	id := "6f1c2a8e-0000-4000-8000-000000000001"
+	now := time.Now()
+	record := captchaRecord{
+		Type:      CaptchaTypeCharacter,
+		CreatedAt: now.Add(-6 * time.Minute).UnixMilli(),
+		ExpireAt:  now.Add(-time.Minute).UnixMilli(),
+		Data:      []byte(`{"code":"ABCD"}`),
+	}
+	if err := store.Set(ctx, id, record, record.storeTTL()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &VerifyRequest{CaptchaID: id, CaptchaType: CaptchaTypeCharacter, Answer: CharacterAnswer{Code: "ABCD"}}
+	result, err := s.VerifyDetailed(ctx, req)
+	if !errors.Is(err, ErrCaptchaExpired) || result.Reason != VerifyReasonExpired || result.OK {
+		t.Fatalf("VerifyDetailed() = %+v, %v, want expired", result, err)
+	}
+	if ok, err := s.Verify(ctx, req); ok || !errors.Is(err, ErrCaptchaExpired) {
+		t.Errorf("Verify() = %v, %v, want ErrCaptchaExpired", ok, err)
+	}
+
+	server.FastForward(expiredRetention)
+	result, err = s.VerifyDetailed(ctx, req)
+	if err != nil || result.Reason != VerifyReasonNotFound {
+		t.Errorf("VerifyDetailed() after retention = %+v, %v, want not_found", result, err)
+	}
+}
+
+// TestVerifyLegacyRecord 升级前只存储答案数据的记录按验证请求的类型验证，不会被当作已过期
+func TestVerifyLegacyRecord(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s := newVerifyTestService(t, store)
+			ctx := context.Background()
+			id := "6f1c2a8e-0000-4000-8000-000000000002"
+			if err := store.Set(ctx, id, CharacterData{Code: "ABCD"}, 5*time.Minute); err != nil {
+				t.Fatal(err)
+			}
+
+			steps := []struct {
+				answer string
+				want   VerifyReason
+			}{
+				{"!!!!", VerifyReasonWrongAnswer},
+				{"abcd", VerifyReasonOK},
+				{"abcd", VerifyReasonReplayed},
+			}
+			for _, step := range steps {
+				req := &VerifyRequest{CaptchaID: id, CaptchaType: CaptchaTypeCharacter, Answer: CharacterAnswer{Code: step.answer}}
+				result, err := s.VerifyDetailed(ctx, req)
+				if err != nil || result.Reason != step.want {
+					t.Fatalf("VerifyDetailed(%q) = %+v, %v, want %s", step.answer, result, err, step.want)
+				}
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,17 +17,51 @@ import (
 
 type Handlers struct {
 	captchaService *captcha.Service
+	trustedProxies []*net.IPNet // 可信反向代理，只有来自这些地址的请求才读取 X-Forwarded-For
 }
 
-func NewHandlers(service *captcha.Service) *Handlers {
+// NewHandlers 创建处理器，trustedProxies 为可信反向代理的网段，为空时只使用连接的对端地址
+func NewHandlers(service *captcha.Service, trustedProxies []*net.IPNet) *Handlers {
 	return &Handlers{
 		captchaService: service,
+		trustedProxies: trustedProxies,
 	}
 }
 
+// parseTrustedProxies 解析逗号分隔的可信代理列表，支持 CIDR 和单个 IP
+func parseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
 // GenerateRequest 生成验证码请求
 type GenerateRequest struct {
-	CaptchaType string `json:"captchaType"` // character, image_select, slide
+	CaptchaType string `json:"captchaType"`     // character, image_select, slide, image_grid, pow
+	Scene       string `json:"scene,omitempty"` // 场景名，设置后按场景配置生成，忽略 CaptchaType
+	Adaptive    bool   `json:"adaptive"`        // 按风险自动选择场景，忽略 CaptchaType 和 Scene
 }
 
 // GenerateResponse 生成验证码响应
@@ -38,15 +75,18 @@ type GenerateResponse struct {
 type VerifyRequest struct {
 	CaptchaID     string      `json:"captchaId"`
 	CaptchaType   string      `json:"captchaType"`
+	Scene         string      `json:"scene,omitempty"`         // 生成时使用的场景名
 	CaptchaCode   string      `json:"captchaCode,omitempty"`   // 字符验证码
 	CaptchaAnswer interface{} `json:"captchaAnswer,omitempty"` // 其他类型验证码答案
+	Website       string      `json:"website,omitempty"`       // 蜜罐字段，页面上对用户隐藏
 }
 
 // VerifyResponse 验证响应
 type VerifyResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Valid   bool   `json:"valid"`
+	Code    int                  `json:"code"`
+	Message string               `json:"message"`
+	Valid   bool                 `json:"valid"`
+	Reason  captcha.VerifyReason `json:"reason,omitempty"` // 验证失败原因，校验通过凭证时为空
 }
 
 // IndexPage 首页
@@ -68,11 +108,27 @@ func (h *Handlers) GenerateCaptcha(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.requestContext(r, ensureSession(w, r))
 	captchaType := captcha.CaptchaType(req.CaptchaType)
 
 	// 生成验证码
-	resp, err := h.captchaService.Generate(ctx, captchaType)
+	var resp *captcha.CaptchaResponse
+	var err error
+	if req.Adaptive {
+		resp, err = h.captchaService.GenerateAdaptive(ctx)
+	} else if req.Scene != "" {
+		resp, err = h.captchaService.GenerateForScene(ctx, req.Scene)
+	} else {
+		resp, err = h.captchaService.Generate(ctx, captchaType)
+	}
+	if errors.Is(err, captcha.ErrRateLimited) {
+		respondWithError(w, "请求过于频繁，请稍后再试", http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, captcha.ErrSceneNotFound) {
+		respondWithError(w, "场景不存在: "+req.Scene, 400)
+		return
+	}
 	if err != nil {
 		respondWithError(w, "生成验证码失败: "+err.Error(), 500)
 		return
@@ -92,26 +148,25 @@ func (h *Handlers) GenerateCaptcha(w http.ResponseWriter, r *http.Request) {
 
 // VerifyCaptcha 验证验证码
 func (h *Handlers) VerifyCaptcha(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("VerifyCaptcha-1")
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	fmt.Println("VerifyCaptcha-2")
+
 	var req VerifyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, "无效的请求参数", 400)
 		return
 	}
-	fmt.Println("VerifyCaptcha-3")
-	ctx := context.Background()
+	ctx := h.requestContext(r, sessionID(r))
 
 	// 构建验证请求
 	verifyReq := &captcha.VerifyRequest{
 		CaptchaID:   req.CaptchaID,
 		CaptchaType: captcha.CaptchaType(req.CaptchaType),
+		Scene:       req.Scene,
+		Honeypot:    req.Website,
 	}
-	fmt.Println("VerifyCaptcha-4")
 	// 根据验证码类型设置答案
 	switch req.CaptchaType {
 	case "character":
@@ -133,6 +188,29 @@ func (h *Handlers) VerifyCaptcha(w http.ResponseWriter, r *http.Request) {
 				SelectedIndexes: selectedIndexes,
 			}
 		}
+	case "image_grid":
+		// 从 CaptchaAnswer 中解析 SelectedTiles
+		if answerMap, ok := req.CaptchaAnswer.(map[string]interface{}); ok {
+			selectedTiles := make([]int, 0)
+			if tiles, ok := answerMap["selectedTiles"].([]interface{}); ok {
+				for _, idx := range tiles {
+					if i, ok := idx.(float64); ok {
+						selectedTiles = append(selectedTiles, int(i))
+					}
+				}
+			}
+			verifyReq.Answer = captcha.ImageGridAnswer{
+				SelectedTiles: selectedTiles,
+			}
+		}
+	case "pow":
+		// 从 CaptchaAnswer 中解析 nonce
+		if answerMap, ok := req.CaptchaAnswer.(map[string]interface{}); ok {
+			nonce, _ := answerMap["nonce"].(string)
+			verifyReq.Answer = captcha.PowAnswer{
+				Nonce: nonce,
+			}
+		}
 	case "slide":
 		// 从 CaptchaAnswer 中解析滑动数据
 		if answerMap, ok := req.CaptchaAnswer.(map[string]interface{}); ok {
@@ -153,16 +231,158 @@ func (h *Handlers) VerifyCaptcha(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
-	fmt.Println("VerifyCaptcha-5")
+
 	// 验证
-	valid, err := h.captchaService.VerifyAndDelete(ctx, verifyReq)
+	result, err := h.captchaService.VerifyDetailed(ctx, verifyReq)
+	if errors.Is(err, captcha.ErrRateLimited) {
+		respondWithError(w, "请求过于频繁，请稍后再试", http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, captcha.ErrCaptchaExpired) {
+		respondWithError(w, "验证码已过期，请刷新后重试", http.StatusGone)
+		return
+	}
+	if errors.Is(err, captcha.ErrCaptchaAnswerFormatWrong) {
+		respondWithError(w, "答案格式错误", 400)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "验证失败: "+err.Error(), 500)
+		return
+	}
+	respondWithVerifyResult(w, result.OK, result.Reason)
+}
+
+// TicketRequest 校验通过凭证请求
+type TicketRequest struct {
+	Ticket string `json:"ticket"`
+}
+
+// InvisibleChallenge 无感验证，风险分较高时返回可见验证码
+func (h *Handlers) InvisibleChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var telemetry captcha.Telemetry
+	if err := json.NewDecoder(r.Body).Decode(&telemetry); err != nil {
+		respondWithError(w, "无效的请求参数", 400)
+		return
+	}
+
+	result, err := h.captchaService.Challenge(h.requestContext(r, ensureSession(w, r)), telemetry)
+	if errors.Is(err, captcha.ErrRateLimited) {
+		respondWithError(w, "请求过于频繁，请稍后再试", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "无感验证失败: "+err.Error(), 500)
+		return
+	}
+
+	respondWithSuccess(w, result)
+}
+
+// VerifyTicket 校验无感验证的通过凭证，凭证只能使用一次
+func (h *Handlers) VerifyTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TicketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "无效的请求参数", 400)
+		return
+	}
+
+	valid, err := h.captchaService.VerifyTicket(h.requestContext(r, sessionID(r)), req.Ticket)
 	if err != nil {
-		fmt.Println("VerifyCaptcha-6")
 		respondWithError(w, "验证失败: "+err.Error(), 500)
 		return
 	}
-	fmt.Println("VerifyCaptcha-7", valid)
-	respondWithVerifyResult(w, valid)
+	respondWithVerifyResult(w, valid, "")
+}
+
+// requestContext 把客户端 IP、User-Agent 和会话 ID 放入 context，用于风险评估、限流和客户端绑定
+func (h *Handlers) requestContext(r *http.Request, session string) context.Context {
+	return captcha.WithRequestMeta(r.Context(), captcha.RequestMeta{
+		IP:        h.clientIP(r),
+		UserAgent: r.UserAgent(),
+		SessionID: session,
+	})
+}
+
+// sessionID 从 cookie 中读取会话 ID
+func sessionID(r *http.Request) string {
+	cookie, err := r.Cookie("sid")
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// ensureSession 返回会话 ID，没有时创建并写入 cookie
+func ensureSession(w http.ResponseWriter, r *http.Request) string {
+	if session := sessionID(r); session != "" {
+		return session
+	}
+
+	session := generateUUID()
+	http.SetCookie(w, &http.Cookie{
+		Name:     "sid",
+		Value:    session,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return session
+}
+
+// clientIP 获取客户端 IP
+//
+// 默认使用连接的对端地址。对端是可信代理时从右往左读取 X-Forwarded-For，取第一个不可信的地址；
+// 客户端可以任意伪造 X-Forwarded-For 左侧的内容，只有可信代理追加在右侧的地址才可靠。
+func (h *Handlers) clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !h.trusted(net.ParseIP(remote)) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	ip := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// 格式不对的地址不可信，使用最后一个可信代理看到的地址
+			break
+		}
+		ip = hop.String()
+		if !h.trusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// trusted 是否为可信代理
+func (h *Handlers) trusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range h.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
 
 // respondWithSuccess 返回成功响应
@@ -177,13 +397,14 @@ func respondWithSuccess(w http.ResponseWriter, data interface{}) {
 }
 
 // respondWithVerifyResult 返回验证结果
-func respondWithVerifyResult(w http.ResponseWriter, valid bool) {
+func respondWithVerifyResult(w http.ResponseWriter, valid bool, reason captcha.VerifyReason) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(VerifyResponse{
 		Code:    0,
 		Message: "success",
 		Valid:   valid,
+		Reason:  reason,
 	})
 }
 
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gpencil/captcha"
+)
+
+// TestClientIP 只有对端是可信代理时才读取 X-Forwarded-For，并取最右侧不可信的地址
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.0.0.0/8, 192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandlers(nil, proxies)
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{name: "无代理", remote: "203.0.113.5:1234", want: "203.0.113.5"},
+		{name: "不可信对端忽略 XFF", remote: "203.0.113.5:1234", forwarded: []string{"1.2.3.4"}, want: "203.0.113.5"},
+		{name: "可信代理", remote: "10.0.0.1:80", forwarded: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "伪造的左侧地址", remote: "10.0.0.1:80", forwarded: []string{"1.2.3.4, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "多级可信代理", remote: "10.0.0.1:80", forwarded: []string{"1.2.3.4, 198.51.100.7, 192.168.1.1, 10.1.2.3"}, want: "198.51.100.7"},
+		{name: "多个 XFF 头", remote: "10.0.0.1:80", forwarded: []string{"1.2.3.4", "198.51.100.7"}, want: "198.51.100.7"},
+		{name: "全部可信取最左侧", remote: "10.0.0.1:80", forwarded: []string{"10.0.0.2, 10.0.0.3"}, want: "10.0.0.2"},
+		{name: "格式错误的地址", remote: "10.0.0.1:80", forwarded: []string{"198.51.100.7, garbage"}, want: "10.0.0.1"},
+		{name: "可信代理没有 XFF", remote: "10.0.0.1:80", want: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := h.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGenerateCaptchaSpoofedForwardedFor 伪造 X-Forwarded-For 不会改变限流的 key
+func TestGenerateCaptchaSpoofedForwardedFor(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string // 每次请求的 X-Forwarded-For
+	}{
+		{name: "直连", remote: "203.0.113.5:1234", forwarded: []string{"1.1.1.1", "2.2.2.2"}},
+		{name: "经过可信代理", remote: "10.0.0.1:80", forwarded: []string{"1.1.1.1, 198.51.100.7", "2.2.2.2, 198.51.100.7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := captcha.NewService(captcha.NewMemStore(),
+				captcha.WithRateLimit(captcha.RateLimitConfig{Generate: captcha.RateLimit{Limit: 1}}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer service.Close()
+			h := NewHandlers(service, proxies)
+
+			codes := make([]int, len(tt.forwarded))
+			for i, forwarded := range tt.forwarded {
+				r := httptest.NewRequest(http.MethodPost, "/api/captcha/generate", strings.NewReader(`{"captchaType":"character"}`))
+				r.RemoteAddr = tt.remote
+				r.Header.Set("X-Forwarded-For", forwarded)
+				w := httptest.NewRecorder()
+				h.GenerateCaptcha(w, r)
+				codes[i] = w.Code
+			}
+			if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+				t.Errorf("status = %v, want [200 429]", codes)
+			}
+		})
+	}
+}
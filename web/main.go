@@ -1,15 +1,29 @@
 package main
 
 import (
+	"flag"
 	"github.com/redis/go-redis/v9"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	"github.com/gpencil/captcha"
+	"github.com/gpencil/captcha/metrics"
 )
 
 func main() {
+	// 部署在反向代理之后时设置可信代理，否则客户端可以通过 X-Forwarded-For 伪造 IP 绕过限流
+	trustedProxies := flag.String("trusted-proxies", "", "可信反向代理，逗号分隔的 CIDR 或 IP，例如 10.0.0.0/8,127.0.0.1")
+	flag.Parse()
+	proxies, err := parseTrustedProxies(*trustedProxies)
+	if err != nil {
+		log.Fatalf("解析可信代理失败: %v", err)
+	}
+
 	// 初始化 Redis 客户端
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     "127.0.0.1:6379",
@@ -17,27 +31,35 @@ func main() {
 		DB:       0,
 	})
 
-	// 创建验证码存储
-	store := captcha.NewRedisStore(redisClient, "captcha:")
+	// 指标注册到独立的 registry，由 /metrics 导出
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	m, err := metrics.New(registry, metrics.Config{})
+	if err != nil {
+		log.Fatalf("创建指标失败: %v", err)
+	}
+
+	// 创建验证码存储，包装后统计存储耗时和错误
+	store := m.Store(captcha.NewRedisStore(redisClient, "captcha:"))
 
-	// 创建验证码服务（支持所有三种类型）
-	service := captcha.NewService(
+	// 创建验证码服务（支持所有类型），配置无效时启动失败
+	service, err := captcha.NewService(
 		store,
-		captcha.CharacterConfig{
+		captcha.WithCharacterConfig(captcha.CharacterConfig{
 			Width:      160,
 			Height:     60,
 			Length:     4,
 			ExpireTime: 5 * time.Minute, // 5分钟
 			Complexity: 2,
-		},
-		captcha.ImageSelectConfig{
+		}),
+		captcha.WithImageSelectConfig(captcha.ImageSelectConfig{
 			ImageCount:  4,
 			SelectCount: 1,
 			ExpireTime:  5 * time.Minute,
 			Category:    "traffic",
 			ImageDir:    "./images/traffic",
-		},
-		captcha.SlideConfig{
+		}),
+		captcha.WithSlideConfig(captcha.SlideConfig{
 			Width:          350,
 			Height:         200,
 			TemplateWidth:  60,
@@ -45,17 +67,98 @@ func main() {
 			ExpireTime:     5 * time.Minute,
 			ImageDir:       "./images/backgrounds",
 			TemplateDir:    "./images/templates",
-		},
+		}),
+		captcha.WithImageGridConfig(captcha.ImageGridConfig{
+			GridSize:   3,
+			ExpireTime: 5 * time.Minute,
+			ImageDir:   "./images/scenes",
+			MinOverlap: 0.2,
+		}),
+		captcha.WithPowConfig(captcha.PowConfig{
+			Difficulty: 16,
+			ExpireTime: 5 * time.Minute,
+		}),
+		// 生成后 1 秒内提交的答案视为机器提交
+		captcha.WithHumanCheck(captcha.HumanCheckConfig{
+			MinSolveTime: time.Second,
+			Action:       captcha.HumanCheckReject,
+		}),
+		// 场景：访问 /?scene=login 或 /?scene=sms 体验
+		captcha.WithScene("login", captcha.SceneConfig{
+			Type:        captcha.CaptchaTypeCharacter,
+			MaxAttempts: 3,
+		}),
+		captcha.WithScene("sms", captcha.SceneConfig{
+			Type:       captcha.SlideTypeSelect,
+			ExpireTime: 2 * time.Minute,
+		}),
+		// 自适应难度：访问 /?adaptive=1 体验，同一 IP 验证失败越多难度越高
+		captcha.WithScene("risk-low", captcha.SceneConfig{
+			Type: captcha.SlideTypeSelect,
+		}),
+		captcha.WithScene("risk-medium", captcha.SceneConfig{
+			Type: captcha.CaptchaTypeCharacter,
+		}),
+		captcha.WithScene("risk-high", captcha.SceneConfig{
+			Type: captcha.CaptchaTypeCharacter,
+			Character: captcha.CharacterConfig{
+				Width:      200,
+				Length:     6,
+				Complexity: 3,
+			},
+		}),
+		captcha.WithRiskConfig(captcha.RiskConfig{
+			Bands: []captcha.RiskBand{
+				{MinScore: 0, Scene: "risk-low"},
+				{MinScore: 0.4, Scene: "risk-medium"},
+				{MinScore: 0.8, Scene: "risk-high"},
+			},
+		}),
+		// 同一 IP 每分钟最多生成 30 次、验证 60 次，计数保存在 Redis 中
+		captcha.WithRateLimit(captcha.RateLimitConfig{
+			Generate: captcha.RateLimit{Limit: 30, Window: time.Minute},
+			Verify:   captcha.RateLimit{Limit: 60, Window: time.Minute},
+		}),
+		captcha.WithRateLimiter(captcha.NewRedisRateLimiter(redisClient, "captcha:ratelimit:")),
+		// 验证码只能由生成它的浏览器提交
+		captcha.WithClientBinding(captcha.ClientBindingConfig{
+			IP:        true,
+			UserAgent: true,
+			Session:   true,
+		}),
+		// 无感验证：风险分低于 0.5 时直接通过，否则回退到滑动验证码
+		captcha.WithInvisible(captcha.InvisibleConfig{
+			Threshold:    0.5,
+			FallbackType: captcha.SlideTypeSelect,
+		}),
+		// 统计生成、验证和限流指标
+		captcha.WithObserver(m),
+		// 图片以签名地址下发，由 /api/captcha/asset/ 提供下载
+		captcha.WithAssetURLs(captcha.AssetURLConfig{
+			BaseURL:    "/api/captcha/asset",
+			SigningKey: "change-me-in-production",
+		}),
 	)
+	if err != nil {
+		log.Fatalf("创建验证码服务失败: %v", err)
+	}
+	defer service.Close()
+	if err := m.RegisterService(service); err != nil {
+		log.Fatalf("注册服务指标失败: %v", err)
+	}
 
 	// 创建处理器
-	h := NewHandlers(service)
+	h := NewHandlers(service, proxies)
 
 	// 设置路由
 	http.HandleFunc("/", h.IndexPage)
 	http.HandleFunc("/api/captcha/generate", h.GenerateCaptcha)
 	http.HandleFunc("/api/captcha/verify", h.VerifyCaptcha)
+	http.HandleFunc("/api/captcha/invisible", h.InvisibleChallenge)
+	http.HandleFunc("/api/captcha/ticket", h.VerifyTicket)
+	http.Handle("/api/captcha/asset/", http.StripPrefix("/api/captcha/asset/", service.AssetHandler()))
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 
 	// 启动服务器
 	addr := ":8083"
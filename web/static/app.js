@@ -1,6 +1,13 @@
 // 全局状态
 let currentCaptchaType = 'character';
 let currentCaptchaId = null;
+// 场景名，如 /?scene=login，为空时按所选类型生成
+const currentScene = new URLSearchParams(window.location.search).get('scene') || '';
+// 自适应难度，如 /?adaptive=1，由服务端按风险选择场景
+const adaptive = new URLSearchParams(window.location.search).get('adaptive') === '1';
+let issuedScene = currentScene;
+// 无感验证模式，风险较高时回退到服务端返回的可见验证码
+let invisibleMode = false;
 let selectedIndexes = [];
 
 // DOM 元素
@@ -22,7 +29,8 @@ document.querySelectorAll('.type-btn').forEach(btn => {
     btn.addEventListener('click', () => {
         document.querySelectorAll('.type-btn').forEach(b => b.classList.remove('active'));
         btn.classList.add('active');
-        currentCaptchaType = btn.dataset.type;
+        invisibleMode = btn.dataset.type === 'invisible';
+        currentCaptchaType = invisibleMode ? 'slide' : btn.dataset.type;
         resetUI();
     });
 });
@@ -35,21 +43,21 @@ refreshBtn.addEventListener('click', generateCaptcha);
 verifyBtn.addEventListener('click', verifyCaptcha);
 
 async function generateCaptcha() {
+    if (invisibleMode && !currentCaptchaId) {
+        return invisibleChallenge();
+    }
+
     try {
         const response = await fetch('/api/captcha/generate', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ captchaType: currentCaptchaType })
+            body: JSON.stringify({ captchaType: currentCaptchaType, scene: currentScene, adaptive: adaptive })
         });
 
         const result = await response.json();
 
         if (result.code === 0) {
-            currentCaptchaId = result.data.captchaId;
-            displayCaptcha(result.data);
-            inputSection.style.display = 'block';
-            refreshBtn.style.display = 'inline-block';
-            hideResult();
+            showCaptcha(result.data);
         } else {
             showError('生成验证码失败: ' + result.message);
         }
@@ -58,6 +66,51 @@ async function generateCaptcha() {
     }
 }
 
+// 无感验证：提交交互数据，通过时校验凭证，否则显示回退的可见验证码
+async function invisibleChallenge() {
+    try {
+        const response = await fetch('/api/captcha/invisible', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(telemetry.collect())
+        });
+
+        const result = await response.json();
+        if (result.code !== 0) {
+            showError('无感验证失败: ' + result.message);
+            return;
+        }
+
+        if (!result.data.passed) {
+            showCaptcha(result.data.captcha);
+            return;
+        }
+
+        // 业务接口收到凭证后调用 Service.VerifyTicket 校验
+        const ticketResponse = await fetch('/api/captcha/ticket', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ ticket: result.data.ticket })
+        });
+        const ticketResult = await ticketResponse.json();
+        showResult(ticketResult.valid);
+        resultMessage.textContent += ` 风险分 ${result.data.score.toFixed(2)}`;
+    } catch (error) {
+        showError('网络错误: ' + error.message);
+    }
+}
+
+function showCaptcha(data) {
+    currentCaptchaId = data.captchaId;
+    // 按场景生成时类型由服务端决定
+    currentCaptchaType = data.captchaType;
+    issuedScene = data.scene || '';
+    displayCaptcha(data);
+    inputSection.style.display = 'block';
+    refreshBtn.style.display = 'inline-block';
+    hideResult();
+}
+
 function displayCaptcha(data) {
     switch (currentCaptchaType) {
         case 'character':
@@ -66,9 +119,54 @@ function displayCaptcha(data) {
         case 'image_select':
             displayImageSelectCaptcha(data.data);
             break;
+        case 'image_grid':
+            displayImageGridCaptcha(data.data);
+            break;
         case 'slide':
             displaySlideCaptcha(data.data);
             break;
+        case 'pow':
+            displayPowCaptcha(data);
+            break;
+    }
+}
+
+// 显示工作量证明验证码，计算完成后自动提交
+async function displayPowCaptcha(data) {
+    characterInput.style.display = 'none';
+    imageSelectInput.style.display = 'none';
+    slideInput.style.display = 'none';
+    window.powNonce = null;
+
+    const captchaId = data.captchaId;
+    const pow = data.data;
+    captchaDisplay.innerHTML = `
+        <div class="captcha-canvas">
+            <p>正在计算工作量证明（难度 ${pow.difficulty} 位）...</p>
+            <p class="hint" id="powProgress">已计算 0 次哈希</p>
+        </div>
+    `;
+
+    try {
+        const result = await powSolver.solve(pow.prefix, pow.difficulty, hashes => {
+            const progress = document.getElementById('powProgress');
+            if (progress) {
+                progress.textContent = `已计算 ${hashes} 次哈希`;
+            }
+        });
+        // 计算期间已切换或刷新验证码
+        if (captchaId !== currentCaptchaId) {
+            return;
+        }
+        window.powNonce = result.nonce;
+        captchaDisplay.innerHTML = `
+            <div class="captcha-canvas">
+                <p>计算完成：${result.hashes} 次哈希，耗时 ${result.ms} 毫秒</p>
+            </div>
+        `;
+        verifyCaptcha();
+    } catch (error) {
+        showError('工作量证明计算失败: ' + error.message);
     }
 }
 
@@ -108,7 +206,7 @@ function displayImageSelectCaptcha(data) {
             <div class="image-grid">
                 ${imagesHtml}
             </div>
-            <p class="select-count">需要选择: ${data.selectCount} 张</p>
+            <p class="select-count">${data.selectCount ? `需要选择: ${data.selectCount} 张` : '请选择所有符合的图片'}</p>
         </div>
     `;
 }
@@ -140,6 +238,38 @@ function updateSelectedCount() {
     document.getElementById('selectedCount').textContent = selectedIndexes.length;
 }
 
+// 显示宫格图片选择验证码（同一张图片按 gridSize 切分为方格）
+function displayImageGridCaptcha(data) {
+    characterInput.style.display = 'none';
+    imageSelectInput.style.display = 'block';
+    slideInput.style.display = 'none';
+    selectedIndexes = [];
+    updateSelectedCount();
+
+    const size = data.gridSize;
+    const tilesHtml = Array.from({ length: size * size }, (_, index) => {
+        const row = Math.floor(index / size);
+        const col = index % size;
+        return `
+        <div class="image-item grid-tile"
+             onclick="toggleImageSelection(${index})"
+             data-index="${index}"
+             style="background-image: url('${data.image}');
+                    background-size: ${size * 100}% ${size * 100}%;
+                    background-position: ${col * 100 / (size - 1)}% ${row * 100 / (size - 1)}%;">
+        </div>`;
+    }).join('');
+
+    captchaDisplay.innerHTML = `
+        <div class="image-select-container">
+            <div class="question">${data.question}</div>
+            <div class="image-grid" style="grid-template-columns: repeat(${size}, 1fr);">
+                ${tilesHtml}
+            </div>
+        </div>
+    `;
+}
+
 // 显示滑动验证码
 function displaySlideCaptcha(data) {
     characterInput.style.display = 'none';
@@ -268,7 +398,9 @@ async function verifyCaptcha() {
 
     let requestBody = {
         captchaId: currentCaptchaId,
-        captchaType: currentCaptchaType
+        captchaType: currentCaptchaType,
+        scene: issuedScene,
+        website: document.getElementById('website').value
     };
 
     // 根据类型添加答案
@@ -292,6 +424,16 @@ async function verifyCaptcha() {
             };
             break;
 
+        case 'image_grid':
+            if (selectedIndexes.length === 0) {
+                showError('请选择方格');
+                return;
+            }
+            requestBody.captchaAnswer = {
+                selectedTiles: selectedIndexes
+            };
+            break;
+
         case 'slide':
             if (!window.sliderData) {
                 showError('请完成滑动验证');
@@ -299,6 +441,16 @@ async function verifyCaptcha() {
             }
             requestBody.captchaAnswer = window.sliderData;
             break;
+
+        case 'pow':
+            if (!window.powNonce) {
+                showError('工作量证明尚未计算完成');
+                return;
+            }
+            requestBody.captchaAnswer = {
+                nonce: window.powNonce
+            };
+            break;
     }
 
     try {
@@ -309,17 +461,39 @@ async function verifyCaptcha() {
         });
 
         const result = await response.json();
-        showResult(result.valid);
+        // 验证码已过期，提示后自动刷新
+        if (response.status === 410) {
+            showError(result.message);
+            setTimeout(generateCaptcha, 1500);
+            return;
+        }
+        if (result.code !== 0) {
+            showError(result.message);
+            return;
+        }
+        showResult(result.valid, result.reason);
     } catch (error) {
         showError('验证失败: ' + error.message);
     }
 }
 
-function showResult(success) {
+// 验证失败原因的提示文字
+const reasonMessages = {
+    wrong_answer: '答案错误',
+    not_found: '验证码不存在',
+    replayed: '验证码已使用',
+    too_many_attempts: '失败次数过多，请刷新验证码',
+    too_fast: '操作过快',
+    honeypot: '请求异常',
+    client_mismatch: '请求异常',
+    trajectory_suspicious: '滑动轨迹异常'
+};
+
+function showResult(success, reason) {
     resultSection.style.display = 'block';
     resultCard.className = 'result-card ' + (success ? 'success' : 'error');
     resultIcon.textContent = success ? '✅' : '❌';
-    resultMessage.textContent = success ? '验证成功！' : '验证失败！';
+    resultMessage.textContent = success ? '验证成功！' : '验证失败！' + (reasonMessages[reason] || '');
 
     if (success) {
         setTimeout(() => {
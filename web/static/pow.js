@@ -0,0 +1,119 @@
+// 工作量证明求解器
+//
+// 在 Web Worker 中寻找 nonce，使 SHA-256(prefix + nonce) 的前 difficulty 位为 0，
+// nonce 为从 0 开始递增的十进制字符串，与服务端 PowCaptcha.Verify 一致。
+const powSolver = (function () {
+    // worker 中运行的代码，使用内置的 SHA-256 实现，避免 crypto.subtle 每次调用的异步开销
+    function worker() {
+        const K = new Uint32Array([
+        0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+        0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+        0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+        0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+        0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+        0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+        0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+        0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2
+        ]);
+        const H = new Uint32Array([
+        0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19
+        ]);
+        const w = new Uint32Array(64);
+        const encoder = new TextEncoder();
+
+        function rotr(x, n) {
+            return (x >>> n) | (x << (32 - n));
+        }
+
+        // sha256First 返回哈希的前 32 位
+        function sha256First(bytes) {
+            const blocks = (bytes.length + 9 + 63) >> 6;
+            const buf = new Uint8Array(blocks * 64);
+            buf.set(bytes);
+            buf[bytes.length] = 0x80;
+            const view = new DataView(buf.buffer);
+            view.setUint32(buf.length - 4, bytes.length * 8);
+
+            let h0 = H[0], h1 = H[1], h2 = H[2], h3 = H[3], h4 = H[4], h5 = H[5], h6 = H[6], h7 = H[7];
+            for (let offset = 0; offset < buf.length; offset += 64) {
+                for (let i = 0; i < 16; i++) {
+                    w[i] = view.getUint32(offset + i * 4);
+                }
+                for (let i = 16; i < 64; i++) {
+                    const s0 = rotr(w[i - 15], 7) ^ rotr(w[i - 15], 18) ^ (w[i - 15] >>> 3);
+                    const s1 = rotr(w[i - 2], 17) ^ rotr(w[i - 2], 19) ^ (w[i - 2] >>> 10);
+                    w[i] = (w[i - 16] + s0 + w[i - 7] + s1) | 0;
+                }
+
+                let a = h0, b = h1, c = h2, d = h3, e = h4, f = h5, g = h6, h = h7;
+                for (let i = 0; i < 64; i++) {
+                    const S1 = rotr(e, 6) ^ rotr(e, 11) ^ rotr(e, 25);
+                    const ch = (e & f) ^ (~e & g);
+                    const t1 = (h + S1 + ch + K[i] + w[i]) | 0;
+                    const S0 = rotr(a, 2) ^ rotr(a, 13) ^ rotr(a, 22);
+                    const maj = (a & b) ^ (a & c) ^ (b & c);
+                    const t2 = (S0 + maj) | 0;
+                    h = g;
+                    g = f;
+                    f = e;
+                    e = (d + t1) | 0;
+                    d = c;
+                    c = b;
+                    b = a;
+                    a = (t1 + t2) | 0;
+                }
+
+                h0 = (h0 + a) | 0;
+                h1 = (h1 + b) | 0;
+                h2 = (h2 + c) | 0;
+                h3 = (h3 + d) | 0;
+                h4 = (h4 + e) | 0;
+                h5 = (h5 + f) | 0;
+                h6 = (h6 + g) | 0;
+                h7 = (h7 + h) | 0;
+            }
+            return h0 >>> 0;
+        }
+
+        self.onmessage = function (e) {
+            const { prefix, difficulty } = e.data;
+            const start = performance.now();
+            for (let nonce = 0; ; nonce++) {
+                // 服务端限制难度不超过 32 位，只需要检查前 32 位
+                if (Math.clz32(sha256First(encoder.encode(prefix + nonce))) >= difficulty) {
+                    self.postMessage({ done: true, nonce: String(nonce), hashes: nonce + 1, ms: Math.round(performance.now() - start) });
+                    return;
+                }
+                if (nonce % 50000 === 0 && nonce > 0) {
+                    self.postMessage({ done: false, hashes: nonce });
+                }
+            }
+        };
+    }
+
+    const source = URL.createObjectURL(new Blob(['(' + worker.toString() + ')()'], { type: 'application/javascript' }));
+
+    return {
+        // solve 返回 Promise，结果为 { nonce, hashes, ms }，onProgress 收到已计算的哈希次数
+        solve(prefix, difficulty, onProgress) {
+            return new Promise((resolve, reject) => {
+                const w = new Worker(source);
+                w.onmessage = e => {
+                    if (!e.data.done) {
+                        if (onProgress) {
+                            onProgress(e.data.hashes);
+                        }
+                        return;
+                    }
+                    w.terminate();
+                    resolve(e.data);
+                };
+                w.onerror = e => {
+                    w.terminate();
+                    reject(new Error(e.message));
+                };
+                w.postMessage({ prefix: prefix, difficulty: difficulty });
+            });
+        }
+    };
+})();
@@ -0,0 +1,59 @@
+// 无感验证交互数据采集
+//
+// 只记录轨迹坐标、按键间隔和事件次数，不记录按键内容。
+const telemetry = (function () {
+    const start = performance.now();
+    const maxPoints = 200;
+    const maxKeys = 100;
+    const pointer = [];
+    const keys = [];
+    let lastMove = 0;
+    let lastKey = 0;
+    let focus = 0;
+    let scroll = 0;
+
+    function now() {
+        return Math.round(performance.now() - start);
+    }
+
+    // 轨迹每 50 毫秒最多记录一个点
+    function recordPoint(x, y) {
+        const t = now();
+        if (t - lastMove < 50 || pointer.length >= maxPoints) {
+            return;
+        }
+        lastMove = t;
+        pointer.push({ x: Math.round(x), y: Math.round(y), t: t });
+    }
+
+    document.addEventListener('mousemove', e => recordPoint(e.clientX, e.clientY), { passive: true });
+    document.addEventListener('touchmove', e => {
+        if (e.touches.length > 0) {
+            recordPoint(e.touches[0].clientX, e.touches[0].clientY);
+        }
+    }, { passive: true });
+    document.addEventListener('keydown', () => {
+        const t = now();
+        if (lastKey > 0 && keys.length < maxKeys) {
+            keys.push(t - lastKey);
+        }
+        lastKey = t;
+    });
+    window.addEventListener('focus', () => focus++);
+    window.addEventListener('blur', () => focus++);
+    window.addEventListener('scroll', () => scroll++, { passive: true });
+
+    return {
+        // collect 返回提交给 /api/captcha/invisible 的数据
+        collect() {
+            return {
+                pointer: pointer,
+                keys: keys,
+                focus: focus,
+                scroll: scroll,
+                duration: now(),
+                webdriver: !!navigator.webdriver
+            };
+        }
+    };
+})();